@@ -0,0 +1,29 @@
+// rebuild-stats 根据审核通过且未作废的充电记录重建按日、按月用电统计表
+//
+// 用法：go run ./cmd/rebuild-stats
+// 使用与服务相同的环境变量连接数据库；重建期间充电记录的写入会被阻塞
+package main
+
+import (
+	"shared-charge/config"
+	"shared-charge/models"
+	"shared-charge/service"
+	"shared-charge/utils"
+	"time"
+)
+
+func main() {
+	config.LoadConfig()
+	cfg := config.GetConfig()
+	if err := utils.InitLogger(cfg.Log.Mode, cfg.Log.Level, cfg.Log.FilePath); err != nil {
+		panic("初始化日志失败: " + err.Error())
+	}
+	models.InitDB()
+
+	start := time.Now()
+	days, err := service.RebuildUserStats()
+	if err != nil {
+		utils.Fatal("重建用电统计失败: %v", err)
+	}
+	utils.Info("重建用电统计完成: 日统计%d行, 耗时%s", days, time.Since(start))
+}
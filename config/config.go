@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"github.com/joho/godotenv"
+	"github.com/shopspring/decimal"
 )
 
 type Config struct {
@@ -17,6 +18,11 @@ type Config struct {
 	MinIO    MinIOConfig
 	Log      LogConfig
 	Redis    RedisConfig
+	Review   ReviewConfig
+	Record   RecordConfig
+	Anomaly  AnomalyConfig
+	OCR      OCRConfig
+	PDF      PDFConfig
 }
 
 type ServerConfig struct {
@@ -34,8 +40,9 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret      string
-	ExpireHours int
+	Secret              string
+	AccessExpireMinutes int // 访问令牌有效期(分钟)
+	RefreshExpireDays   int // 刷新令牌有效期(天)
 }
 
 type WechatConfig struct {
@@ -44,7 +51,8 @@ type WechatConfig struct {
 }
 
 type AppConfig struct {
-	DefaultUnitPrice float64
+	DefaultUnitPrice decimal.Decimal
+	RoundingMode     string // 金额舍入方式: half_up(四舍五入), banker(银行家舍入)
 	MaxFileSize      int64
 	UploadPath       string
 }
@@ -69,6 +77,37 @@ type RedisConfig struct {
 	DB       int
 }
 
+type ReviewConfig struct {
+	Policy string // auto: 提交即通过; manual: 需管理员审核
+}
+
+type RecordConfig struct {
+	EditWindowHours int // 创建后允许用户直接修改的时长，<=0 表示不限制
+}
+
+type AnomalyConfig struct {
+	ChargerMaxPowerKW  float64 // 充电桩最大功率(kW)
+	SlotHours          float64 // 单个时段时长(小时)
+	HistoryFactor      float64 // 偏离用户历史均值的标准差倍数
+	HistoryMinSamples  int     // 启用历史偏离检查所需的最少记录数
+	FlagScoreThreshold int     // 达到该分数即标记为异常
+}
+
+type OCRConfig struct {
+	Engine         string  // none | tesseract
+	TesseractPath  string  // tesseract 可执行文件路径
+	Language       string  // tesseract 语言包，默认 chi_sim+eng 以识别中文标签
+	TimeoutSeconds int     // 单次识别超时时间
+	KWHTolerance   float64 // 识别度数与填写度数允许的误差
+	MinConfidence  float64 // 置信度低于该值时不做比对
+}
+
+type PDFConfig struct {
+	FontPath        string // 支持中文的 TTF 字体路径，未配置或加载失败时无法生成账单
+	ThumbnailWidth  int    // 截图缩略图宽度(像素)
+	ImageTimeoutSec int    // 单张截图读取超时时间(秒)
+}
+
 var config *Config
 
 // 环境变量缓存
@@ -93,15 +132,17 @@ func LoadConfig() {
 			SSLMode:  getEnv("DB_SSLMODE", "disable"),
 		},
 		JWT: JWTConfig{
-			Secret:      getEnv("JWT_SECRET", "your-jwt-secret-key"),
-			ExpireHours: getEnvAsInt("JWT_EXPIRE_HOURS", 24),
+			Secret:              getEnv("JWT_SECRET", "your-jwt-secret-key"),
+			AccessExpireMinutes: getEnvAsInt("JWT_ACCESS_EXPIRE_MINUTES", 30),
+			RefreshExpireDays:   getEnvAsInt("JWT_REFRESH_EXPIRE_DAYS", 30),
 		},
 		Wechat: WechatConfig{
 			AppID:  getEnv("WECHAT_APPID", ""),
 			Secret: getEnv("WECHAT_SECRET", ""),
 		},
 		App: AppConfig{
-			DefaultUnitPrice: getEnvAsFloat("DEFAULT_UNIT_PRICE", 0.7),
+			DefaultUnitPrice: getEnvAsDecimal("DEFAULT_UNIT_PRICE", decimal.RequireFromString("0.7")),
+			RoundingMode:     getEnv("MONEY_ROUNDING_MODE", "half_up"),
 			MaxFileSize:      getEnvAsInt64("MAX_FILE_SIZE", 10485760), // 10MB
 			UploadPath:       getEnv("UPLOAD_PATH", "./uploads"),
 		},
@@ -122,6 +163,32 @@ func LoadConfig() {
 			Password: getEnv("REDIS_PASSWORD", ""),
 			DB:       getEnvAsInt("REDIS_DB", 0),
 		},
+		Review: ReviewConfig{
+			Policy: getEnv("RECORD_REVIEW_POLICY", "auto"),
+		},
+		Record: RecordConfig{
+			EditWindowHours: getEnvAsInt("RECORD_EDIT_WINDOW_HOURS", 48),
+		},
+		Anomaly: AnomalyConfig{
+			ChargerMaxPowerKW:  getEnvAsFloat("ANOMALY_CHARGER_MAX_KW", 7),
+			SlotHours:          getEnvAsFloat("ANOMALY_SLOT_HOURS", 12),
+			HistoryFactor:      getEnvAsFloat("ANOMALY_HISTORY_FACTOR", 3),
+			HistoryMinSamples:  getEnvAsInt("ANOMALY_HISTORY_MIN_SAMPLES", 5),
+			FlagScoreThreshold: getEnvAsInt("ANOMALY_FLAG_SCORE", 2),
+		},
+		OCR: OCRConfig{
+			Engine:         getEnv("OCR_ENGINE", "none"),
+			TesseractPath:  getEnv("OCR_TESSERACT_PATH", "tesseract"),
+			Language:       getEnv("OCR_LANGUAGE", "chi_sim+eng"),
+			TimeoutSeconds: getEnvAsInt("OCR_TIMEOUT_SECONDS", 10),
+			KWHTolerance:   getEnvAsFloat("OCR_KWH_TOLERANCE", 0.5),
+			MinConfidence:  getEnvAsFloat("OCR_MIN_CONFIDENCE", 0.6),
+		},
+		PDF: PDFConfig{
+			FontPath:        getEnv("PDF_FONT_PATH", ""),
+			ThumbnailWidth:  getEnvAsInt("PDF_THUMBNAIL_WIDTH", 160),
+			ImageTimeoutSec: getEnvAsInt("PDF_IMAGE_TIMEOUT_SECONDS", 5),
+		},
 	}
 }
 
@@ -179,6 +246,15 @@ func getEnvAsFloat(key string, defaultValue float64) float64 {
 	return defaultValue
 }
 
+func getEnvAsDecimal(key string, defaultValue decimal.Decimal) decimal.Decimal {
+	if value := getEnv(key, ""); value != "" {
+		if decimalValue, err := decimal.NewFromString(value); err == nil {
+			return decimalValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsBool(key string, defaultValue bool) bool {
 	if value := getEnv(key, ""); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -4,11 +4,14 @@ import (
 	"bytes"
 	"io"
 	"net/http"
+	"shared-charge/models"
 	"shared-charge/service"
+	"strconv"
 
 	"shared-charge/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 )
 
 // GetAllUsers 管理员获取所有用户列表
@@ -54,6 +57,10 @@ func UpdateUserCanReserve(c *gin.Context) {
 	utils.InfoCtx(c, "参数校验通过: user_id=%d, can_reserve=%t", req.UserID, req.CanReserve)
 
 	if err := service.UpdateUserCanReserve(c, req.UserID, req.CanReserve); err != nil {
+		if err.Error() == "用户不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "用户不存在"})
+			return
+		}
 		utils.ErrorCtx(c, "更新用户可预约状态失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新失败"})
 		return
@@ -66,19 +73,23 @@ func UpdateUserCanReserve(c *gin.Context) {
 // UpdateUserUnitPrice 管理员修改用户电价
 func UpdateUserUnitPrice(c *gin.Context) {
 	type reqBody struct {
-		UserID    uint    `json:"user_id" binding:"required"`
-		UnitPrice float64 `json:"unit_price" binding:"required"`
+		UserID    uint            `json:"user_id" binding:"required"`
+		UnitPrice decimal.Decimal `json:"unit_price" binding:"required"`
 	}
 	var req reqBody
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误"})
 		return
 	}
-	if req.UnitPrice <= 0 {
+	if !req.UnitPrice.IsPositive() {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "电价必须为正数"})
 		return
 	}
 	if err := service.UpdateUserUnitPrice(c, req.UserID, req.UnitPrice); err != nil {
+		if err.Error() == "用户不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "用户不存在"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新失败"})
 		return
 	}
@@ -94,8 +105,450 @@ func GetMonthlyReport(c *gin.Context) {
 	}
 	result, err := service.GetMonthlyReport(c, month)
 	if err != nil {
+		if err.Error() == "月份格式错误" {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取月度对账失败"})
 		return
 	}
 	c.JSON(http.StatusOK, result)
 }
+
+// GetAdminGroupStatistics 管理员获取群组月度统计
+// @Summary 获取群组月度统计（管理员）
+// @Description 获取全体成员的月度用电量与费用、时段预约/使用/取消比例、白班/夜班占比、活跃成员数及按成员明细
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param month query string true "月份(YYYY-MM)"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/statistics [get]
+func GetAdminGroupStatistics(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	month := c.Query("month")
+	if month == "" || len(month) != 7 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+		return
+	}
+	resp, err := service.GetGroupStatistics(c, month, userModel.ID, true)
+	if err != nil {
+		if err.Error() == "月份格式错误" {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取群组统计失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": resp})
+}
+
+// GetAdminRangeStatistics 管理员获取区间统计
+// @Summary 获取区间统计（管理员）
+// @Description 按日/周/月/年汇总全体成员或指定成员的用电量和费用，并给出与上一区间相比的变化
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "开始日期(YYYY-MM-DD)"
+// @Param to query string false "结束日期(YYYY-MM-DD)"
+// @Param month query string false "月份(YYYY-MM)，未传from/to时使用"
+// @Param granularity query string false "统计粒度 day|week|month|year，默认day"
+// @Param tz query string false "时区，如Asia/Shanghai，默认服务器时区"
+// @Param user_id query int false "用户ID，不传则统计全部成员"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/statistics/range [get]
+func GetAdminRangeStatistics(c *gin.Context) {
+	var userID uint64
+	if value := c.Query("user_id"); value != "" {
+		var err error
+		userID, err = strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "用户ID格式错误", "error": err.Error()})
+			return
+		}
+	}
+	r, ok := statisticsRangeFromQuery(c)
+	if !ok {
+		return
+	}
+	resp, err := service.GetRangeStatistics(c, uint(userID), r)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取区间统计失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": resp})
+}
+
+// GetAdminUtilizationHeatmap 管理员获取星期×时段使用率热力图
+// @Summary 获取使用率热力图（管理员）
+// @Description 按星期几和时段统计日期范围内的预约率、取消率、爽约率和平均每次充电度数，未传from/to/month时统计最近12周
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "开始日期(YYYY-MM-DD)"
+// @Param to query string false "结束日期(YYYY-MM-DD)"
+// @Param month query string false "月份(YYYY-MM)，未传from/to时使用"
+// @Param tz query string false "时区，如Asia/Shanghai，默认服务器时区"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/statistics/heatmap [get]
+func GetAdminUtilizationHeatmap(c *gin.Context) {
+	r, err := service.ParseStatisticsRange(service.StatisticsRangeRequest{
+		From:        c.Query("from"),
+		To:          c.Query("to"),
+		Month:       c.Query("month"),
+		Granularity: service.GranularityWeek,
+		Timezone:    c.Query("tz"),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	resp, err := service.GetUtilizationHeatmap(c, r)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取使用率热力图失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": resp})
+}
+
+// ReviewRecordsRequest 批量审核充电记录请求
+type ReviewRecordsRequest struct {
+	RecordIDs []uint `json:"record_ids" binding:"required,min=1"`
+	Reason    string `json:"reason"`
+}
+
+// GetRecordsForReview 管理员获取待审核充电记录
+// @Summary 获取待审核充电记录
+// @Description 按审核状态获取充电记录，默认返回待审核(submitted)的记录
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "审核状态(submitted/approved/rejected)"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/records/review [get]
+func GetRecordsForReview(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReviewStatusSubmitted)
+	if status != models.ReviewStatusSubmitted && status != models.ReviewStatusApproved && status != models.ReviewStatusRejected {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "审核状态参数错误"})
+		return
+	}
+	records, err := service.GetRecordsForReview(c, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取待审核记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": records})
+}
+
+// ApproveRecords 管理员批量审核通过充电记录
+// @Summary 批量审核通过充电记录
+// @Description 批量将充电记录设为审核通过，并通知记录所属用户
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ReviewRecordsRequest true "审核请求"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/records/approve [post]
+func ApproveRecords(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	var req ReviewRecordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.WarnCtx(c, "审核参数校验失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误", "error": err.Error()})
+		return
+	}
+	count, err := service.ApproveRecords(c, adminUser.ID, req.RecordIDs)
+	if err != nil {
+		reviewRecordsError(c, err, "审核失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "审核成功", "data": gin.H{"reviewed": count}})
+}
+
+// RejectRecords 管理员批量驳回充电记录
+// @Summary 批量驳回充电记录
+// @Description 批量驳回充电记录（需填写原因），并通知记录所属用户
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ReviewRecordsRequest true "审核请求"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/records/reject [post]
+func RejectRecords(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	var req ReviewRecordsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.WarnCtx(c, "驳回参数校验失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误", "error": err.Error()})
+		return
+	}
+	if req.Reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "驳回原因不能为空"})
+		return
+	}
+	count, err := service.RejectRecords(c, adminUser.ID, req.RecordIDs, req.Reason)
+	if err != nil {
+		reviewRecordsError(c, err, "驳回失败")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "驳回成功", "data": gin.H{"reviewed": count}})
+}
+
+// reviewRecordsError 将批量审核的业务错误映射为对应的HTTP状态码，其余错误不向客户端暴露细节
+func reviewRecordsError(c *gin.Context, err error, message string) {
+	switch err.Error() {
+	case "请选择要审核的记录", "驳回原因不能为空":
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": message})
+	}
+}
+
+// ReviewCorrectionRequest 驳回更正申请请求
+type ReviewCorrectionRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// GetAdminRecordRevisions 管理员获取任意充电记录的修订历史
+// @Summary 获取充电记录修订历史
+// @Description 管理员查看任意充电记录的修改历史
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "记录ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/records/{id}/revisions [get]
+func GetAdminRecordRevisions(c *gin.Context) {
+	recordID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	revisions, err := service.GetRecordRevisions(c, 0, uint(recordID))
+	if err != nil {
+		if err.Error() == "充电记录不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "充电记录不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取修订历史失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": revisions})
+}
+
+// GetCorrectionRequests 管理员获取更正申请列表
+// @Summary 获取更正申请列表
+// @Description 按状态获取充电记录更正申请，默认返回待审核(pending)的申请
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "状态(pending/approved/rejected)"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/records/corrections [get]
+func GetCorrectionRequests(c *gin.Context) {
+	status := c.DefaultQuery("status", models.CorrectionStatusPending)
+	corrections, err := service.GetCorrectionRequests(c, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取更正申请失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": corrections})
+}
+
+// ApproveCorrectionRequest 管理员通过更正申请
+// @Summary 通过更正申请
+// @Description 按申请内容修改充电记录并写入修订历史
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "更正申请ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/records/corrections/{id}/approve [post]
+func ApproveCorrectionRequest(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	if err := service.ApproveCorrectionRequest(c, adminUser.ID, uint(id)); err != nil {
+		utils.ErrorCtx(c, "通过更正申请失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "通过更正申请失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更正申请已通过"})
+}
+
+// RejectCorrectionRequest 管理员驳回更正申请
+// @Summary 驳回更正申请
+// @Description 驳回充电记录更正申请（需填写原因）
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "更正申请ID"
+// @Param request body ReviewCorrectionRequest true "驳回原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/records/corrections/{id}/reject [post]
+func RejectCorrectionRequest(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	var req ReviewCorrectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "驳回原因不能为空", "error": err.Error()})
+		return
+	}
+	if err := service.RejectCorrectionRequest(c, adminUser.ID, uint(id), req.Reason); err != nil {
+		utils.ErrorCtx(c, "驳回更正申请失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "驳回更正申请失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更正申请已驳回"})
+}
+
+// GetFlaggedRecords 管理员获取异常充电记录队列
+// @Summary 获取异常充电记录队列
+// @Description 获取异常检测分数达到阈值的充电记录及异常原因，默认返回待审核的记录
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "审核状态(submitted/approved/rejected)"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/records/flagged [get]
+func GetFlaggedRecords(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ReviewStatusSubmitted)
+	records, err := service.GetFlaggedRecords(c, status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取异常记录失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": records})
+}
+
+// AdminVoidRecord 管理员作废充电记录
+// @Summary 作废充电记录
+// @Description 管理员随时作废任意充电记录（需填写原因），并通知记录所属用户
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "记录ID"
+// @Param request body VoidRecordRequest true "作废原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/records/{id}/void [post]
+func AdminVoidRecord(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	recordID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	var req VoidRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "作废原因不能为空", "error": err.Error()})
+		return
+	}
+	record, err := service.VoidRecord(c, adminUser.ID, uint(recordID), req.Reason, true)
+	if err != nil {
+		if err.Error() == "充电记录不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "充电记录不存在"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "作废充电记录失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "充电记录已作废", "data": record.FormatRecordInfo()})
+}
+
+// GetAdminOutstandingUploads 管理员获取待上传充电记录的预约
+// @Summary 获取待上传充电记录的预约
+// @Description 获取全部用户已结束、未取消但尚未上传充电记录的预约，可按月份和用户筛选
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param month query string false "月份(YYYY-MM)，为空时不限"
+// @Param user_id query int false "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/records/outstanding [get]
+func GetAdminOutstandingUploads(c *gin.Context) {
+	var userID uint64
+	if value := c.Query("user_id"); value != "" {
+		var err error
+		userID, err = strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "用户ID格式错误", "error": err.Error()})
+			return
+		}
+	}
+	items, err := service.GetOutstandingUploads(c, uint(userID), c.Query("month"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取待上传记录失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": items})
+}
+
+// SendUploadReminder 管理员提醒用户补传充电记录
+// @Summary 提醒补传充电记录
+// @Description 向已结束但未上传充电记录的预约所属用户发送站内提醒，并记录提醒历史
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "预约ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/reservations/{id}/remind [post]
+func SendUploadReminder(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	reservationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	reminder, err := service.SendUploadReminder(c, adminUser.ID, uint(reservationID))
+	if err != nil {
+		if err.Error() == "预约不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "预约不存在"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "发送提醒失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "提醒已发送", "data": reminder})
+}
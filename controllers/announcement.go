@@ -0,0 +1,208 @@
+package controllers
+
+import (
+	"net/http"
+	"shared-charge/service"
+	"shared-charge/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublishAnnouncementRequest 发布公告请求
+type PublishAnnouncementRequest struct {
+	Kind    string `json:"kind" binding:"required,oneof=notice rules"`
+	Title   string `json:"title" binding:"required,max=100"`
+	Content string `json:"content" binding:"required"`
+}
+
+// AcceptRulesRequest 同意充电规则请求
+type AcceptRulesRequest struct {
+	Version int `json:"version" binding:"required"`
+}
+
+// GetAnnouncements 获取群组公告
+// @Summary 获取群组公告
+// @Description 获取群组公告和各版本充电规则，最新的在前；rules_status 为当前充电规则及是否已同意
+// @Tags 公告
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param kind query string false "类型(notice、rules)，为空返回全部"
+// @Success 200 {object} map[string]interface{}
+// @Router /announcements [get]
+func GetAnnouncements(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	announcements, err := service.GetAnnouncements(c, c.Query("kind"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取公告失败"})
+		return
+	}
+	status, err := service.GetRulesStatus(c, userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取充电规则失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": gin.H{"list": announcements, "rules_status": status}})
+}
+
+// GetRulesStatus 获取当前充电规则及是否已同意
+// @Summary 获取当前充电规则
+// @Description 获取当前版本的充电规则及当前用户是否已同意，未同意时不能预约；尚未发布规则时 rules 为空
+// @Tags 公告
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /announcements/rules [get]
+func GetRulesStatus(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	status, err := service.GetRulesStatus(c, userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取充电规则失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": status})
+}
+
+// AcceptRules 同意当前版本的充电规则
+// @Summary 同意充电规则
+// @Description 同意当前版本的充电规则，version 需为当前版本号，规则已更新时返回 409
+// @Tags 公告
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body AcceptRulesRequest true "规则版本号"
+// @Success 200 {object} map[string]interface{}
+// @Router /announcements/rules/accept [post]
+func AcceptRules(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	var req AcceptRulesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "规则版本号不能为空", "error": err.Error()})
+		return
+	}
+	status, err := service.AcceptRules(c, userModel.ID, req.Version)
+	if err != nil {
+		switch err.Error() {
+		case "充电规则已更新，请阅读最新版本后再同意":
+			c.JSON(http.StatusConflict, gin.H{"code": 409, "message": err.Error()})
+		case "暂无需要同意的充电规则":
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "同意充电规则失败"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": status})
+}
+
+// AdminGetAnnouncements 管理员获取公告列表
+// @Summary 获取公告列表（管理员）
+// @Description 获取已发布且未撤回的公告和充电规则，最新的在前
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param kind query string false "类型(notice、rules)，为空返回全部"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/announcements [get]
+func AdminGetAnnouncements(c *gin.Context) {
+	announcements, err := service.GetAnnouncements(c, c.Query("kind"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取公告失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": announcements, "total": len(announcements)})
+}
+
+// PublishAnnouncement 管理员发布公告或新版充电规则
+// @Summary 发布公告
+// @Description 发布普通公告(notice)或新版充电规则(rules)，版本号在同类型内递增并通知成员；发布充电规则后成员需同意新版本才能预约
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body PublishAnnouncementRequest true "类型、标题和内容"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/announcements [post]
+func PublishAnnouncement(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	var req PublishAnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误", "error": err.Error()})
+		return
+	}
+	data, err := service.PublishAnnouncement(c, adminUser.ID, req.Kind, req.Title, req.Content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "发布公告失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": data})
+}
+
+// WithdrawAnnouncement 管理员撤回公告
+// @Summary 撤回公告
+// @Description 撤回公告；撤回当前版本的充电规则后，上一版本重新生效
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "公告ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/announcements/{id} [delete]
+func WithdrawAnnouncement(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "公告ID格式错误", "error": err.Error()})
+		return
+	}
+	if err := service.WithdrawAnnouncement(c, uint(id)); err != nil {
+		if err.Error() == "公告不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "公告不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "撤回公告失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success"})
+}
+
+// GetRuleAcceptances 管理员查看成员对充电规则的同意情况
+// @Summary 查看充电规则同意情况
+// @Description 查看正常和暂停状态的成员是否已同意指定版本（默认当前版本）的充电规则，未同意的在前
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param version query int false "规则版本号，默认当前版本"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/announcements/rules/acceptances [get]
+func GetRuleAcceptances(c *gin.Context) {
+	version, ok := queryUintParam(c, "version", "参数version格式错误")
+	if !ok {
+		return
+	}
+	data, err := service.GetRuleAcceptances(c, int(version))
+	if err != nil {
+		if err.Error() == "尚未发布充电规则" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取充电规则同意情况失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": data})
+}
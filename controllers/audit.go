@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"shared-charge/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogs 管理员查看审计日志
+// @Summary 获取审计日志
+// @Description 分页查看管理操作和影响金额的操作记录（操作人、操作、对象、修改前后的值、trace_id、IP），最新的在前；action 以 * 结尾时按前缀匹配，如 user.*
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param actor_id query int false "操作人ID"
+// @Param action query string false "操作，如 user.unit_price、record.*"
+// @Param target_type query string false "对象类型，如 user、record、discount_rule"
+// @Param target_id query int false "对象ID"
+// @Param from query string false "开始日期(YYYY-MM-DD)"
+// @Param to query string false "结束日期(YYYY-MM-DD)"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认50，最多200"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/audit_logs [get]
+func GetAuditLogs(c *gin.Context) {
+	actorID, ok := queryUintParam(c, "actor_id", "操作人ID格式错误")
+	if !ok {
+		return
+	}
+	targetID, ok := queryUintParam(c, "target_id", "对象ID格式错误")
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	logs, total, err := service.GetAuditLogs(c, service.AuditLogFilter{
+		ActorID:    actorID,
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   targetID,
+		From:       c.Query("from"),
+		To:         c.Query("to"),
+		Page:       page,
+		PageSize:   pageSize,
+	})
+	if err != nil {
+		if err.Error() == "日期格式错误，应为YYYY-MM-DD" {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取审计日志失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": logs, "total": total})
+}
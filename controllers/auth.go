@@ -3,8 +3,10 @@ package controllers
 import (
 	"net/http"
 	"shared-charge/config"
+	"shared-charge/models"
 	"shared-charge/service"
 	"shared-charge/utils"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/silenceper/wechat/v2"
@@ -13,18 +15,33 @@ import (
 )
 
 type WechatLoginRequest struct {
-	Code      string `json:"code" binding:"required"`
-	PhoneCode string `json:"phoneCode"`
+	Code       string `json:"code" binding:"required"`
+	PhoneCode  string `json:"phoneCode"`
+	InviteCode string `json:"inviteCode"`
 }
 
 type WechatLoginResponse struct {
-	Token    string      `json:"token"`
-	UserInfo interface{} `json:"user_info"`
+	Token            string      `json:"token"`
+	ExpiresAt        time.Time   `json:"expires_at"`
+	RefreshToken     string      `json:"refresh_token"`
+	RefreshExpiresAt time.Time   `json:"refresh_expires_at"`
+	UserInfo         interface{} `json:"user_info"`
+}
+
+// newLoginResponse 组装登录和刷新令牌的返回数据
+func newLoginResponse(pair service.TokenPair, userInfo interface{}) WechatLoginResponse {
+	return WechatLoginResponse{
+		Token:            pair.Token,
+		ExpiresAt:        pair.ExpiresAt,
+		RefreshToken:     pair.RefreshToken,
+		RefreshExpiresAt: pair.RefreshExpiresAt,
+		UserInfo:         userInfo,
+	}
 }
 
 // WechatLogin 微信登录
 // @Summary 微信小程序登录
-// @Description 微信小程序登录，获取token和用户信息
+// @Description 微信小程序登录，获取短期访问令牌(token)、刷新令牌(refresh_token)和用户信息；新用户填写有效邀请码(inviteCode)直接加入，否则进入待审核状态并返回403，待审核用户也可在登录时补填邀请码
 // @Tags 认证
 // @Accept json
 // @Produce json
@@ -72,21 +89,39 @@ func WechatLogin(c *gin.Context) {
 	user, err := service.GetUserByOpenID(authResult.OpenID)
 	if err != nil {
 		utils.InfoCtx(c, "创建新用户: openid=%s", authResult.OpenID)
+		// 没有有效邀请码的新用户需要管理员审核
 		userToCreate := service.UserCreateInput{
-			OpenID: authResult.OpenID,
-			Name:   "",
-			Phone:  phone,
-			Role:   "user",
-			Status: "active",
+			OpenID:     authResult.OpenID,
+			Name:       "",
+			Phone:      phone,
+			Role:       models.UserRoleUser,
+			Status:     models.UserStatusPendingApproval,
+			InviteCode: req.InviteCode,
 		}
-		user, err = service.CreateUserWithInput(userToCreate)
+		user, err = service.CreateUserWithInput(c, userToCreate)
 		if err != nil {
+			if err.Error() == "邀请码无效或已过期" {
+				c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+				return
+			}
 			utils.ErrorCtx(c, "创建用户失败: %v", err)
 			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "创建用户失败"})
 			return
 		}
-		utils.InfoCtx(c, "新用户创建成功: user_id=%d", user.ID)
-	} else if phone != "" && user.Phone == "" {
+		utils.InfoCtx(c, "新用户创建成功: user_id=%d, status=%s", user.ID, user.Status)
+	} else if user.Status == models.UserStatusPendingApproval && req.InviteCode != "" {
+		if err := service.RedeemInviteCodeForUser(c, &user, req.InviteCode); err != nil {
+			if err.Error() == "邀请码无效或已过期" {
+				c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "兑换邀请码失败"})
+			return
+		}
+	} else {
+		service.LiftExpiredSuspension(c, &user)
+	}
+	if phone != "" && user.Phone == "" {
 		utils.InfoCtx(c, "更新用户手机号: user_id=%d", user.ID)
 		// 如果用户已存在但没有手机号，且本次获取到了手机号，则更新手机号
 		err := service.UpdateUserPhoneByID(c, user.ID, phone)
@@ -96,47 +131,75 @@ func WechatLogin(c *gin.Context) {
 			return
 		}
 	}
-	formattedUser := user.FormatUserInfo()
-	token, err := utils.GenerateToken(formattedUser)
+	// 待审核、暂停或已移除的用户不签发令牌，手机号仍会更新便于管理员审核
+	if !user.IsActive() {
+		utils.WarnCtx(c, "非正常状态用户登录被拒绝: user_id=%d, status=%s", user.ID, user.Status)
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": user.InactiveMessage(), "status": user.Status})
+		return
+	}
+	pair, err := service.IssueTokens(c, &user)
 	if err != nil {
-		utils.ErrorCtx(c, "生成令牌失败: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成令牌失败", "error": err.Error()})
 		return
 	}
 	utils.InfoCtx(c, "用户登录成功: user_id=%d", user.ID)
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "登录成功", "data": WechatLoginResponse{Token: token, UserInfo: formattedUser}})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "登录成功", "data": newLoginResponse(pair, user.FormatUserInfo())})
+}
+
+// RefreshTokenRequest 刷新令牌请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
 }
 
 // RefreshToken 刷新令牌
 // @Summary 刷新令牌
-// @Description 刷新用户token
+// @Description 用刷新令牌换取新的访问令牌和刷新令牌，旧刷新令牌随即失效；已使用过的刷新令牌再次使用时注销整个会话
 // @Tags 认证
 // @Accept json
 // @Produce json
-// @Security BearerAuth
-// @Success 200 {object} map[string]interface{}
+// @Param request body RefreshTokenRequest true "刷新令牌"
+// @Success 200 {object} controllers.WechatLoginResponse
 // @Router /auth/refresh [post]
 func RefreshToken(c *gin.Context) {
 	utils.InfoCtx(c, "刷新令牌请求")
-	userModel, ok := utils.GetUserFromContext(c)
-	if !ok {
-		utils.WarnCtx(c, "刷新令牌未认证")
-		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户未认证"})
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "刷新令牌不能为空", "error": err.Error()})
 		return
 	}
-	userData, err := service.GetUserByID(c, userModel.ID)
+	pair, user, err := service.RotateRefreshToken(c, req.RefreshToken)
 	if err != nil {
-		utils.ErrorCtx(c, "刷新令牌获取用户信息失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取用户信息失败", "error": err.Error()})
+		switch err.Error() {
+		case "刷新令牌无效或已过期", "刷新令牌已被使用，会话已注销，请重新登录":
+			c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": err.Error()})
+		case "用户状态异常，无法刷新令牌":
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "刷新令牌失败"})
+		}
 		return
 	}
-	formattedUser := userData.FormatUserInfo()
-	token, err := utils.GenerateToken(formattedUser)
-	if err != nil {
-		utils.ErrorCtx(c, "刷新令牌生成失败: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成令牌失败", "error": err.Error()})
+	utils.InfoCtx(c, "令牌刷新成功: user_id=%d", user.ID)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "令牌刷新成功", "data": newLoginResponse(pair, user.FormatUserInfo())})
+}
+
+// Logout 退出登录
+// @Summary 退出登录
+// @Description 注销当前会话：刷新令牌失效，当前访问令牌加入黑名单
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /auth/logout [post]
+func Logout(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	if err := service.Logout(c, userModel.ID, c.GetString(utils.SessionIDKey)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "退出登录失败"})
 		return
 	}
-	utils.InfoCtx(c, "令牌刷新成功: user_id=%d", userData.ID)
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "令牌刷新成功", "data": gin.H{"token": token, "user_info": formattedUser}})
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "已退出登录"})
 }
@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"net/http"
+	"shared-charge/service"
+	"shared-charge/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// BulkUnitPriceRequest 批量修改电价请求
+type BulkUnitPriceRequest struct {
+	Filter        service.BulkUserFilter `json:"filter"`
+	UnitPrice     decimal.Decimal        `json:"unit_price" binding:"required" swaggertype:"number"`
+	EffectiveDate string                 `json:"effective_date"`
+	DryRun        bool                   `json:"dry_run"`
+}
+
+// BulkCanReserveRequest 批量修改可预约状态请求
+type BulkCanReserveRequest struct {
+	Filter     service.BulkUserFilter `json:"filter"`
+	CanReserve bool                   `json:"can_reserve"`
+	DryRun     bool                   `json:"dry_run"`
+}
+
+// BulkStatusRequest 批量暂停或恢复成员请求
+type BulkStatusRequest struct {
+	Filter service.BulkUserFilter `json:"filter"`
+	Status string                 `json:"status" binding:"required,oneof=active suspended"`
+	Reason string                 `json:"reason"`
+	Until  string                 `json:"until"`
+	DryRun bool                   `json:"dry_run"`
+}
+
+// respondBulkUsers 返回批量操作结果
+func respondBulkUsers(c *gin.Context, data map[string]interface{}, err error, failMessage string) {
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": failMessage, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": data})
+}
+
+// BulkUpdateUnitPrice 管理员批量修改成员电价
+// @Summary 批量修改电价
+// @Description 在一个事务内修改筛选出的成员电价；effective_date 为空时立即生效，否则为每个成员创建电价计划。dry_run 为 true 时只返回预览，每个变更的成员写入一条审计日志
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BulkUnitPriceRequest true "筛选条件、电价、生效日期(YYYY-MM-DD)和是否预览"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/bulk/unit_price [post]
+func BulkUpdateUnitPrice(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	var req BulkUnitPriceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误", "error": err.Error()})
+		return
+	}
+	data, err := service.BulkUpdateUnitPrice(c, adminUser.ID, req.Filter, req.UnitPrice, req.EffectiveDate, req.DryRun)
+	respondBulkUsers(c, data, err, "批量修改电价失败")
+}
+
+// BulkUpdateCanReserve 管理员批量修改成员可预约状态
+// @Summary 批量修改可预约状态
+// @Description 在一个事务内修改筛选出的成员是否可预约，dry_run 为 true 时只返回预览，每个变更的成员写入一条审计日志
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BulkCanReserveRequest true "筛选条件、是否可预约和是否预览"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/bulk/can_reserve [post]
+func BulkUpdateCanReserve(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	var req BulkCanReserveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误", "error": err.Error()})
+		return
+	}
+	data, err := service.BulkUpdateCanReserve(c, adminUser.ID, req.Filter, req.CanReserve, req.DryRun)
+	respondBulkUsers(c, data, err, "批量修改可预约状态失败")
+}
+
+// BulkUpdateStatus 管理员批量暂停或恢复成员
+// @Summary 批量暂停或恢复成员
+// @Description 在一个事务内暂停(suspended，需填写原因，可选截止日期)或恢复(active)筛选出的成员，规则与单个操作相同，操作人自己会被跳过。dry_run 为 true 时只返回预览，每个变更的成员写入一条审计日志
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body BulkStatusRequest true "筛选条件、目标状态、暂停原因、截止日期(YYYY-MM-DD)和是否预览"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/bulk/status [post]
+func BulkUpdateStatus(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	var req BulkStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "状态无效，仅支持active和suspended", "error": err.Error()})
+		return
+	}
+	data, err := service.BulkUpdateStatus(c, adminUser.ID, req.Filter, req.Status, req.Reason, req.Until, req.DryRun)
+	respondBulkUsers(c, data, err, "批量修改成员状态失败")
+}
@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"net/http"
+	"shared-charge/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAdminDashboard 管理端首页概览
+// @Summary 管理端首页概览
+// @Description 一次返回今天和明天的预约、待上传记录、异常和待审核记录、上月未支付账单、待审核新用户以及本月累计数据；各模块并发加载，失败或超时的模块为 null 并在 errors 中说明
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/dashboard [get]
+func GetAdminDashboard(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": service.GetAdminDashboard(c)})
+}
@@ -0,0 +1,145 @@
+package controllers
+
+import (
+	"net/http"
+	"shared-charge/service"
+	"shared-charge/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// DiscountRuleRequest 创建/更新优惠规则请求
+type DiscountRuleRequest struct {
+	Name           string          `json:"name" binding:"required"`
+	Type           string          `json:"type" binding:"required,oneof=percentage fixed_credit free_kwh"`
+	Value          decimal.Decimal `json:"value" binding:"required" swaggertype:"number"`
+	Scope          string          `json:"scope" binding:"required,oneof=user plate all"`
+	UserID         *uint           `json:"user_id"`
+	LicensePlateID *uint           `json:"license_plate_id"`
+	StartDate      string          `json:"start_date"`
+	EndDate        string          `json:"end_date"`
+	Active         *bool           `json:"active"`
+}
+
+func (r DiscountRuleRequest) toService() service.DiscountRuleRequest {
+	return service.DiscountRuleRequest{
+		Name:           r.Name,
+		Type:           r.Type,
+		Value:          r.Value,
+		Scope:          r.Scope,
+		UserID:         r.UserID,
+		LicensePlateID: r.LicensePlateID,
+		StartDate:      r.StartDate,
+		EndDate:        r.EndDate,
+		Active:         r.Active,
+	}
+}
+
+// GetDiscountRules 管理员获取优惠规则列表
+// @Summary 获取优惠规则列表
+// @Description 获取全部优惠规则（折扣、固定抵扣、每月免费度数）
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/discounts [get]
+func GetDiscountRules(c *gin.Context) {
+	rules, err := service.GetDiscountRules(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取优惠规则失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": rules})
+}
+
+// CreateDiscountRule 管理员创建优惠规则
+// @Summary 创建优惠规则
+// @Description 创建优惠规则，type: percentage(value为折扣百分比)、fixed_credit(value为每月抵扣金额，元)、free_kwh(value为每月免费度数)；scope: user/plate/all
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body DiscountRuleRequest true "优惠规则"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/discounts [post]
+func CreateDiscountRule(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	var req DiscountRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误", "error": err.Error()})
+		return
+	}
+	rule, err := service.CreateDiscountRule(c, adminUser.ID, req.toService())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "创建优惠规则失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "创建成功", "data": rule.FormatDiscountRuleInfo()})
+}
+
+// UpdateDiscountRule 管理员修改优惠规则
+// @Summary 修改优惠规则
+// @Description 修改优惠规则，仅影响之后计算费用的记录
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "规则ID"
+// @Param request body DiscountRuleRequest true "优惠规则"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/discounts/{id} [put]
+func UpdateDiscountRule(c *gin.Context) {
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	var req DiscountRuleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误", "error": err.Error()})
+		return
+	}
+	rule, err := service.UpdateDiscountRule(c, uint(ruleID), req.toService())
+	if err != nil {
+		if err.Error() == "优惠规则不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "优惠规则不存在"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "修改优惠规则失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "修改成功", "data": rule.FormatDiscountRuleInfo()})
+}
+
+// DeleteDiscountRule 管理员删除优惠规则
+// @Summary 删除优惠规则
+// @Description 删除优惠规则，已应用到记录上的优惠明细保留
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "规则ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/discounts/{id} [delete]
+func DeleteDiscountRule(c *gin.Context) {
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	if err := service.DeleteDiscountRule(c, uint(ruleID)); err != nil {
+		if err.Error() == "优惠规则不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "优惠规则不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "删除优惠规则失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "删除成功"})
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"net/http"
+	"shared-charge/service"
+	"shared-charge/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportOptionsFromQuery 解析导出格式与表头语言，默认导出中文表头的 XLSX
+func exportOptionsFromQuery(c *gin.Context) (string, string, bool) {
+	format := c.DefaultQuery("format", service.ExportFormatXLSX)
+	if format != service.ExportFormatCSV && format != service.ExportFormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "导出格式错误，应为csv或xlsx"})
+		return "", "", false
+	}
+	lang := c.DefaultQuery("lang", service.ExportLangZH)
+	if lang != service.ExportLangZH && lang != service.ExportLangEN {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "语言参数错误，应为zh或en"})
+		return "", "", false
+	}
+	return format, lang, true
+}
+
+// queryUintParam 解析可选的数字查询参数，未传时返回0
+func queryUintParam(c *gin.Context, key, message string) (uint, bool) {
+	value := c.Query(key)
+	if value == "" {
+		return 0, true
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": message, "error": err.Error()})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// recordExportFilterFromQuery 从查询参数解析充电记录导出条件
+func recordExportFilterFromQuery(c *gin.Context) (service.RecordExportFilter, bool) {
+	startDate, endDate, err := service.ParseExportDateRange(c.Query("from"), c.Query("to"), c.Query("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return service.RecordExportFilter{}, false
+	}
+	plateID, ok := queryUintParam(c, "license_plate_id", "车牌号ID格式错误")
+	if !ok {
+		return service.RecordExportFilter{}, false
+	}
+	return service.RecordExportFilter{
+		LicensePlateID: plateID,
+		StartDate:      startDate,
+		EndDate:        endDate,
+		ReviewStatus:   c.Query("review_status"),
+		Timeslot:       c.Query("timeslot"),
+		IncludeVoided:  c.Query("include_voided") == "true",
+	}, true
+}
+
+// finishExport 导出失败时，若尚未写出内容则返回错误响应；已开始写出时中断连接，避免客户端收到看似完整的截断文件
+func finishExport(c *gin.Context, err error) {
+	if err == nil {
+		return
+	}
+	if c.Writer.Written() {
+		utils.ErrorCtx(c, "导出中断: %v", err)
+		utils.AbortStream(c)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "导出失败"})
+}
+
+// ExportMyRecords 成员导出自己的充电记录
+// @Summary 导出我的充电记录
+// @Description 按条件导出当前用户的充电记录，支持CSV和XLSX，金额单位为元
+// @Tags 充电记录
+// @Produce application/octet-stream
+// @Security BearerAuth
+// @Param format query string false "导出格式 csv|xlsx，默认xlsx"
+// @Param lang query string false "表头语言 zh|en，默认zh"
+// @Param from query string false "开始日期(YYYY-MM-DD)"
+// @Param to query string false "结束日期(YYYY-MM-DD)"
+// @Param month query string false "月份(YYYY-MM)，未传from/to时使用"
+// @Param license_plate_id query int false "车牌号ID"
+// @Param review_status query string false "审核状态 submitted|approved|rejected"
+// @Param timeslot query string false "班次 day|night"
+// @Param include_voided query bool false "是否包含已作废记录"
+// @Success 200 {file} file
+// @Router /records/export [get]
+func ExportMyRecords(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	format, lang, ok := exportOptionsFromQuery(c)
+	if !ok {
+		return
+	}
+	filter, ok := recordExportFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	filter.UserID = userModel.ID
+	finishExport(c, service.ExportRecords(c, filter, format, lang))
+}
+
+// AdminExportRecords 管理员导出充电记录
+// @Summary 导出充电记录（管理员）
+// @Description 按条件导出全部或指定用户的充电记录，支持CSV和XLSX，金额单位为元
+// @Tags 管理员
+// @Produce application/octet-stream
+// @Security BearerAuth
+// @Param format query string false "导出格式 csv|xlsx，默认xlsx"
+// @Param lang query string false "表头语言 zh|en，默认zh"
+// @Param from query string false "开始日期(YYYY-MM-DD)"
+// @Param to query string false "结束日期(YYYY-MM-DD)"
+// @Param month query string false "月份(YYYY-MM)，未传from/to时使用"
+// @Param user_id query int false "用户ID"
+// @Param license_plate_id query int false "车牌号ID"
+// @Param review_status query string false "审核状态 submitted|approved|rejected"
+// @Param timeslot query string false "班次 day|night"
+// @Param include_voided query bool false "是否包含已作废记录"
+// @Success 200 {file} file
+// @Router /admin/export/records [get]
+func AdminExportRecords(c *gin.Context) {
+	format, lang, ok := exportOptionsFromQuery(c)
+	if !ok {
+		return
+	}
+	filter, ok := recordExportFilterFromQuery(c)
+	if !ok {
+		return
+	}
+	if filter.UserID, ok = queryUintParam(c, "user_id", "用户ID格式错误"); !ok {
+		return
+	}
+	finishExport(c, service.ExportRecords(c, filter, format, lang))
+}
+
+// AdminExportReservations 管理员导出预约
+// @Summary 导出预约（管理员）
+// @Description 按条件导出预约及是否已上传充电记录，支持CSV和XLSX
+// @Tags 管理员
+// @Produce application/octet-stream
+// @Security BearerAuth
+// @Param format query string false "导出格式 csv|xlsx，默认xlsx"
+// @Param lang query string false "表头语言 zh|en，默认zh"
+// @Param from query string false "开始日期(YYYY-MM-DD)"
+// @Param to query string false "结束日期(YYYY-MM-DD)"
+// @Param month query string false "月份(YYYY-MM)，未传from/to时使用"
+// @Param user_id query int false "用户ID"
+// @Param status query string false "状态 pending|confirmed|cancelled|completed"
+// @Success 200 {file} file
+// @Router /admin/export/reservations [get]
+func AdminExportReservations(c *gin.Context) {
+	format, lang, ok := exportOptionsFromQuery(c)
+	if !ok {
+		return
+	}
+	startDate, endDate, err := service.ParseExportDateRange(c.Query("from"), c.Query("to"), c.Query("month"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	userID, ok := queryUintParam(c, "user_id", "用户ID格式错误")
+	if !ok {
+		return
+	}
+	filter := service.ReservationExportFilter{
+		UserID:    userID,
+		StartDate: startDate,
+		EndDate:   endDate,
+		Status:    c.Query("status"),
+	}
+	finishExport(c, service.ExportReservations(c, filter, format, lang))
+}
+
+// AdminExportMonthlyReport 管理员导出月度对账
+// @Summary 导出月度对账（管理员）
+// @Description 导出月度对账，XLSX第一个sheet为汇总，之后每个车牌号一个sheet列出审核通过的记录；CSV各部分以空行分隔
+// @Tags 管理员
+// @Produce application/octet-stream
+// @Security BearerAuth
+// @Param month query string true "月份(YYYY-MM)"
+// @Param format query string false "导出格式 csv|xlsx，默认xlsx"
+// @Param lang query string false "表头语言 zh|en，默认zh"
+// @Success 200 {file} file
+// @Router /admin/export/monthly_report [get]
+func AdminExportMonthlyReport(c *gin.Context) {
+	format, lang, ok := exportOptionsFromQuery(c)
+	if !ok {
+		return
+	}
+	month := c.Query("month")
+	if _, _, err := service.ParseExportDateRange("", "", month); err != nil || month == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+		return
+	}
+	finishExport(c, service.ExportMonthlyReport(c, month, format, lang))
+}
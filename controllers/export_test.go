@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"shared-charge/config"
+	"shared-charge/utils"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newExportContext(t *testing.T) (*gin.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	config.LoadConfig()
+	if err := utils.InitLogger("dev", "error", ""); err != nil {
+		t.Fatalf("初始化日志失败: %v", err)
+	}
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/api/records/export?format=csv", nil)
+	return c, w
+}
+
+func TestFinishExportBeforeWriteReturnsError(t *testing.T) {
+	c, w := newExportContext(t)
+	finishExport(c, errors.New("查询失败"))
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", w.Code)
+	}
+}
+
+func TestFinishExportMidStreamAbortsConnection(t *testing.T) {
+	c, _ := newExportContext(t)
+	if _, err := c.Writer.Write([]byte("日期,度数\n")); err != nil {
+		t.Fatal(err)
+	}
+	// 测试用的 ResponseRecorder 不支持劫持连接，此时应由 net/http 中止响应
+	defer func() {
+		if r := recover(); r != http.ErrAbortHandler {
+			t.Fatalf("已写出内容后应中断连接，recover = %v", r)
+		}
+		if !c.IsAborted() {
+			t.Fatal("请求应被标记为中止")
+		}
+	}()
+	finishExport(c, errors.New("写出失败"))
+}
@@ -0,0 +1,94 @@
+package controllers
+
+import (
+	"net/http"
+	"shared-charge/service"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forecastMonthFromQuery 解析预测月份，默认本月
+func forecastMonthFromQuery(c *gin.Context) (string, bool) {
+	month := c.DefaultQuery("month", time.Now().Format("2006-01"))
+	if len(month) != 7 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+		return "", false
+	}
+	return month, true
+}
+
+// respondForecast 返回预测结果，月份错误时返回400
+func respondForecast(c *gin.Context, resp map[string]interface{}, err error) {
+	if err != nil {
+		if err.Error() == "月份格式错误" {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "计算月末预测失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": resp})
+}
+
+// GetForecast 成员月末用电量和费用预测
+// @Summary 获取月末预测
+// @Description 根据本月已上传的记录、尚未上传记录的预约和近90天平均每次充电度数，预测当前用户月末的用电量和费用及80%置信区间；预约按当日适用电价（含待生效的电价计划）估算，不计优惠
+// @Tags 统计
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param month query string false "月份(YYYY-MM)，默认本月"
+// @Success 200 {object} map[string]interface{}
+// @Router /statistics/forecast [get]
+func GetForecast(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	month, ok := forecastMonthFromQuery(c)
+	if !ok {
+		return
+	}
+	resp, err := service.GetMemberForecast(c, userModel.ID, month)
+	respondForecast(c, resp, err)
+}
+
+// GetGroupForecast 成员查看群组月末用电量预测
+// @Summary 获取群组月末预测
+// @Description 预测全体成员月末的总用电量及80%置信区间，不含费用和他人明细
+// @Tags 统计
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param month query string false "月份(YYYY-MM)，默认本月"
+// @Success 200 {object} map[string]interface{}
+// @Router /statistics/forecast/group [get]
+func GetGroupForecast(c *gin.Context) {
+	month, ok := forecastMonthFromQuery(c)
+	if !ok {
+		return
+	}
+	resp, err := service.GetGroupForecast(c, month, false)
+	respondForecast(c, resp, err)
+}
+
+// GetAdminForecast 管理员查看群组月末预测
+// @Summary 获取群组月末预测（管理员）
+// @Description 预测全体成员月末的用电量和费用及80%置信区间，包含按成员明细
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param month query string false "月份(YYYY-MM)，默认本月"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/statistics/forecast [get]
+func GetAdminForecast(c *gin.Context) {
+	month, ok := forecastMonthFromQuery(c)
+	if !ok {
+		return
+	}
+	resp, err := service.GetGroupForecast(c, month, true)
+	respondForecast(c, resp, err)
+}
@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"net/http"
+	"shared-charge/service"
+	"shared-charge/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// InviteCodeRequest 创建邀请码请求
+type InviteCodeRequest struct {
+	Code       string           `json:"code"`
+	Remark     string           `json:"remark"`
+	ExpiresOn  string           `json:"expires_on"`
+	MaxUses    int              `json:"max_uses"`
+	UnitPrice  *decimal.Decimal `json:"unit_price" swaggertype:"number"`
+	CanReserve bool             `json:"can_reserve"`
+}
+
+// GetInviteCodes 管理员获取邀请码列表
+// @Summary 获取邀请码列表
+// @Description 获取全部邀请码及剩余次数、是否可用
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/invite_codes [get]
+func GetInviteCodes(c *gin.Context) {
+	codes, err := service.GetInviteCodes(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取邀请码失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": codes})
+}
+
+// CreateInviteCode 管理员创建邀请码
+// @Summary 创建邀请码
+// @Description 创建群组邀请码：code 为空时自动生成；expires_on 为最后可用日期(YYYY-MM-DD，含当天)，为空不过期；max_uses 为 0 不限次数；unit_price 为空时使用默认电价；can_reserve 为兑换用户是否可预约
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body InviteCodeRequest true "邀请码"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/invite_codes [post]
+func CreateInviteCode(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	var req InviteCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误", "error": err.Error()})
+		return
+	}
+	code, err := service.CreateInviteCode(c, adminUser.ID, service.InviteCodeInput{
+		Code:       req.Code,
+		Remark:     req.Remark,
+		ExpiresOn:  req.ExpiresOn,
+		MaxUses:    req.MaxUses,
+		UnitPrice:  req.UnitPrice,
+		CanReserve: req.CanReserve,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "创建邀请码失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": code})
+}
+
+// DisableInviteCode 管理员停用邀请码
+// @Summary 停用邀请码
+// @Description 停用邀请码，之后不能再兑换，已加入的用户不受影响
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "邀请码ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/invite_codes/{id} [delete]
+func DisableInviteCode(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	if err := service.DisableInviteCode(c, uint(id)); err != nil {
+		if err.Error() == "邀请码不存在或已停用" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "停用邀请码失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success"})
+}
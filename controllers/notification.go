@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"net/http"
+	"shared-charge/service"
+	"shared-charge/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetNotifications 获取当前用户通知列表
+// @Summary 获取通知列表
+// @Description 获取当前用户的站内通知（如充电记录审核结果）
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param unread query bool false "仅返回未读通知"
+// @Success 200 {object} map[string]interface{}
+// @Router /notifications [get]
+func GetNotifications(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	unreadOnly := c.Query("unread") == "true"
+	notifications, unread, err := service.GetUserNotifications(c, userModel.ID, unreadOnly)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取通知失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": gin.H{"list": notifications, "unread": unread}})
+}
+
+// MarkNotificationRead 标记通知为已读
+// @Summary 标记通知已读
+// @Description 将指定通知标记为已读
+// @Tags 通知
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "通知ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /notifications/{id}/read [put]
+func MarkNotificationRead(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	if err := service.MarkNotificationRead(c, userModel.ID, uint(id)); err != nil {
+		if err.Error() == "通知不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "通知不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "标记已读失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success"})
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"net/http"
+	"shared-charge/service"
+	"shared-charge/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// PriceScheduleRequest 创建电价计划请求
+type PriceScheduleRequest struct {
+	UserID        uint            `json:"user_id" binding:"required"`
+	UnitPrice     decimal.Decimal `json:"unit_price" binding:"required" swaggertype:"number"`
+	EffectiveDate string          `json:"effective_date" binding:"required"`
+}
+
+// GetPriceSchedules 管理员获取待生效的电价计划
+// @Summary 获取电价计划
+// @Description 获取尚未生效的电价计划，可按用户筛选
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query int false "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/price_schedules [get]
+func GetPriceSchedules(c *gin.Context) {
+	userID, ok := queryUintParam(c, "user_id", "用户ID格式错误")
+	if !ok {
+		return
+	}
+	schedules, err := service.GetPriceSchedules(c, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取电价计划失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": schedules})
+}
+
+// CreatePriceSchedule 管理员设置未来生效的电价
+// @Summary 创建电价计划
+// @Description 为用户设置从指定日期起生效的新电价，生效日期必须晚于今天；到期后自动写入用户电价
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body PriceScheduleRequest true "电价计划"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/price_schedules [post]
+func CreatePriceSchedule(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	var req PriceScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误", "error": err.Error()})
+		return
+	}
+	schedule, err := service.CreatePriceSchedule(c, adminUser.ID, req.UserID, req.UnitPrice, req.EffectiveDate)
+	if err != nil {
+		if err.Error() == "用户不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "创建电价计划失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": schedule})
+}
+
+// CancelPriceSchedule 管理员取消尚未生效的电价计划
+// @Summary 取消电价计划
+// @Description 取消尚未生效的电价计划，已生效的计划不能取消
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "电价计划ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/price_schedules/{id} [delete]
+func CancelPriceSchedule(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	if err := service.CancelPriceSchedule(c, uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "取消电价计划失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success"})
+}
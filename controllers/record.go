@@ -3,22 +3,24 @@ package controllers
 import (
 	"net/http"
 	"shared-charge/service"
+	"strconv"
+	"time"
 
-	"shared-charge/config"
 	"shared-charge/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 )
 
 // CreateRecordRequest 创建充电记录请求
 type CreateRecordRequest struct {
-	Date           string  `json:"date" binding:"required"`
-	KWH            float64 `json:"kwh" binding:"required,gt=0"`
-	ImageURL       string  `json:"image_url"`
-	Remark         string  `json:"remark"`
-	ReservationID  uint    `json:"reservation_id"`
-	Timeslot       string  `json:"timeslot"`
-	LicensePlateID *uint   `json:"license_plate_id"`
+	Date           string          `json:"date" binding:"required"`
+	KWH            decimal.Decimal `json:"kwh" binding:"required" swaggertype:"number"`
+	ImageURL       string          `json:"image_url"`
+	Remark         string          `json:"remark"`
+	ReservationID  uint            `json:"reservation_id"`
+	Timeslot       string          `json:"timeslot"`
+	LicensePlateID *uint           `json:"license_plate_id"`
 }
 
 // GetRecords 获取充电记录列表
@@ -51,7 +53,7 @@ func GetRecords(c *gin.Context) {
 
 // CreateRecord 创建充电记录
 // @Summary 创建充电记录
-// @Description 创建新的充电记录
+// @Description 创建新的充电记录；启用截图识别时返回识别出的度数(ocr_kwh)、置信度(ocr_confidence)及是否与填写度数不一致(kwh_mismatch)
 // @Tags 充电记录
 // @Accept json
 // @Produce json
@@ -73,14 +75,22 @@ func CreateRecord(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "请求参数错误", "error": err.Error()})
 		return
 	}
+	if !req.KWH.IsPositive() {
+		utils.WarnCtx(c, "创建充电记录度数必须大于0: kwh=%s", req.KWH)
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "充电度数必须大于0"})
+		return
+	}
 	if req.ReservationID == 0 {
 		utils.WarnCtx(c, "创建充电记录缺少预约ID")
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "缺少预约ID"})
 		return
 	}
-	unitPrice := userModel.UnitPrice
-	if unitPrice <= 0 {
-		unitPrice = config.GetConfig().App.DefaultUnitPrice
+	// 从数据库读取电价，确保已到生效日的电价计划生效
+	unitPrice, err := service.GetUserPrice(userModel.ID)
+	if err != nil {
+		utils.ErrorCtx(c, "获取用户电价失败: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取用户电价失败"})
+		return
 	}
 	createReq := service.CreateRecordRequest{
 		UserID:         userModel.ID,
@@ -93,13 +103,16 @@ func CreateRecord(c *gin.Context) {
 		Remark:         req.Remark,   // 修复：传递 remark
 		LicensePlateID: req.LicensePlateID,
 	}
-	if err := service.CreateRecordWithTimeslot(c, createReq); err != nil {
+	record, err := service.CreateRecordWithTimeslot(c, createReq)
+	if err != nil {
 		utils.ErrorCtx(c, "创建充电记录失败: %v", err)
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "创建充电记录失败"})
 		return
 	}
 	utils.InfoCtx(c, "充电记录创建成功: user_id=%d, reservation_id=%d", userModel.ID, req.ReservationID)
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "充电记录创建成功"})
+	data := record.FormatRecordInfo()
+	data["kwh_mismatch"] = service.IsOCRMismatch(record)
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "充电记录创建成功", "data": data})
 }
 
 // GetUnsubmittedRecords 获取当前用户未提交的充电记录
@@ -129,6 +142,30 @@ func GetUnsubmittedRecords(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "获取未提交充电记录成功", "data": records})
 }
 
+// GetOutstandingUploads 获取当前用户待上传充电记录的预约
+// @Summary 获取待上传充电记录的预约
+// @Description 获取当前用户已结束、未取消但尚未上传充电记录的预约，包含超期时长、车牌号和提醒历史
+// @Tags Record
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param month query string false "月份(YYYY-MM)，为空时不限"
+// @Success 200 {object} map[string]interface{}
+// @Router /records/outstanding [get]
+func GetOutstandingUploads(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	items, err := service.GetOutstandingUploads(c, userModel.ID, c.Query("month"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取待上传记录失败", "error": err.Error()})
+		return
+	}
+	utils.InfoCtx(c, "获取待上传记录成功: user_id=%d, count=%d", userModel.ID, len(items))
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": items})
+}
+
 // GetMonthlyStatistics 月度统计
 // @Summary 获取月度累计用电量和费用
 // @Description 获取指定月份的累计用电量和累计费用
@@ -213,12 +250,132 @@ func GetMonthlyShiftStatistics(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": gin.H{"dayKwh": dayKwh, "nightKwh": nightKwh, "totalKwh": totalKwh}})
 }
 
+// statisticsRangeFromQuery 从查询参数解析区间统计范围，解析失败时直接返回400
+func statisticsRangeFromQuery(c *gin.Context) (service.StatisticsRange, bool) {
+	r, err := service.ParseStatisticsRange(service.StatisticsRangeRequest{
+		From:        c.Query("from"),
+		To:          c.Query("to"),
+		Month:       c.Query("month"),
+		Granularity: c.Query("granularity"),
+		Timezone:    c.Query("tz"),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return r, false
+	}
+	return r, true
+}
+
+// GetRangeStatistics 区间统计
+// @Summary 获取区间统计
+// @Description 按日/周/月/年汇总日期范围内的白班、夜班、总用电量和费用，并给出与上一区间相比的变化
+// @Tags 统计
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "开始日期(YYYY-MM-DD)"
+// @Param to query string false "结束日期(YYYY-MM-DD)"
+// @Param month query string false "月份(YYYY-MM)，未传from/to时使用"
+// @Param granularity query string false "统计粒度 day|week|month|year，默认day"
+// @Param tz query string false "时区，如Asia/Shanghai，默认服务器时区"
+// @Success 200 {object} map[string]interface{}
+// @Router /statistics/range [get]
+func GetRangeStatistics(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	r, ok := statisticsRangeFromQuery(c)
+	if !ok {
+		return
+	}
+	resp, err := service.GetRangeStatistics(c, userModel.ID, r)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "数据库查询失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": resp})
+}
+
+// GetVehicleStatistics 按车牌号统计
+// @Summary 获取按车牌号统计
+// @Description 按车牌号汇总当前用户日期范围内的用电量、费用、充电次数和平均每次充电度数，未绑定车牌号的记录单独作为一组；未传from/to/month时统计本月
+// @Tags 统计
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "开始日期(YYYY-MM-DD)"
+// @Param to query string false "结束日期(YYYY-MM-DD)"
+// @Param month query string false "月份(YYYY-MM)，未传from/to时使用，默认本月"
+// @Param tz query string false "时区，如Asia/Shanghai，默认服务器时区"
+// @Success 200 {object} map[string]interface{}
+// @Router /statistics/vehicles [get]
+func GetVehicleStatistics(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	req := service.StatisticsRangeRequest{
+		From:     c.Query("from"),
+		To:       c.Query("to"),
+		Month:    c.Query("month"),
+		Timezone: c.Query("tz"),
+	}
+	if req.From == "" && req.To == "" && req.Month == "" {
+		req.Month = time.Now().Format("2006-01")
+	}
+	r, err := service.ParseStatisticsRange(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": err.Error()})
+		return
+	}
+	resp, err := service.GetVehicleStatistics(c, userModel.ID, r)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "数据库查询失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": resp})
+}
+
+// GetGroupStatistics 成员查看群组月度统计
+// @Summary 获取群组月度统计
+// @Description 获取全体成员的月度用电量、时段使用率、白班/夜班占比和活跃成员数，仅包含自己的用电占比，不含他人明细和费用
+// @Tags 统计
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param month query string true "月份(YYYY-MM)"
+// @Success 200 {object} map[string]interface{}
+// @Router /statistics/group [get]
+func GetGroupStatistics(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	month := c.Query("month")
+	if month == "" || len(month) != 7 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+		return
+	}
+	resp, err := service.GetGroupStatistics(c, month, userModel.ID, false)
+	if err != nil {
+		if err.Error() == "月份格式错误" {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "数据库查询失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": resp})
+}
+
 // UpdateRecordRequest 更新充电记录请求
 type UpdateRecordRequest struct {
-	KWH            float64 `json:"kwh" binding:"required,gt=0"`
-	ImageURL       string  `json:"image_url"`
-	Remark         string  `json:"remark"`
-	LicensePlateID *uint   `json:"license_plate_id"`
+	KWH            decimal.Decimal `json:"kwh" binding:"required" swaggertype:"number"`
+	ImageURL       string          `json:"image_url"`
+	Remark         string          `json:"remark"`
+	LicensePlateID *uint           `json:"license_plate_id"`
+	Reason         string          `json:"reason"`
 }
 
 // GetRecordsList 获取充电记录列表（按月筛选）
@@ -303,7 +460,7 @@ func GetRecordDetail(c *gin.Context) {
 
 // UpdateRecord 更新充电记录
 // @Summary 更新充电记录
-// @Description 根据记录ID更新充电记录信息
+// @Description 根据记录ID更新充电记录信息；超过编辑期限时转为更正申请（返回202）
 // @Tags 充电记录
 // @Accept json
 // @Produce json
@@ -334,12 +491,17 @@ func UpdateRecord(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "请求参数错误", "error": err.Error()})
 		return
 	}
+	if !req.KWH.IsPositive() {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "充电度数必须大于0"})
+		return
+	}
 
-	updatedRecord, err := service.UpdateRecordByID(userModel.ID, recordID, service.UpdateRecordRequest{
+	updatedRecord, err := service.UpdateRecordByID(c, userModel.ID, recordID, service.UpdateRecordRequest{
 		KWH:            req.KWH,
 		ImageURL:       req.ImageURL,
 		Remark:         req.Remark,
 		LicensePlateID: req.LicensePlateID,
+		Reason:         req.Reason,
 	})
 	if err != nil {
 		utils.ErrorCtx(c, "更新充电记录失败: %v", err)
@@ -353,6 +515,87 @@ func UpdateRecord(c *gin.Context) {
 		return
 	}
 
+	if correction, ok := updatedRecord["correction_request"]; ok {
+		utils.InfoCtx(c, "充电记录已超过编辑期限，已提交更正申请: record_id=%s, user_id=%d", recordID, userModel.ID)
+		c.JSON(http.StatusAccepted, gin.H{"code": 202, "message": "已超过编辑期限，更正申请已提交，待管理员审核", "data": correction})
+		return
+	}
+
 	utils.InfoCtx(c, "更新充电记录成功: record_id=%s, user_id=%d", recordID, userModel.ID)
 	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "更新成功", "data": updatedRecord})
 }
+
+// GetRecordRevisions 获取充电记录修订历史
+// @Summary 获取充电记录修订历史
+// @Description 获取当前用户某条充电记录的全部修改历史（修改前后的值、修改人）
+// @Tags 充电记录
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "记录ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /records/{id}/revisions [get]
+func GetRecordRevisions(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	recordID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	revisions, err := service.GetRecordRevisions(c, userModel.ID, uint(recordID))
+	if err != nil {
+		if err.Error() == "充电记录不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "充电记录不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取修订历史失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": revisions})
+}
+
+// VoidRecordRequest 作废充电记录请求
+type VoidRecordRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// VoidRecord 作废充电记录
+// @Summary 作废充电记录
+// @Description 在编辑期限内作废自己的充电记录（需填写原因）；作废后记录保留但不计入统计，关联预约可重新上传记录
+// @Tags 充电记录
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "记录ID"
+// @Param request body VoidRecordRequest true "作废原因"
+// @Success 200 {object} map[string]interface{}
+// @Router /records/{id} [delete]
+func VoidRecord(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	recordID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "ID格式错误", "error": err.Error()})
+		return
+	}
+	var req VoidRecordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "作废原因不能为空", "error": err.Error()})
+		return
+	}
+	record, err := service.VoidRecord(c, userModel.ID, uint(recordID), req.Reason, false)
+	if err != nil {
+		if err.Error() == "充电记录不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "充电记录不存在"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "作废充电记录失败", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "充电记录已作废", "data": record.FormatRecordInfo()})
+}
@@ -51,7 +51,7 @@ func GetReservations(c *gin.Context) {
 
 // CreateReservation 创建预约
 // @Summary 创建预约
-// @Description 创建新的预约
+// @Description 创建新的预约；未同意最新版本的充电规则时返回 403（rules_required 为 true）
 // @Tags 预约
 // @Accept json
 // @Produce json
@@ -88,6 +88,10 @@ func CreateReservation(c *gin.Context) {
 	reservation, err := service.CreateReservationWithCheck(c, userModel.ID, date, req.Timeslot, req.Remark, req.LicensePlateID)
 	if err != nil {
 		utils.ErrorCtx(c, "创建预约失败: %v", err)
+		if err.Error() == "请先阅读并同意最新的充电规则" {
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": err.Error(), "rules_required": true})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "创建预约失败"})
 		return
 	}
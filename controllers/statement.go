@@ -0,0 +1,125 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"shared-charge/service"
+	"shared-charge/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BillPaymentRequest 标记账单支付状态请求
+type BillPaymentRequest struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Month  string `json:"month" binding:"required,len=7"`
+	Paid   bool   `json:"paid"`
+	Remark string `json:"remark"`
+}
+
+// GetMyStatement 成员下载月度账单PDF
+// @Summary 下载月度账单
+// @Description 生成当前用户指定月份的PDF账单，包含每条审核通过的记录、合计、支付状态和电量截图缩略图
+// @Tags 充电记录
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param month query string true "月份(YYYY-MM)"
+// @Success 200 {file} file
+// @Router /records/statement [get]
+func GetMyStatement(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	month := c.Query("month")
+	if month == "" || len(month) != 7 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+		return
+	}
+	var buf bytes.Buffer
+	if err := service.RenderMemberStatement(c, &buf, userModel.ID, month); err != nil {
+		statementError(c, err)
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, service.StatementFileName(userModel.ID, month)))
+	c.Data(http.StatusOK, "application/pdf", buf.Bytes())
+}
+
+// AdminExportStatements 管理员批量下载月度账单
+// @Summary 批量下载月度账单（管理员）
+// @Description 将指定月份每个有审核通过记录的用户的PDF账单打包为zip下载，传user_id时只包含该用户
+// @Tags 管理员
+// @Produce application/zip
+// @Security BearerAuth
+// @Param month query string true "月份(YYYY-MM)"
+// @Param user_id query int false "用户ID"
+// @Success 200 {file} file
+// @Router /admin/statements [get]
+func AdminExportStatements(c *gin.Context) {
+	month := c.Query("month")
+	if month == "" || len(month) != 7 {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+		return
+	}
+	userID, ok := queryUintParam(c, "user_id", "用户ID格式错误")
+	if !ok {
+		return
+	}
+	err := service.ExportStatementsZip(c, month, userID)
+	if err != nil && !c.Writer.Written() {
+		statementError(c, err)
+		return
+	}
+	finishExport(c, err)
+}
+
+// statementError 生成账单失败时返回错误响应
+func statementError(c *gin.Context, err error) {
+	switch err.Error() {
+	case "月份格式错误":
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+	case "用户不存在":
+		c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+	case "未配置PDF中文字体，无法生成账单":
+		c.JSON(http.StatusServiceUnavailable, gin.H{"code": 503, "message": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "生成账单失败"})
+	}
+}
+
+// SetBillPaymentStatus 管理员标记用户月度账单支付状态
+// @Summary 标记账单支付状态（管理员）
+// @Description 标记用户某月账单已支付或未支付，并记录当时的应付金额
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param data body BillPaymentRequest true "支付状态"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/bills/payment [post]
+func SetBillPaymentStatus(c *gin.Context) {
+	userModel, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	var req BillPaymentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.WarnCtx(c, "参数校验失败: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误", "error": err.Error()})
+		return
+	}
+	bill, err := service.SetBillPaymentStatus(c, userModel.ID, req.UserID, req.Month, req.Paid, req.Remark)
+	if err != nil {
+		switch err.Error() {
+		case "月份格式错误":
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数month格式错误，应为YYYY-MM"})
+		case "用户不存在":
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "更新支付状态失败"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": bill})
+}
@@ -2,7 +2,6 @@ package controllers
 
 import (
 	"net/http"
-	"shared-charge/config"
 	"shared-charge/service"
 	"shared-charge/utils"
 
@@ -11,7 +10,7 @@ import (
 
 // GetUserProfile 获取用户信息
 // @Summary 获取用户信息
-// @Description 获取当前登录用户的详细信息
+// @Description 获取当前登录用户的详细信息，permissions 为当前角色拥有的管理权限
 // @Tags 用户
 // @Accept json
 // @Produce json
@@ -34,12 +33,14 @@ func GetUserProfile(c *gin.Context) {
 		return
 	}
 	utils.InfoCtx(c, "获取用户信息成功: user_id=%d", userModel.ID)
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "获取用户信息成功", "data": userData.FormatUserInfo()})
+	userInfo := userData.FormatUserInfo()
+	userInfo["permissions"] = userData.Permissions()
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "获取用户信息成功", "data": userInfo})
 }
 
 // GetUserPrice 获取当前用户专属电价
 // @Summary 获取当前用户专属电价
-// @Description 获取当前登录用户的专属电价（如无则返回全局默认）
+// @Description 获取当前登录用户的专属电价（如无则返回全局默认），scheduled 为待生效的电价计划
 // @Tags 用户
 // @Accept json
 // @Produce json
@@ -54,12 +55,13 @@ func GetUserPrice(c *gin.Context) {
 		c.JSON(http.StatusUnauthorized, gin.H{"code": 401, "message": "用户未认证"})
 		return
 	}
-	unitPrice := userModel.UnitPrice
-	if unitPrice <= 0 {
-		unitPrice = config.GetConfig().App.DefaultUnitPrice
+	data, err := service.GetUserPriceInfo(c, userModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取用户电价失败"})
+		return
 	}
-	utils.InfoCtx(c, "获取用户电价成功: user_id=%d, price=%v", userModel.ID, unitPrice)
-	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "获取用户电价成功", "data": gin.H{"unit_price": unitPrice}})
+	utils.InfoCtx(c, "获取用户电价成功: user_id=%d, price=%v", userModel.ID, data["unit_price"])
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "获取用户电价成功", "data": data})
 }
 
 // UpdateUserProfile 更新用户信息
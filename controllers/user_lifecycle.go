@@ -0,0 +1,263 @@
+package controllers
+
+import (
+	"net/http"
+	"shared-charge/service"
+	"shared-charge/utils"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// SuspendUserRequest 暂停用户请求
+type SuspendUserRequest struct {
+	Reason string `json:"reason" binding:"required"`
+	Until  string `json:"until"`
+}
+
+// UpdateUserRoleRequest 修改用户角色请求
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin treasurer scheduler viewer user"`
+}
+
+// userIDFromPath 解析路径中的用户ID
+func userIDFromPath(c *gin.Context) (uint, bool) {
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "用户ID格式错误", "error": err.Error()})
+		return 0, false
+	}
+	return uint(userID), true
+}
+
+// respondUserLifecycle 返回用户状态变更结果，用户不存在时返回404
+func respondUserLifecycle(c *gin.Context, data map[string]interface{}, err error, failMessage string) {
+	if err != nil {
+		if err.Error() == "用户不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "用户不存在"})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": failMessage, "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": data})
+}
+
+// SuspendUser 管理员暂停用户
+// @Summary 暂停用户
+// @Description 暂停用户（需填写原因），可选截止日期（含当天），到期后自动恢复；暂停期间尚未开始的预约会被取消，用户无法登录和调用接口
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body SuspendUserRequest true "暂停原因和截止日期(YYYY-MM-DD)"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/{id}/suspend [post]
+func SuspendUser(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDFromPath(c)
+	if !ok {
+		return
+	}
+	var req SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "暂停原因不能为空", "error": err.Error()})
+		return
+	}
+	data, err := service.SuspendUser(c, adminUser.ID, userID, req.Reason, req.Until)
+	respondUserLifecycle(c, data, err, "暂停用户失败")
+}
+
+// ReactivateUser 管理员恢复被暂停的用户
+// @Summary 恢复用户
+// @Description 提前恢复被暂停的用户，已移除的用户不能恢复
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/{id}/reactivate [post]
+func ReactivateUser(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDFromPath(c)
+	if !ok {
+		return
+	}
+	data, err := service.ReactivateUser(c, adminUser.ID, userID)
+	respondUserLifecycle(c, data, err, "恢复用户失败")
+}
+
+// RevokeUserSessions 管理员注销用户的全部会话
+// @Summary 注销用户全部会话
+// @Description 注销用户所有刷新令牌，已签发的访问令牌加入黑名单，用户需重新登录
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/{id}/revoke_sessions [post]
+func RevokeUserSessions(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDFromPath(c)
+	if !ok {
+		return
+	}
+	count, err := service.RevokeUserSessions(c, adminUser.ID, userID)
+	respondUserLifecycle(c, map[string]interface{}{"revoked_sessions": count}, err, "注销用户会话失败")
+}
+
+// GetRoles 管理员获取可分配的角色及其权限
+// @Summary 获取角色列表
+// @Description 获取可分配的角色（admin、treasurer、scheduler、viewer、user）及各角色拥有的权限
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/roles [get]
+func GetRoles(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": service.GetRoles()})
+}
+
+// UpdateUserRole 管理员修改用户角色
+// @Summary 修改用户角色
+// @Description 设置为管理员(admin)、财务(treasurer)、调度(scheduler)、只读(viewer)或普通成员(user)；不能修改自己的角色，且至少保留一名正常状态的管理员
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body UpdateUserRoleRequest true "角色"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/{id}/role [put]
+func UpdateUserRole(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDFromPath(c)
+	if !ok {
+		return
+	}
+	var req UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "角色无效，仅支持admin、treasurer、scheduler、viewer和user", "error": err.Error()})
+		return
+	}
+	data, err := service.UpdateUserRole(c, adminUser.ID, userID, req.Role)
+	respondUserLifecycle(c, data, err, "修改用户角色失败")
+}
+
+// RemoveUser 管理员移除用户
+// @Summary 移除用户
+// @Description 移除用户：取消其尚未开始的预约和待生效的电价计划，保留充电记录和账单；移除后用户无法登录，仍出现在移除当月及之前的月度对账中
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/{id} [delete]
+func RemoveUser(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDFromPath(c)
+	if !ok {
+		return
+	}
+	data, err := service.RemoveUser(c, adminUser.ID, userID)
+	respondUserLifecycle(c, data, err, "移除用户失败")
+}
+
+// ApproveUserRequest 审核通过新用户请求
+type ApproveUserRequest struct {
+	CanReserve bool             `json:"can_reserve"`
+	UnitPrice  *decimal.Decimal `json:"unit_price" swaggertype:"number"`
+}
+
+// GetPendingUsers 管理员获取待审核的新用户
+// @Summary 获取待审核用户
+// @Description 获取未使用邀请码登录、等待审核的新用户，先注册的在前
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/pending [get]
+func GetPendingUsers(c *gin.Context) {
+	users, err := service.GetPendingUsers(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "获取待审核用户失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 200, "message": "success", "data": users, "total": len(users)})
+}
+
+// ApproveUser 管理员审核通过新用户
+// @Summary 审核通过新用户
+// @Description 审核通过待审核用户，可同时设置是否可预约和电价（为空时使用默认电价），并通知用户
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Param request body ApproveUserRequest false "可预约和电价"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/{id}/approve [post]
+func ApproveUser(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDFromPath(c)
+	if !ok {
+		return
+	}
+	var req ApproveUserRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "参数错误", "error": err.Error()})
+			return
+		}
+	}
+	data, err := service.ApproveUser(c, adminUser.ID, userID, req.CanReserve, req.UnitPrice)
+	respondUserLifecycle(c, data, err, "审核通过用户失败")
+}
+
+// RejectUser 管理员拒绝新用户
+// @Summary 拒绝新用户
+// @Description 拒绝待审核用户，用户标记为已移除，之后无法登录
+// @Tags 管理员
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "用户ID"
+// @Success 200 {object} map[string]interface{}
+// @Router /admin/users/{id}/reject [post]
+func RejectUser(c *gin.Context) {
+	adminUser, ok := utils.GetUserFromContext(c)
+	if !ok {
+		return
+	}
+	userID, ok := userIDFromPath(c)
+	if !ok {
+		return
+	}
+	data, err := service.RejectUser(c, adminUser.ID, userID)
+	respondUserLifecycle(c, data, err, "拒绝用户失败")
+}
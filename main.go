@@ -5,6 +5,7 @@ import (
 	"shared-charge/controllers"
 	"shared-charge/middleware"
 	"shared-charge/models"
+	"shared-charge/service"
 	"shared-charge/utils"
 
 	_ "shared-charge/docs"
@@ -50,6 +51,16 @@ func main() {
 		// 原日志打印已删除
 	}
 
+	// 初始化电量截图识别引擎（未配置时不启用）
+	if err := service.InitMeterExtractor(); err != nil {
+		utils.Warn("初始化电量截图识别失败，已禁用: %v", err)
+	}
+
+	// 加载PDF账单中文字体（未配置时账单接口返回错误）
+	if err := service.InitStatementFont(); err != nil {
+		utils.Error("加载PDF账单字体失败，账单下载不可用: %v", err)
+	}
+
 	// 初始化Redis
 	redisCfg := config.GetConfig().Redis
 	utils.InitRedis(redisCfg.Addr, redisCfg.Password, redisCfg.DB)
@@ -71,7 +82,8 @@ func main() {
 		auth := api.Group("/auth")
 		{
 			auth.POST("/login", controllers.WechatLogin)
-			auth.POST("/refresh", middleware.AuthMiddleware(), controllers.RefreshToken)
+			auth.POST("/refresh", controllers.RefreshToken)
+			auth.POST("/logout", middleware.AuthMiddleware(), controllers.Logout)
 		}
 
 		// 用户相关
@@ -113,9 +125,14 @@ func main() {
 			records.GET("", controllers.GetRecords)
 			records.POST("", controllers.CreateRecord)
 			records.GET("/unsubmitted", controllers.GetUnsubmittedRecords)
+			records.GET("/outstanding", controllers.GetOutstandingUploads)
 			records.GET("/list", controllers.GetRecordsList)
+			records.GET("/export", controllers.ExportMyRecords)
+			records.GET("/statement", controllers.GetMyStatement)
 			records.GET("/:id", controllers.GetRecordDetail)
 			records.PUT("/:id", controllers.UpdateRecord)
+			records.DELETE("/:id", controllers.VoidRecord)
+			records.GET("/:id/revisions", controllers.GetRecordRevisions)
 		}
 
 		// 文件上传
@@ -135,16 +152,87 @@ func main() {
 			statistics.GET("/monthly", controllers.GetMonthlyStatistics)
 			statistics.GET("/daily", controllers.GetDailyStatistics)
 			statistics.GET("/monthly-shift", controllers.GetMonthlyShiftStatistics)
+			statistics.GET("/group", controllers.GetGroupStatistics)
+			statistics.GET("/range", controllers.GetRangeStatistics)
+			statistics.GET("/vehicles", controllers.GetVehicleStatistics)
+			statistics.GET("/forecast", controllers.GetForecast)
+			statistics.GET("/forecast/group", controllers.GetGroupForecast)
+		}
+
+		// 站内通知
+		notifications := api.Group("/notifications")
+		notifications.Use(middleware.AuthMiddleware())
+		{
+			notifications.GET("", controllers.GetNotifications)
+			notifications.PUT("/:id/read", controllers.MarkNotificationRead)
+		}
+
+		// 公告相关
+		announcements := api.Group("/announcements")
+		announcements.Use(middleware.AuthMiddleware())
+		{
+			announcements.GET("", controllers.GetAnnouncements)
+			announcements.GET("/rules", controllers.GetRulesStatus)
+			announcements.POST("/rules/accept", controllers.AcceptRules)
 		}
 
-		// 管理员相关
+		// 管理端相关，各接口按角色权限校验
 		admin := api.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(), middleware.AdminRequired())
+		admin.Use(middleware.AuthMiddleware(), middleware.AuditAdminRequests())
+		perm := middleware.PermissionRequired
 		{
-			admin.GET("/users", controllers.GetAllUsers)
-			admin.POST("/user/can_reserve", controllers.UpdateUserCanReserve)
-			admin.POST("/user/unit_price", controllers.UpdateUserUnitPrice)
-			admin.GET("/monthly_report", controllers.GetMonthlyReport)
+			admin.GET("/dashboard", perm(models.PermReportsView), controllers.GetAdminDashboard)
+			admin.GET("/users", perm(models.PermUsersView), controllers.GetAllUsers)
+			admin.GET("/users/pending", perm(models.PermUsersView), controllers.GetPendingUsers)
+			admin.POST("/users/bulk/unit_price", perm(models.PermUsersPrice), controllers.BulkUpdateUnitPrice)
+			admin.POST("/users/bulk/can_reserve", perm(models.PermUsersManage), controllers.BulkUpdateCanReserve)
+			admin.POST("/users/bulk/status", perm(models.PermUsersManage), controllers.BulkUpdateStatus)
+			admin.POST("/users/:id/approve", perm(models.PermUsersManage), controllers.ApproveUser)
+			admin.POST("/users/:id/reject", perm(models.PermUsersManage), controllers.RejectUser)
+			admin.POST("/users/:id/suspend", perm(models.PermUsersManage), controllers.SuspendUser)
+			admin.POST("/users/:id/reactivate", perm(models.PermUsersManage), controllers.ReactivateUser)
+			admin.GET("/roles", perm(models.PermUsersRoles), controllers.GetRoles)
+			admin.PUT("/users/:id/role", perm(models.PermUsersRoles), controllers.UpdateUserRole)
+			admin.POST("/users/:id/revoke_sessions", perm(models.PermUsersManage), controllers.RevokeUserSessions)
+			admin.DELETE("/users/:id", perm(models.PermUsersManage), controllers.RemoveUser)
+			admin.GET("/announcements", perm(models.PermAnnouncementsManage), controllers.AdminGetAnnouncements)
+			admin.POST("/announcements", perm(models.PermAnnouncementsManage), controllers.PublishAnnouncement)
+			admin.DELETE("/announcements/:id", perm(models.PermAnnouncementsManage), controllers.WithdrawAnnouncement)
+			admin.GET("/announcements/rules/acceptances", perm(models.PermAnnouncementsManage), controllers.GetRuleAcceptances)
+			admin.GET("/audit_logs", perm(models.PermAuditView), controllers.GetAuditLogs)
+			admin.GET("/invite_codes", perm(models.PermUsersManage), controllers.GetInviteCodes)
+			admin.POST("/invite_codes", perm(models.PermUsersManage), controllers.CreateInviteCode)
+			admin.DELETE("/invite_codes/:id", perm(models.PermUsersManage), controllers.DisableInviteCode)
+			admin.POST("/user/can_reserve", perm(models.PermUsersManage), controllers.UpdateUserCanReserve)
+			admin.POST("/user/unit_price", perm(models.PermUsersPrice), controllers.UpdateUserUnitPrice)
+			admin.GET("/monthly_report", perm(models.PermReportsView), controllers.GetMonthlyReport)
+			admin.GET("/statistics", perm(models.PermReportsView), controllers.GetAdminGroupStatistics)
+			admin.GET("/statistics/range", perm(models.PermReportsView), controllers.GetAdminRangeStatistics)
+			admin.GET("/statistics/heatmap", perm(models.PermUsageView), controllers.GetAdminUtilizationHeatmap)
+			admin.GET("/statistics/forecast", perm(models.PermReportsView), controllers.GetAdminForecast)
+			admin.GET("/records/review", perm(models.PermRecordsReview), controllers.GetRecordsForReview)
+			admin.GET("/records/flagged", perm(models.PermRecordsReview), controllers.GetFlaggedRecords)
+			admin.POST("/records/approve", perm(models.PermRecordsReview), controllers.ApproveRecords)
+			admin.POST("/records/reject", perm(models.PermRecordsReview), controllers.RejectRecords)
+			admin.GET("/records/corrections", perm(models.PermRecordsReview), controllers.GetCorrectionRequests)
+			admin.POST("/records/corrections/:id/approve", perm(models.PermRecordsReview), controllers.ApproveCorrectionRequest)
+			admin.POST("/records/corrections/:id/reject", perm(models.PermRecordsReview), controllers.RejectCorrectionRequest)
+			admin.GET("/records/:id/revisions", perm(models.PermRecordsReview), controllers.GetAdminRecordRevisions)
+			admin.POST("/records/:id/void", perm(models.PermRecordsReview), controllers.AdminVoidRecord)
+			admin.GET("/records/outstanding", perm(models.PermReservationsManage), controllers.GetAdminOutstandingUploads)
+			admin.POST("/reservations/:id/remind", perm(models.PermReservationsManage), controllers.SendUploadReminder)
+			admin.GET("/discounts", perm(models.PermBillingDiscounts), controllers.GetDiscountRules)
+			admin.POST("/discounts", perm(models.PermBillingDiscounts), controllers.CreateDiscountRule)
+			admin.PUT("/discounts/:id", perm(models.PermBillingDiscounts), controllers.UpdateDiscountRule)
+			admin.DELETE("/discounts/:id", perm(models.PermBillingDiscounts), controllers.DeleteDiscountRule)
+			admin.GET("/price_schedules", perm(models.PermUsersPrice), controllers.GetPriceSchedules)
+			admin.POST("/price_schedules", perm(models.PermUsersPrice), controllers.CreatePriceSchedule)
+			admin.DELETE("/price_schedules/:id", perm(models.PermUsersPrice), controllers.CancelPriceSchedule)
+			admin.GET("/export/records", perm(models.PermReportsExport), controllers.AdminExportRecords)
+			admin.GET("/export/reservations", perm(models.PermReportsExport), controllers.AdminExportReservations)
+			admin.GET("/export/monthly_report", perm(models.PermReportsExport), controllers.AdminExportMonthlyReport)
+			admin.GET("/statements", perm(models.PermReportsExport), controllers.AdminExportStatements)
+			admin.POST("/bills/payment", perm(models.PermBillingCloseMonth), controllers.SetBillPaymentStatus)
 		}
 
 	}
@@ -7,18 +7,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AdminRequired 仅允许管理员访问
-func AdminRequired() gin.HandlerFunc {
+// PermissionRequired 仅允许角色拥有指定权限的用户访问
+func PermissionRequired(permission string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		user, ok := utils.GetUserFromContext(c)
 		if !ok {
 			c.Abort()
 			return
 		}
-		if user.Role != "admin" {
+		if !user.HasPermission(permission) {
+			utils.WarnCtx(c, "权限不足: user_id=%d, role=%s, permission=%s", user.ID, user.Role, permission)
 			c.JSON(http.StatusForbidden, gin.H{
-				"code":    403,
-				"message": "无权限，仅管理员可操作",
+				"code":       403,
+				"message":    "无权限执行此操作",
+				"permission": permission,
 			})
 			c.Abort()
 			return
@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"shared-charge/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AuditAdminRequests 管理端接口兜底审计，放在 AuthMiddleware 之后、各接口的 PermissionRequired 之前
+// 业务层已提交具体审计日志的请求和因权限不足被拒绝的请求不再记录；其余修改类请求和数据导出记录方法、路径、参数和响应状态
+func AuditAdminRequests() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+		service.RecordAdminRequestAudit(c)
+	}
+}
@@ -2,7 +2,6 @@ package middleware
 
 import (
 	"encoding/json"
-	"fmt"
 	"net/http"
 	"shared-charge/config"
 	"shared-charge/models"
@@ -47,8 +46,7 @@ func cleanupUserCache() {
 
 // getUserFromCache 从 Redis 获取用户缓存
 func getUserFromCache(userID uint) (*models.User, bool) {
-	key := fmt.Sprintf("user_cache:%d", userID)
-	val, err := utils.GetRedis().Get(utils.RedisCtx(), key).Result()
+	val, err := utils.GetRedis().Get(utils.RedisCtx(), utils.UserCacheKey(userID)).Result()
 	if err != nil {
 		return nil, false
 	}
@@ -61,9 +59,18 @@ func getUserFromCache(userID uint) (*models.User, bool) {
 
 // setUserToCache 设置用户缓存到 Redis
 func setUserToCache(user *models.User) {
-	key := fmt.Sprintf("user_cache:%d", user.ID)
 	data, _ := json.Marshal(user)
-	utils.GetRedis().Set(utils.RedisCtx(), key, data, time.Hour) // 1小时过期
+	utils.GetRedis().Set(utils.RedisCtx(), utils.UserCacheKey(user.ID), data, time.Hour) // 1小时过期
+}
+
+// rejectInactiveUser 拒绝暂停或已移除的用户
+func rejectInactiveUser(c *gin.Context, user *models.User) {
+	c.JSON(http.StatusForbidden, gin.H{
+		"code":    403,
+		"message": user.InactiveMessage(),
+		"status":  user.Status,
+	})
+	c.Abort()
 }
 
 // AuthMiddleware JWT认证中间件
@@ -127,21 +134,43 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// 先从缓存获取用户信息
+		// 检查令牌是否已注销（退出登录、刷新令牌泄露或管理员注销会话）
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "认证令牌已失效，请重新登录",
+			})
+			c.Abort()
+			return
+		}
+		denied, err := utils.IsTokenDenied(jti)
+		if err != nil {
+			// Redis 不可用时不拦截请求，与用户缓存的处理一致
+			utils.ErrorCtx(c, "查询令牌黑名单失败: jti=%s, err=%v", jti, err)
+		}
+		if denied {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "认证令牌已注销，请重新登录",
+			})
+			c.Abort()
+			return
+		}
+		sessionID, _ := claims["sid"].(string)
+		c.Set(utils.SessionIDKey, sessionID)
+
+		// 先从缓存获取用户信息；暂停已到期的用户回源数据库恢复状态
 		userIDUint := uint(userID)
-		if user, exists := getUserFromCache(userIDUint); exists {
+		if user, exists := getUserFromCache(userIDUint); exists && !user.SuspensionExpired(time.Now()) {
 			// 检查用户状态
 			if !user.IsActive() {
-				c.JSON(http.StatusForbidden, gin.H{
-					"code":    403,
-					"message": "用户已被禁用",
-				})
-				c.Abort()
+				rejectInactiveUser(c, user)
 				return
 			}
 			c.Set("user", *user)
 			c.Next()
-			// return
+			return
 		}
 
 		// 缓存未命中，查询数据库
@@ -155,13 +184,14 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if _, err := user.LiftExpiredSuspension(models.DB); err != nil {
+			utils.ErrorCtx(c, "恢复暂停到期用户失败: user_id=%d, err=%v", user.ID, err)
+		}
+
 		// 检查用户状态
 		if !user.IsActive() {
-			c.JSON(http.StatusForbidden, gin.H{
-				"code":    403,
-				"message": "用户已被禁用",
-			})
-			c.Abort()
+			setUserToCache(&user)
+			rejectInactiveUser(c, &user)
 			return
 		}
 
@@ -18,8 +18,9 @@ func TraceMiddleware() gin.HandlerFunc {
 		// 创建带 trace_id 字段的 logger
 		logger := utils.GetLogger().Desugar().With(zap.String("trace_id", traceID)).Sugar()
 
-		// 将 logger 存入 gin.Context
+		// 将 logger 和 trace_id 存入 gin.Context
 		c.Set("logger", logger)
+		c.Set(utils.TraceIDKey, traceID)
 
 		// 设置响应头，便于链路排查
 		c.Header("X-Trace-ID", traceID)
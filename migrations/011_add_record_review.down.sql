@@ -0,0 +1,9 @@
+-- 删除站内通知表
+DROP TABLE IF EXISTS notifications;
+
+-- 删除充电记录审核字段
+DROP INDEX IF EXISTS idx_records_review_status;
+ALTER TABLE records DROP COLUMN IF EXISTS reviewed_at;
+ALTER TABLE records DROP COLUMN IF EXISTS reviewed_by;
+ALTER TABLE records DROP COLUMN IF EXISTS review_reason;
+ALTER TABLE records DROP COLUMN IF EXISTS review_status;
@@ -0,0 +1,35 @@
+-- 充电记录审核字段
+-- 历史记录视为已审核通过，新记录默认待审核
+ALTER TABLE records ADD COLUMN IF NOT EXISTS review_status VARCHAR(20) NOT NULL DEFAULT 'approved';
+ALTER TABLE records ALTER COLUMN review_status SET DEFAULT 'submitted';
+ALTER TABLE records ADD COLUMN IF NOT EXISTS review_reason VARCHAR(255);
+ALTER TABLE records ADD COLUMN IF NOT EXISTS reviewed_by INTEGER;
+ALTER TABLE records ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_records_review_status ON records(review_status);
+
+COMMENT ON COLUMN records.review_status IS '审核状态:submitted,approved,rejected';
+COMMENT ON COLUMN records.review_reason IS '审核意见(驳回原因)';
+COMMENT ON COLUMN records.reviewed_by IS '审核人ID（逻辑关联，无外键约束）';
+COMMENT ON COLUMN records.reviewed_at IS '审核时间';
+
+-- 站内通知表
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content VARCHAR(500),
+    related_id INTEGER DEFAULT 0,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user_id ON notifications(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_notifications_deleted_at ON notifications(deleted_at);
+
+COMMENT ON TABLE notifications IS '站内通知表';
+COMMENT ON COLUMN notifications.type IS '通知类型';
+COMMENT ON COLUMN notifications.related_id IS '关联业务ID';
@@ -0,0 +1,5 @@
+-- 删除充电记录更正申请表
+DROP TABLE IF EXISTS record_correction_requests;
+
+-- 删除充电记录修订历史表
+DROP TABLE IF EXISTS record_revisions;
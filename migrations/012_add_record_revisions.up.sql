@@ -0,0 +1,45 @@
+-- 充电记录修订历史表（只追加）
+CREATE TABLE IF NOT EXISTS record_revisions (
+    id SERIAL PRIMARY KEY,
+    record_id INTEGER NOT NULL,
+    changed_by INTEGER NOT NULL,
+    source VARCHAR(20) NOT NULL,
+    old_values JSONB,
+    new_values JSONB,
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_record_revisions_record_id ON record_revisions(record_id, created_at);
+
+COMMENT ON TABLE record_revisions IS '充电记录修订历史表';
+COMMENT ON COLUMN record_revisions.changed_by IS '修改人ID（逻辑关联，无外键约束）';
+COMMENT ON COLUMN record_revisions.source IS '来源:member,admin,correction';
+
+-- 充电记录更正申请表
+CREATE TABLE IF NOT EXISTS record_correction_requests (
+    id SERIAL PRIMARY KEY,
+    record_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    changes JSONB NOT NULL,
+    reason VARCHAR(255),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    reviewed_by INTEGER,
+    reviewed_at TIMESTAMP,
+    review_reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_record_corrections_record_id ON record_correction_requests(record_id);
+CREATE INDEX IF NOT EXISTS idx_record_corrections_status ON record_correction_requests(status);
+CREATE INDEX IF NOT EXISTS idx_record_corrections_deleted_at ON record_correction_requests(deleted_at);
+-- 同一记录同时只能有一条待审核的更正申请
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_record_corrections_pending
+    ON record_correction_requests(record_id) WHERE status = 'pending' AND deleted_at IS NULL;
+
+COMMENT ON TABLE record_correction_requests IS '充电记录更正申请表';
+COMMENT ON COLUMN record_correction_requests.changes IS '申请修改的值';
+COMMENT ON COLUMN record_correction_requests.status IS '状态:pending,approved,rejected';
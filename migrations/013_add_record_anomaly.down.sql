@@ -0,0 +1,5 @@
+-- 删除充电记录异常检测字段
+DROP INDEX IF EXISTS idx_records_date_kwh;
+DROP INDEX IF EXISTS idx_records_anomaly;
+ALTER TABLE records DROP COLUMN IF EXISTS anomaly_reasons;
+ALTER TABLE records DROP COLUMN IF EXISTS anomaly_score;
@@ -0,0 +1,11 @@
+-- 充电记录异常检测字段
+ALTER TABLE records ADD COLUMN IF NOT EXISTS anomaly_score INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE records ADD COLUMN IF NOT EXISTS anomaly_reasons JSONB;
+
+-- 异常记录队列查询索引
+CREATE INDEX IF NOT EXISTS idx_records_anomaly ON records(review_status, anomaly_score) WHERE anomaly_score > 0;
+-- 重复度数/日期检查索引
+CREATE INDEX IF NOT EXISTS idx_records_date_kwh ON records(date, kwh);
+
+COMMENT ON COLUMN records.anomaly_score IS '异常分数';
+COMMENT ON COLUMN records.anomaly_reasons IS '异常原因列表';
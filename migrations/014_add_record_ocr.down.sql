@@ -0,0 +1,4 @@
+-- 删除电量截图识别结果字段
+ALTER TABLE records DROP COLUMN IF EXISTS ocr_confidence;
+ALTER TABLE records DROP COLUMN IF EXISTS ocr_meter_reading;
+ALTER TABLE records DROP COLUMN IF EXISTS ocr_kwh;
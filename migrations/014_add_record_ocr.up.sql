@@ -0,0 +1,8 @@
+-- 电量截图识别结果字段
+ALTER TABLE records ADD COLUMN IF NOT EXISTS ocr_kwh DECIMAL(10,2);
+ALTER TABLE records ADD COLUMN IF NOT EXISTS ocr_meter_reading DECIMAL(12,2);
+ALTER TABLE records ADD COLUMN IF NOT EXISTS ocr_confidence DECIMAL(5,4);
+
+COMMENT ON COLUMN records.ocr_kwh IS '截图识别出的度数';
+COMMENT ON COLUMN records.ocr_meter_reading IS '截图识别出的电表读数';
+COMMENT ON COLUMN records.ocr_confidence IS '截图识别置信度(0-1)';
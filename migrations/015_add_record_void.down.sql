@@ -0,0 +1,5 @@
+-- 删除充电记录作废字段
+DROP INDEX IF EXISTS uniq_records_active_reservation;
+ALTER TABLE records DROP COLUMN IF EXISTS void_reason;
+ALTER TABLE records DROP COLUMN IF EXISTS voided_by;
+ALTER TABLE records DROP COLUMN IF EXISTS voided_at;
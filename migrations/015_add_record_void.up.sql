@@ -0,0 +1,12 @@
+-- 充电记录作废字段（作废记录保留用于审计，不计入统计与对账）
+ALTER TABLE records ADD COLUMN IF NOT EXISTS voided_at TIMESTAMP;
+ALTER TABLE records ADD COLUMN IF NOT EXISTS voided_by INTEGER;
+ALTER TABLE records ADD COLUMN IF NOT EXISTS void_reason VARCHAR(255);
+
+-- 同一预约只能有一条未作废的充电记录
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_records_active_reservation
+    ON records(reservation_id) WHERE voided_at IS NULL AND deleted_at IS NULL AND reservation_id > 0;
+
+COMMENT ON COLUMN records.voided_at IS '作废时间';
+COMMENT ON COLUMN records.voided_by IS '作废操作人ID（逻辑关联，无外键约束）';
+COMMENT ON COLUMN records.void_reason IS '作废原因';
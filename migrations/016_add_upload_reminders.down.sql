@@ -0,0 +1,2 @@
+-- 删除充电记录补传提醒表
+DROP TABLE IF EXISTS upload_reminders;
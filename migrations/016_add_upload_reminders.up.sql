@@ -0,0 +1,14 @@
+-- 充电记录补传提醒表（只追加）
+CREATE TABLE IF NOT EXISTS upload_reminders (
+    id SERIAL PRIMARY KEY,
+    reservation_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    sent_by INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_upload_reminders_reservation_id ON upload_reminders(reservation_id, created_at);
+
+COMMENT ON TABLE upload_reminders IS '充电记录补传提醒表';
+COMMENT ON COLUMN upload_reminders.user_id IS '被提醒用户ID（逻辑关联，无外键约束）';
+COMMENT ON COLUMN upload_reminders.sent_by IS '发送提醒的管理员ID（逻辑关联，无外键约束）';
@@ -0,0 +1,3 @@
+-- 恢复单价为2位小数
+ALTER TABLE records ALTER COLUMN unit_price TYPE DECIMAL(10,2);
+ALTER TABLE users ALTER COLUMN unit_price TYPE DECIMAL(10,2);
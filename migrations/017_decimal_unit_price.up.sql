@@ -0,0 +1,6 @@
+-- 单价支持4位小数（元/度），金额仍以分为单位存储
+ALTER TABLE users ALTER COLUMN unit_price TYPE NUMERIC(12,4);
+ALTER TABLE records ALTER COLUMN unit_price TYPE NUMERIC(12,4);
+
+COMMENT ON COLUMN users.unit_price IS '用户电价(元/度，4位小数)';
+COMMENT ON COLUMN records.unit_price IS '记录单价(元/度，4位小数)';
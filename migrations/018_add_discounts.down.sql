@@ -0,0 +1,5 @@
+-- 删除优惠相关表与字段
+ALTER TABLE records DROP COLUMN IF EXISTS discount_amount;
+ALTER TABLE records DROP COLUMN IF EXISTS gross_amount;
+DROP TABLE IF EXISTS record_discounts;
+DROP TABLE IF EXISTS discount_rules;
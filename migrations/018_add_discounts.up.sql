@@ -0,0 +1,51 @@
+-- 优惠规则表
+CREATE TABLE IF NOT EXISTS discount_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    value NUMERIC(12,4) NOT NULL,
+    scope VARCHAR(20) NOT NULL,
+    user_id INTEGER,
+    license_plate_id INTEGER,
+    start_date DATE,
+    end_date DATE,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_discount_rules_scope ON discount_rules(scope, active);
+CREATE INDEX IF NOT EXISTS idx_discount_rules_deleted_at ON discount_rules(deleted_at);
+
+COMMENT ON TABLE discount_rules IS '优惠规则表';
+COMMENT ON COLUMN discount_rules.type IS '类型:percentage(折扣百分比),fixed_credit(每月抵扣金额,元),free_kwh(每月免费度数)';
+COMMENT ON COLUMN discount_rules.scope IS '适用范围:user,plate,all';
+
+-- 充电记录优惠明细表
+CREATE TABLE IF NOT EXISTS record_discounts (
+    id SERIAL PRIMARY KEY,
+    record_id INTEGER NOT NULL,
+    rule_id INTEGER NOT NULL,
+    rule_name VARCHAR(100),
+    type VARCHAR(20) NOT NULL,
+    kwh DECIMAL(10,2) NOT NULL DEFAULT 0,
+    amount BIGINT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_record_discounts_record_id ON record_discounts(record_id);
+CREATE INDEX IF NOT EXISTS idx_record_discounts_rule_id ON record_discounts(rule_id);
+
+COMMENT ON TABLE record_discounts IS '充电记录优惠明细表';
+COMMENT ON COLUMN record_discounts.amount IS '优惠金额(分)';
+
+-- 充电记录优惠前金额与优惠金额
+ALTER TABLE records ADD COLUMN IF NOT EXISTS gross_amount BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE records ADD COLUMN IF NOT EXISTS discount_amount BIGINT NOT NULL DEFAULT 0;
+UPDATE records SET gross_amount = amount WHERE gross_amount = 0;
+
+COMMENT ON COLUMN records.amount IS '费用金额(分，已扣除优惠)';
+COMMENT ON COLUMN records.gross_amount IS '优惠前费用金额(分)';
+COMMENT ON COLUMN records.discount_amount IS '优惠金额(分)';
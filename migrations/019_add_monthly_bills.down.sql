@@ -0,0 +1,2 @@
+-- 删除月度账单支付状态表
+DROP TABLE IF EXISTS monthly_bills;
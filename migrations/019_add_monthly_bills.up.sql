@@ -0,0 +1,21 @@
+-- 月度账单支付状态表
+CREATE TABLE IF NOT EXISTS monthly_bills (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    month VARCHAR(7) NOT NULL,
+    amount BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'unpaid',
+    paid_at TIMESTAMP,
+    marked_by INTEGER,
+    remark VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_monthly_bills_user_month ON monthly_bills(user_id, month);
+
+COMMENT ON TABLE monthly_bills IS '月度账单支付状态表';
+COMMENT ON COLUMN monthly_bills.month IS '账单月份(YYYY-MM)';
+COMMENT ON COLUMN monthly_bills.amount IS '标记支付时的应付金额(分)';
+COMMENT ON COLUMN monthly_bills.status IS '支付状态:unpaid,paid';
+COMMENT ON COLUMN monthly_bills.marked_by IS '标记支付状态的管理员ID（逻辑关联，无外键约束）';
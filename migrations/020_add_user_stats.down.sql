@@ -0,0 +1,3 @@
+-- 删除用户用电统计表
+DROP TABLE IF EXISTS user_month_stats;
+DROP TABLE IF EXISTS user_day_stats;
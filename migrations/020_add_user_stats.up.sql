@@ -0,0 +1,61 @@
+-- 用户按日用电统计表，随充电记录创建、修改、作废在同一事务内增量更新
+CREATE TABLE IF NOT EXISTS user_day_stats (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    date DATE NOT NULL,
+    day_kwh DECIMAL(12,2) NOT NULL DEFAULT 0,
+    night_kwh DECIMAL(12,2) NOT NULL DEFAULT 0,
+    total_kwh DECIMAL(12,2) NOT NULL DEFAULT 0,
+    total_amount BIGINT NOT NULL DEFAULT 0,
+    record_count INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_user_day_stats_user_date ON user_day_stats(user_id, date);
+CREATE INDEX IF NOT EXISTS idx_user_day_stats_date ON user_day_stats(date);
+
+COMMENT ON TABLE user_day_stats IS '用户按日用电统计表（仅统计未作废的充电记录）';
+COMMENT ON COLUMN user_day_stats.day_kwh IS '白班用电量(kWh)';
+COMMENT ON COLUMN user_day_stats.night_kwh IS '夜班用电量(kWh)';
+COMMENT ON COLUMN user_day_stats.total_kwh IS '总用电量(kWh)，含未标记班次的记录';
+COMMENT ON COLUMN user_day_stats.total_amount IS '费用合计(分，已扣除优惠)';
+COMMENT ON COLUMN user_day_stats.record_count IS '充电记录数';
+
+-- 用户按月用电统计表
+CREATE TABLE IF NOT EXISTS user_month_stats (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    month VARCHAR(7) NOT NULL,
+    day_kwh DECIMAL(12,2) NOT NULL DEFAULT 0,
+    night_kwh DECIMAL(12,2) NOT NULL DEFAULT 0,
+    total_kwh DECIMAL(12,2) NOT NULL DEFAULT 0,
+    total_amount BIGINT NOT NULL DEFAULT 0,
+    record_count INTEGER NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_user_month_stats_user_month ON user_month_stats(user_id, month);
+CREATE INDEX IF NOT EXISTS idx_user_month_stats_month ON user_month_stats(month);
+
+COMMENT ON TABLE user_month_stats IS '用户按月用电统计表（仅统计未作废的充电记录）';
+COMMENT ON COLUMN user_month_stats.month IS '统计月份(YYYY-MM)';
+COMMENT ON COLUMN user_month_stats.total_amount IS '费用合计(分，已扣除优惠)';
+
+-- 根据现有充电记录初始化统计数据
+INSERT INTO user_day_stats (user_id, date, day_kwh, night_kwh, total_kwh, total_amount, record_count)
+SELECT user_id, date,
+    COALESCE(SUM(CASE WHEN timeslot = 'day' THEN kwh ELSE 0 END), 0),
+    COALESCE(SUM(CASE WHEN timeslot = 'night' THEN kwh ELSE 0 END), 0),
+    COALESCE(SUM(kwh), 0),
+    COALESCE(SUM(amount), 0),
+    COUNT(*)
+FROM records
+WHERE voided_at IS NULL AND deleted_at IS NULL
+GROUP BY user_id, date
+ON CONFLICT (user_id, date) DO NOTHING;
+
+INSERT INTO user_month_stats (user_id, month, day_kwh, night_kwh, total_kwh, total_amount, record_count)
+SELECT user_id, to_char(date, 'YYYY-MM'), SUM(day_kwh), SUM(night_kwh), SUM(total_kwh), SUM(total_amount), SUM(record_count)
+FROM user_day_stats
+GROUP BY user_id, to_char(date, 'YYYY-MM')
+ON CONFLICT (user_id, month) DO NOTHING;
@@ -0,0 +1,2 @@
+-- 删除用户电价调整计划表
+DROP TABLE IF EXISTS unit_price_schedules;
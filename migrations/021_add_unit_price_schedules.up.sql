@@ -0,0 +1,22 @@
+-- 用户电价调整计划表
+CREATE TABLE IF NOT EXISTS unit_price_schedules (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    unit_price NUMERIC(12,4) NOT NULL,
+    effective_date DATE NOT NULL,
+    applied_at TIMESTAMP,
+    created_by INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_unit_price_schedules_user_id ON unit_price_schedules(user_id);
+CREATE INDEX IF NOT EXISTS idx_unit_price_schedules_pending ON unit_price_schedules(effective_date) WHERE applied_at IS NULL AND deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_unit_price_schedules_deleted_at ON unit_price_schedules(deleted_at);
+
+COMMENT ON TABLE unit_price_schedules IS '用户电价调整计划表';
+COMMENT ON COLUMN unit_price_schedules.unit_price IS '新电价(元/度)';
+COMMENT ON COLUMN unit_price_schedules.effective_date IS '生效日期，当天起按新电价计费';
+COMMENT ON COLUMN unit_price_schedules.applied_at IS '到期后写入 users.unit_price 的时间';
+COMMENT ON COLUMN unit_price_schedules.created_by IS '创建人ID（逻辑关联，无外键约束）';
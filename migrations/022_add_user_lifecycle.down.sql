@@ -0,0 +1,7 @@
+-- 删除用户生命周期字段
+ALTER TABLE users DROP COLUMN IF EXISTS removed_by;
+ALTER TABLE users DROP COLUMN IF EXISTS removed_at;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_by;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_at;
+ALTER TABLE users DROP COLUMN IF EXISTS suspended_until;
+ALTER TABLE users DROP COLUMN IF EXISTS suspend_reason;
@@ -0,0 +1,15 @@
+-- 用户生命周期字段：暂停（可设结束日期）与移除，移除的用户保留记录和账单
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspend_reason VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_until DATE;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS suspended_by INTEGER;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS removed_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS removed_by INTEGER;
+
+COMMENT ON COLUMN users.status IS '状态:active,suspended,removed';
+COMMENT ON COLUMN users.suspend_reason IS '暂停原因';
+COMMENT ON COLUMN users.suspended_until IS '暂停截止日期（含当天），为空表示需管理员手动恢复';
+COMMENT ON COLUMN users.suspended_at IS '暂停时间';
+COMMENT ON COLUMN users.suspended_by IS '暂停操作人ID（逻辑关联，无外键约束）';
+COMMENT ON COLUMN users.removed_at IS '移除时间';
+COMMENT ON COLUMN users.removed_by IS '移除操作人ID（逻辑关联，无外键约束）';
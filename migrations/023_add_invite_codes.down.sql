@@ -0,0 +1,7 @@
+-- 删除邀请码表及用户审核字段
+DROP INDEX IF EXISTS idx_users_pending_approval;
+ALTER TABLE users DROP COLUMN IF EXISTS approved_by;
+ALTER TABLE users DROP COLUMN IF EXISTS approved_at;
+ALTER TABLE users DROP COLUMN IF EXISTS invite_code_id;
+COMMENT ON COLUMN users.status IS '状态:active,suspended,removed';
+DROP TABLE IF EXISTS invite_codes;
@@ -0,0 +1,39 @@
+-- 群组邀请码表
+CREATE TABLE IF NOT EXISTS invite_codes (
+    id SERIAL PRIMARY KEY,
+    code VARCHAR(32) NOT NULL,
+    remark VARCHAR(255),
+    expires_at TIMESTAMP,
+    max_uses INTEGER NOT NULL DEFAULT 0,
+    used_count INTEGER NOT NULL DEFAULT 0,
+    unit_price NUMERIC(12,4),
+    can_reserve BOOLEAN NOT NULL DEFAULT FALSE,
+    disabled_at TIMESTAMP,
+    created_by INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invite_codes_code ON invite_codes(code);
+CREATE INDEX IF NOT EXISTS idx_invite_codes_deleted_at ON invite_codes(deleted_at);
+
+COMMENT ON TABLE invite_codes IS '群组邀请码表';
+COMMENT ON COLUMN invite_codes.max_uses IS '最多可用次数，0为不限';
+COMMENT ON COLUMN invite_codes.used_count IS '已使用次数';
+COMMENT ON COLUMN invite_codes.unit_price IS '兑换用户的电价(元/度)，为空时使用默认电价';
+COMMENT ON COLUMN invite_codes.can_reserve IS '兑换用户是否可预约';
+COMMENT ON COLUMN invite_codes.disabled_at IS '停用时间';
+COMMENT ON COLUMN invite_codes.created_by IS '创建人ID（逻辑关联，无外键约束）';
+
+-- 用户通过哪个邀请码加入、审核信息
+ALTER TABLE users ADD COLUMN IF NOT EXISTS invite_code_id INTEGER;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS approved_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS approved_by INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_users_pending_approval ON users(created_at) WHERE status = 'pending_approval';
+
+COMMENT ON COLUMN users.status IS '状态:pending_approval,active,suspended,removed';
+COMMENT ON COLUMN users.invite_code_id IS '注册时兑换的邀请码ID（逻辑关联，无外键约束）';
+COMMENT ON COLUMN users.approved_at IS '审核通过时间（兑换邀请码时为兑换时间）';
+COMMENT ON COLUMN users.approved_by IS '审核人ID（逻辑关联，无外键约束）';
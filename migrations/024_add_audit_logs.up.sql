@@ -0,0 +1,42 @@
+-- 审计日志表（只追加），记录管理操作和影响金额的操作
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id BIGSERIAL PRIMARY KEY,
+    actor_id INTEGER,
+    actor_role VARCHAR(20),
+    action VARCHAR(64) NOT NULL,
+    target_type VARCHAR(32),
+    target_id INTEGER,
+    before_values JSONB,
+    after_values JSONB,
+    trace_id VARCHAR(32),
+    ip VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_actor ON audit_logs(actor_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_target ON audit_logs(target_type, target_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action, created_at);
+
+-- 禁止修改和删除审计日志
+CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_audit_logs_append_only ON audit_logs;
+CREATE TRIGGER trg_audit_logs_append_only
+    BEFORE UPDATE OR DELETE ON audit_logs
+    FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+COMMENT ON TABLE audit_logs IS '审计日志表（只追加）';
+COMMENT ON COLUMN audit_logs.actor_id IS '操作人ID，系统任务为空（逻辑关联，无外键约束）';
+COMMENT ON COLUMN audit_logs.actor_role IS '操作时的角色:user,admin,system';
+COMMENT ON COLUMN audit_logs.action IS '操作，如 user.unit_price、record.void';
+COMMENT ON COLUMN audit_logs.target_type IS '操作对象类型，如 user、record、discount_rule';
+COMMENT ON COLUMN audit_logs.target_id IS '操作对象ID';
+COMMENT ON COLUMN audit_logs.before_values IS '修改前的值';
+COMMENT ON COLUMN audit_logs.after_values IS '修改后的值';
+COMMENT ON COLUMN audit_logs.trace_id IS '请求 trace_id，与日志中的 trace_id 对应';
+COMMENT ON COLUMN audit_logs.ip IS '客户端IP';
@@ -0,0 +1,3 @@
+-- 删除公告表和充电规则同意记录表
+DROP TABLE IF EXISTS rule_acceptances;
+DROP TABLE IF EXISTS announcements;
@@ -0,0 +1,37 @@
+-- 群组公告表，充电规则(kind=rules)按版本发布，成员需同意最新版本后才能预约
+CREATE TABLE IF NOT EXISTS announcements (
+    id SERIAL PRIMARY KEY,
+    kind VARCHAR(20) NOT NULL DEFAULT 'notice',
+    version INTEGER NOT NULL,
+    title VARCHAR(100) NOT NULL,
+    content TEXT NOT NULL,
+    published_by INTEGER,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_announcements_kind_version ON announcements(kind, version);
+CREATE INDEX IF NOT EXISTS idx_announcements_deleted_at ON announcements(deleted_at);
+
+COMMENT ON TABLE announcements IS '群组公告表';
+COMMENT ON COLUMN announcements.kind IS '类型:notice普通公告,rules充电规则';
+COMMENT ON COLUMN announcements.version IS '同类型内的版本号，从1递增';
+COMMENT ON COLUMN announcements.published_by IS '发布人ID（逻辑关联，无外键约束）';
+
+-- 成员同意充电规则的记录
+CREATE TABLE IF NOT EXISTS rule_acceptances (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    announcement_id INTEGER NOT NULL,
+    version INTEGER NOT NULL,
+    accepted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_rule_acceptances_user_version ON rule_acceptances(user_id, version);
+CREATE INDEX IF NOT EXISTS idx_rule_acceptances_version ON rule_acceptances(version);
+
+COMMENT ON TABLE rule_acceptances IS '充电规则同意记录';
+COMMENT ON COLUMN rule_acceptances.user_id IS '用户ID（逻辑关联，无外键约束）';
+COMMENT ON COLUMN rule_acceptances.announcement_id IS '充电规则公告ID（逻辑关联，无外键约束）';
+COMMENT ON COLUMN rule_acceptances.version IS '同意的规则版本号';
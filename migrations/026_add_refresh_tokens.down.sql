@@ -0,0 +1,2 @@
+-- 删除刷新令牌表
+DROP TABLE IF EXISTS refresh_tokens;
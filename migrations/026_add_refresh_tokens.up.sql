@@ -0,0 +1,29 @@
+-- 刷新令牌表，只保存令牌哈希；同一次登录轮换出的令牌属于同一会话(session_id)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    session_id VARCHAR(32) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    access_jti VARCHAR(32),
+    access_expires_at TIMESTAMP,
+    used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    revoke_reason VARCHAR(20),
+    ip VARCHAR(64),
+    user_agent VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens(token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_session_id ON refresh_tokens(session_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id, revoked_at);
+
+COMMENT ON TABLE refresh_tokens IS '刷新令牌表';
+COMMENT ON COLUMN refresh_tokens.user_id IS '用户ID（逻辑关联，无外键约束）';
+COMMENT ON COLUMN refresh_tokens.session_id IS '登录会话ID，与访问令牌的 sid 一致';
+COMMENT ON COLUMN refresh_tokens.token_hash IS '刷新令牌的 SHA-256 哈希';
+COMMENT ON COLUMN refresh_tokens.access_jti IS '同时签发的访问令牌ID，注销时加入黑名单';
+COMMENT ON COLUMN refresh_tokens.access_expires_at IS '同时签发的访问令牌过期时间';
+COMMENT ON COLUMN refresh_tokens.used_at IS '已轮换的时间，再次使用视为令牌泄露';
+COMMENT ON COLUMN refresh_tokens.revoke_reason IS '注销原因:logout,reuse,admin';
@@ -0,0 +1,22 @@
+-- 恢复为统计全部未作废的充电记录
+COMMENT ON TABLE user_day_stats IS '用户按日用电统计表（仅统计未作废的充电记录）';
+COMMENT ON TABLE user_month_stats IS '用户按月用电统计表（仅统计未作废的充电记录）';
+
+DELETE FROM user_day_stats;
+DELETE FROM user_month_stats;
+
+INSERT INTO user_day_stats (user_id, date, day_kwh, night_kwh, total_kwh, total_amount, record_count)
+SELECT user_id, date,
+    COALESCE(SUM(CASE WHEN timeslot = 'day' THEN kwh ELSE 0 END), 0),
+    COALESCE(SUM(CASE WHEN timeslot = 'night' THEN kwh ELSE 0 END), 0),
+    COALESCE(SUM(kwh), 0),
+    COALESCE(SUM(amount), 0),
+    COUNT(*)
+FROM records
+WHERE voided_at IS NULL AND deleted_at IS NULL
+GROUP BY user_id, date;
+
+INSERT INTO user_month_stats (user_id, month, day_kwh, night_kwh, total_kwh, total_amount, record_count)
+SELECT user_id, to_char(date, 'YYYY-MM'), SUM(day_kwh), SUM(night_kwh), SUM(total_kwh), SUM(total_amount), SUM(record_count)
+FROM user_day_stats
+GROUP BY user_id, to_char(date, 'YYYY-MM');
@@ -0,0 +1,22 @@
+-- 用电统计表改为只统计审核通过且未作废的充电记录，与月度对账口径一致，并按新口径重建现有数据
+COMMENT ON TABLE user_day_stats IS '用户按日用电统计表（仅统计审核通过且未作废的充电记录）';
+COMMENT ON TABLE user_month_stats IS '用户按月用电统计表（仅统计审核通过且未作废的充电记录）';
+
+DELETE FROM user_day_stats;
+DELETE FROM user_month_stats;
+
+INSERT INTO user_day_stats (user_id, date, day_kwh, night_kwh, total_kwh, total_amount, record_count)
+SELECT user_id, date,
+    COALESCE(SUM(CASE WHEN timeslot = 'day' THEN kwh ELSE 0 END), 0),
+    COALESCE(SUM(CASE WHEN timeslot = 'night' THEN kwh ELSE 0 END), 0),
+    COALESCE(SUM(kwh), 0),
+    COALESCE(SUM(amount), 0),
+    COUNT(*)
+FROM records
+WHERE voided_at IS NULL AND deleted_at IS NULL AND review_status = 'approved'
+GROUP BY user_id, date;
+
+INSERT INTO user_month_stats (user_id, month, day_kwh, night_kwh, total_kwh, total_amount, record_count)
+SELECT user_id, to_char(date, 'YYYY-MM'), SUM(day_kwh), SUM(night_kwh), SUM(total_kwh), SUM(total_amount), SUM(record_count)
+FROM user_day_stats
+GROUP BY user_id, to_char(date, 'YYYY-MM');
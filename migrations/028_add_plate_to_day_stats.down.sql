@@ -0,0 +1,17 @@
+-- 去掉按日统计表的车牌号维度，按用户、日期重新汇总
+DROP INDEX IF EXISTS uniq_user_day_stats_user_date_plate;
+DELETE FROM user_day_stats;
+ALTER TABLE user_day_stats DROP COLUMN IF EXISTS license_plate_id;
+
+INSERT INTO user_day_stats (user_id, date, day_kwh, night_kwh, total_kwh, total_amount, record_count)
+SELECT user_id, date,
+    COALESCE(SUM(CASE WHEN timeslot = 'day' THEN kwh ELSE 0 END), 0),
+    COALESCE(SUM(CASE WHEN timeslot = 'night' THEN kwh ELSE 0 END), 0),
+    COALESCE(SUM(kwh), 0),
+    COALESCE(SUM(amount), 0),
+    COUNT(*)
+FROM records
+WHERE voided_at IS NULL AND deleted_at IS NULL AND review_status = 'approved'
+GROUP BY user_id, date;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_user_day_stats_user_date ON user_day_stats(user_id, date);
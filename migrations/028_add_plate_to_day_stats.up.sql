@@ -0,0 +1,19 @@
+-- 按日统计表增加车牌号维度，车辆统计改为读取统计表；未关联车牌号的记录计入 license_plate_id = 0
+ALTER TABLE user_day_stats ADD COLUMN IF NOT EXISTS license_plate_id INTEGER NOT NULL DEFAULT 0;
+COMMENT ON COLUMN user_day_stats.license_plate_id IS '车牌号ID，0表示未关联车牌号';
+
+DROP INDEX IF EXISTS uniq_user_day_stats_user_date;
+DELETE FROM user_day_stats;
+
+INSERT INTO user_day_stats (user_id, date, license_plate_id, day_kwh, night_kwh, total_kwh, total_amount, record_count)
+SELECT user_id, date, COALESCE(license_plate_id, 0),
+    COALESCE(SUM(CASE WHEN timeslot = 'day' THEN kwh ELSE 0 END), 0),
+    COALESCE(SUM(CASE WHEN timeslot = 'night' THEN kwh ELSE 0 END), 0),
+    COALESCE(SUM(kwh), 0),
+    COALESCE(SUM(amount), 0),
+    COUNT(*)
+FROM records
+WHERE voided_at IS NULL AND deleted_at IS NULL AND review_status = 'approved'
+GROUP BY user_id, date, COALESCE(license_plate_id, 0);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_user_day_stats_user_date_plate ON user_day_stats(user_id, date, license_plate_id);
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 公告类型
+const (
+	AnnouncementKindNotice = "notice"
+	AnnouncementKindRules  = "rules"
+)
+
+// Announcement 群组公告，每次发布新增一条，版本号在同类型内递增
+// 充电规则(rules)以最新未撤回的版本为准，成员同意该版本后才能预约
+type Announcement struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	Kind        string         `json:"kind" gorm:"size:20;not null;default:'notice';uniqueIndex:idx_announcements_kind_version;comment:类型:notice,rules"`
+	Version     int            `json:"version" gorm:"not null;uniqueIndex:idx_announcements_kind_version;comment:同类型内的版本号"`
+	Title       string         `json:"title" gorm:"size:100;not null;comment:标题"`
+	Content     string         `json:"content" gorm:"type:text;not null;comment:内容"`
+	PublishedBy uint           `json:"published_by" gorm:"comment:发布人ID"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggerignore:"true"`
+}
+
+// TableName 指定表名
+func (Announcement) TableName() string {
+	return "announcements"
+}
+
+// IsRules 是否为充电规则
+func (a *Announcement) IsRules() bool {
+	return a.Kind == AnnouncementKindRules
+}
+
+// RuleAcceptance 成员同意某一版本充电规则的记录
+type RuleAcceptance struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_rule_acceptances_user_version;comment:用户ID"`
+	AnnouncementID uint      `json:"announcement_id" gorm:"not null;comment:充电规则公告ID"`
+	Version        int       `json:"version" gorm:"not null;uniqueIndex:idx_rule_acceptances_user_version;comment:同意的规则版本号"`
+	AcceptedAt     time.Time `json:"accepted_at" gorm:"not null;comment:同意时间"`
+}
+
+// TableName 指定表名
+func (RuleAcceptance) TableName() string {
+	return "rule_acceptances"
+}
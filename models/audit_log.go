@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// AuditActorSystem 系统任务（如电价计划到期生效）写审计日志时的角色，没有操作人
+const AuditActorSystem = "system"
+
+// AuditLog 审计日志表（只追加，数据库触发器禁止修改和删除）
+type AuditLog struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	ActorID      *uint     `json:"actor_id" gorm:"comment:操作人ID"`
+	ActorRole    string    `json:"actor_role" gorm:"size:20;comment:操作时的角色:user,admin,system"`
+	Action       string    `json:"action" gorm:"size:64;not null;comment:操作"`
+	TargetType   string    `json:"target_type" gorm:"size:32;comment:操作对象类型"`
+	TargetID     uint      `json:"target_id" gorm:"comment:操作对象ID"`
+	BeforeValues string    `json:"before_values" gorm:"type:jsonb;comment:修改前的值"`
+	AfterValues  string    `json:"after_values" gorm:"type:jsonb;comment:修改后的值"`
+	TraceID      string    `json:"trace_id" gorm:"size:32;comment:请求trace_id"`
+	IP           string    `json:"ip" gorm:"column:ip;size:64;comment:客户端IP"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
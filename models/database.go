@@ -1,10 +1,12 @@
 package models
 
 import (
+	"errors"
 	"fmt"
 	"shared-charge/config"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -45,3 +47,12 @@ func InitDB() {
 	sqlDB.SetConnMaxIdleTime(30 * time.Minute) // 空闲连接超时时间
 
 }
+
+// IsUniqueViolation 判断错误是否为违反指定唯一索引（constraint 为空时匹配任意唯一索引）
+func IsUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != "23505" {
+		return false
+	}
+	return constraint == "" || pgErr.ConstraintName == constraint
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// 优惠类型
+const (
+	DiscountTypePercentage  = "percentage"   // 按比例折扣，Value 为折扣百分比(如 10 表示九折)
+	DiscountTypeFixedCredit = "fixed_credit" // 每月固定抵扣金额，Value 单位为元
+	DiscountTypeFreeKWH     = "free_kwh"     // 每月免费度数，Value 单位为度
+)
+
+// 优惠适用范围
+const (
+	DiscountScopeUser  = "user"
+	DiscountScopePlate = "plate"
+	DiscountScopeAll   = "all"
+)
+
+// DiscountRule 优惠规则表
+type DiscountRule struct {
+	ID             uint            `json:"id" gorm:"primaryKey"`
+	Name           string          `json:"name" gorm:"size:100;not null;comment:规则名称"`
+	Type           string          `json:"type" gorm:"size:20;not null;comment:类型:percentage,fixed_credit,free_kwh"`
+	Value          decimal.Decimal `json:"value" gorm:"type:decimal(12,4);not null;comment:折扣百分比/每月抵扣金额(元)/每月免费度数"`
+	Scope          string          `json:"scope" gorm:"size:20;not null;comment:范围:user,plate,all"`
+	UserID         *uint           `json:"user_id" gorm:"comment:适用用户ID"`
+	LicensePlateID *uint           `json:"license_plate_id" gorm:"comment:适用车牌号ID"`
+	StartDate      *time.Time      `json:"start_date" gorm:"type:date;comment:生效日期"`
+	EndDate        *time.Time      `json:"end_date" gorm:"type:date;comment:失效日期"`
+	Active         bool            `json:"active" gorm:"not null;default:true;comment:是否启用"`
+	CreatedBy      uint            `json:"created_by" gorm:"comment:创建人ID"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt  `json:"deleted_at" gorm:"index" swaggerignore:"true"`
+}
+
+// TableName 指定表名
+func (DiscountRule) TableName() string {
+	return "discount_rules"
+}
+
+// AppliesOn 规则在指定日期是否生效
+func (d *DiscountRule) AppliesOn(date time.Time) bool {
+	day := date.Format("2006-01-02")
+	if d.StartDate != nil && day < d.StartDate.Format("2006-01-02") {
+		return false
+	}
+	if d.EndDate != nil && day > d.EndDate.Format("2006-01-02") {
+		return false
+	}
+	return d.Active
+}
+
+// FormatDiscountRuleInfo 格式化优惠规则
+func (d *DiscountRule) FormatDiscountRuleInfo() map[string]interface{} {
+	result := map[string]interface{}{
+		"id":               d.ID,
+		"name":             d.Name,
+		"type":             d.Type,
+		"value":            d.Value,
+		"scope":            d.Scope,
+		"user_id":          d.UserID,
+		"license_plate_id": d.LicensePlateID,
+		"start_date":       nil,
+		"end_date":         nil,
+		"active":           d.Active,
+		"created_by":       d.CreatedBy,
+		"created_at":       d.CreatedAt,
+		"updated_at":       d.UpdatedAt,
+	}
+	if d.StartDate != nil {
+		result["start_date"] = d.StartDate.Format("2006-01-02")
+	}
+	if d.EndDate != nil {
+		result["end_date"] = d.EndDate.Format("2006-01-02")
+	}
+	return result
+}
+
+// RecordDiscount 充电记录实际使用的优惠明细
+type RecordDiscount struct {
+	ID        uint            `json:"id" gorm:"primaryKey"`
+	RecordID  uint            `json:"record_id" gorm:"not null;index;comment:充电记录ID"`
+	RuleID    uint            `json:"rule_id" gorm:"not null;index;comment:优惠规则ID"`
+	RuleName  string          `json:"rule_name" gorm:"size:100;comment:规则名称(快照)"`
+	Type      string          `json:"type" gorm:"size:20;not null;comment:优惠类型"`
+	KWH       decimal.Decimal `json:"kwh" gorm:"type:decimal(10,2);not null;default:0;comment:抵扣的免费度数"`
+	Amount    int64           `json:"amount" gorm:"not null;comment:优惠金额(分)"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// TableName 指定表名
+func (RecordDiscount) TableName() string {
+	return "record_discounts"
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// InviteCode 群组邀请码，新用户登录时兑换后直接成为正常成员
+// MaxUses 为 0 表示不限次数；UnitPrice 为空时使用默认电价
+type InviteCode struct {
+	ID         uint             `json:"id" gorm:"primaryKey"`
+	Code       string           `json:"code" gorm:"size:32;uniqueIndex;not null;comment:邀请码"`
+	Remark     string           `json:"remark" gorm:"size:255;comment:备注"`
+	ExpiresAt  *time.Time       `json:"expires_at" gorm:"comment:过期时间"`
+	MaxUses    int              `json:"max_uses" gorm:"not null;default:0;comment:最多可用次数,0为不限"`
+	UsedCount  int              `json:"used_count" gorm:"not null;default:0;comment:已使用次数"`
+	UnitPrice  *decimal.Decimal `json:"unit_price" gorm:"type:decimal(12,4);comment:兑换用户的电价"`
+	CanReserve bool             `json:"can_reserve" gorm:"not null;default:false;comment:兑换用户是否可预约"`
+	DisabledAt *time.Time       `json:"disabled_at" gorm:"comment:停用时间"`
+	CreatedBy  uint             `json:"created_by" gorm:"comment:创建人ID"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt   `json:"deleted_at" gorm:"index" swaggerignore:"true"`
+}
+
+// TableName 指定表名
+func (InviteCode) TableName() string {
+	return "invite_codes"
+}
+
+// UsesLeft 剩余可用次数，不限次数时返回 nil
+func (ic *InviteCode) UsesLeft() *int {
+	if ic.MaxUses == 0 {
+		return nil
+	}
+	left := ic.MaxUses - ic.UsedCount
+	if left < 0 {
+		left = 0
+	}
+	return &left
+}
+
+// IsUsable 邀请码当前是否可兑换
+func (ic *InviteCode) IsUsable(now time.Time) bool {
+	if ic.DisabledAt != nil {
+		return false
+	}
+	if ic.ExpiresAt != nil && !ic.ExpiresAt.After(now) {
+		return false
+	}
+	return ic.MaxUses == 0 || ic.UsedCount < ic.MaxUses
+}
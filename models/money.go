@@ -0,0 +1,43 @@
+package models
+
+import (
+	"shared-charge/config"
+
+	"github.com/shopspring/decimal"
+)
+
+// 金额舍入方式
+const (
+	RoundingHalfUp = "half_up"
+	RoundingBanker = "banker"
+)
+
+// 数值精度（小数位数）
+const (
+	KWHPlaces   int32 = 2 // 充电度数
+	PricePlaces int32 = 4 // 单价(元/度)
+	YuanPlaces  int32 = 2 // 金额(元)
+)
+
+func init() {
+	// 接口中的金额与度数仍以数字而不是字符串返回
+	decimal.MarshalJSONWithoutQuotes = true
+}
+
+// RoundDecimal 按配置的舍入方式保留 places 位小数
+func RoundDecimal(value decimal.Decimal, places int32) decimal.Decimal {
+	if config.GetConfig().App.RoundingMode == RoundingBanker {
+		return value.RoundBank(places)
+	}
+	return value.Round(places)
+}
+
+// CalculateAmountFen 计算费用(分)：度数 × 单价，按舍入方式取整到分
+func CalculateAmountFen(kwh, unitPrice decimal.Decimal) int64 {
+	return RoundDecimal(kwh.Mul(unitPrice).Shift(2), 0).IntPart()
+}
+
+// FenToYuan 将分转换为元，结果精确无浮点误差
+func FenToYuan(fen int64) decimal.Decimal {
+	return decimal.New(fen, -YuanPlaces)
+}
@@ -0,0 +1,102 @@
+package models
+
+import (
+	"shared-charge/config"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+// useRoundingMode 测试期间切换舍入方式，结束后恢复
+func useRoundingMode(t *testing.T, mode string) {
+	t.Helper()
+	config.LoadConfig()
+	cfg := config.GetConfig()
+	previous := cfg.App.RoundingMode
+	cfg.App.RoundingMode = mode
+	t.Cleanup(func() { cfg.App.RoundingMode = previous })
+}
+
+func TestRoundDecimal(t *testing.T) {
+	tests := []struct {
+		mode   string
+		value  string
+		places int32
+		want   string
+	}{
+		{RoundingHalfUp, "0.125", 2, "0.13"},
+		{RoundingBanker, "0.125", 2, "0.12"},
+		{RoundingHalfUp, "0.135", 2, "0.14"},
+		{RoundingBanker, "0.135", 2, "0.14"},
+		{RoundingHalfUp, "2.5", 0, "3"},
+		{RoundingBanker, "2.5", 0, "2"},
+		{RoundingHalfUp, "3.5", 0, "4"},
+		{RoundingBanker, "3.5", 0, "4"},
+		{RoundingHalfUp, "-2.5", 0, "-3"},
+		{RoundingBanker, "-2.5", 0, "-2"},
+		{RoundingHalfUp, "0.12345", PricePlaces, "0.1235"},
+		{RoundingBanker, "0.12345", PricePlaces, "0.1234"},
+		{RoundingHalfUp, "0.124", 2, "0.12"},
+		{RoundingBanker, "0.126", 2, "0.13"},
+		{RoundingBanker, "0.1251", 2, "0.13"},
+		{RoundingHalfUp, "12.3", 2, "12.3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.value, func(t *testing.T) {
+			useRoundingMode(t, tt.mode)
+			got := RoundDecimal(decimal.RequireFromString(tt.value), tt.places)
+			if !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("RoundDecimal(%s, %d) = %s, want %s", tt.value, tt.places, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateAmountFen(t *testing.T) {
+	tests := []struct {
+		mode      string
+		kwh       string
+		unitPrice string
+		want      int64
+	}{
+		{RoundingHalfUp, "10", "0.5", 500},
+		{RoundingHalfUp, "12.34", "0.6", 740},     // 7.404 元
+		{RoundingHalfUp, "0.5", "0.125", 6},       // 6.25 分
+		{RoundingBanker, "0.5", "0.125", 6},       // 6.25 分
+		{RoundingHalfUp, "1", "0.0125", 1},        // 1.25 分
+		{RoundingHalfUp, "2", "0.0125", 3},        // 2.5 分
+		{RoundingBanker, "2", "0.0125", 2},        // 2.5 分，舍入到偶数
+		{RoundingHalfUp, "6", "0.0125", 8},        // 7.5 分
+		{RoundingBanker, "6", "0.0125", 8},        // 7.5 分，舍入到偶数
+		{RoundingHalfUp, "33.33", "0.5678", 1892}, // 18.924774 元
+		{RoundingHalfUp, "0.01", "0.0001", 0},
+		{RoundingHalfUp, "0", "1.2", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode+"/"+tt.kwh+"x"+tt.unitPrice, func(t *testing.T) {
+			useRoundingMode(t, tt.mode)
+			got := CalculateAmountFen(decimal.RequireFromString(tt.kwh), decimal.RequireFromString(tt.unitPrice))
+			if got != tt.want {
+				t.Errorf("CalculateAmountFen(%s, %s) = %d, want %d", tt.kwh, tt.unitPrice, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFenToYuan(t *testing.T) {
+	tests := []struct {
+		fen  int64
+		want string
+	}{
+		{0, "0.00"},
+		{1, "0.01"},
+		{10, "0.10"},
+		{1234, "12.34"},
+		{-250, "-2.50"},
+	}
+	for _, tt := range tests {
+		if got := FenToYuan(tt.fen).StringFixed(YuanPlaces); got != tt.want {
+			t.Errorf("FenToYuan(%d) = %s, want %s", tt.fen, got, tt.want)
+		}
+	}
+}
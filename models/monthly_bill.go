@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// 月度账单支付状态
+const (
+	BillStatusUnpaid = "unpaid"
+	BillStatusPaid   = "paid"
+)
+
+// MonthlyBill 用户月度账单的支付状态，每个用户每月一条
+type MonthlyBill struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"not null;uniqueIndex:uniq_monthly_bills_user_month;comment:用户ID"`
+	Month     string     `json:"month" gorm:"size:7;not null;uniqueIndex:uniq_monthly_bills_user_month;comment:账单月份(YYYY-MM)"`
+	Amount    int64      `json:"amount" gorm:"not null;default:0;comment:标记支付时的应付金额(分)"`
+	Status    string     `json:"status" gorm:"size:20;not null;default:'unpaid';comment:支付状态:unpaid,paid"`
+	PaidAt    *time.Time `json:"paid_at" gorm:"comment:支付时间"`
+	MarkedBy  *uint      `json:"marked_by" gorm:"comment:标记支付状态的管理员ID"`
+	Remark    string     `json:"remark" gorm:"size:255;comment:备注"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (MonthlyBill) TableName() string {
+	return "monthly_bills"
+}
+
+// IsPaid 检查是否已支付
+func (b *MonthlyBill) IsPaid() bool {
+	return b.Status == BillStatusPaid
+}
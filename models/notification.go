@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification 站内通知表
+type Notification struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    uint           `json:"user_id" gorm:"not null;index;comment:接收用户ID"`
+	Type      string         `json:"type" gorm:"size:50;not null;comment:通知类型"`
+	Title     string         `json:"title" gorm:"size:100;not null;comment:标题"`
+	Content   string         `json:"content" gorm:"size:500;comment:内容"`
+	RelatedID uint           `json:"related_id" gorm:"comment:关联业务ID"`
+	ReadAt    *time.Time     `json:"read_at" gorm:"comment:已读时间"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggerignore:"true"`
+}
+
+// TableName 指定表名
+func (Notification) TableName() string {
+	return "notifications"
+}
+
+// IsRead 检查是否已读
+func (n *Notification) IsRead() bool {
+	return n.ReadAt != nil
+}
+
+// FormatNotificationInfo 格式化通知信息
+func (n *Notification) FormatNotificationInfo() map[string]interface{} {
+	return map[string]interface{}{
+		"id":         n.ID,
+		"type":       n.Type,
+		"title":      n.Title,
+		"content":    n.Content,
+		"related_id": n.RelatedID,
+		"is_read":    n.IsRead(),
+		"read_at":    n.ReadAt,
+		"created_at": n.CreatedAt,
+	}
+}
@@ -0,0 +1,91 @@
+package models
+
+// 权限，管理端接口按权限而不是角色校验
+const (
+	PermUsersView           = "users.view"
+	PermUsersManage         = "users.manage"
+	PermUsersRoles          = "users.roles"
+	PermUsersPrice          = "users.price"
+	PermReservationsManage  = "reservations.manage"
+	PermRecordsReview       = "records.review"
+	PermBillingDiscounts    = "billing.discounts"
+	PermBillingCloseMonth   = "billing.close_month"
+	PermReportsView         = "reports.view"
+	PermUsageView           = "usage.view"
+	PermReportsExport       = "reports.export"
+	PermAuditView           = "audit.view"
+	PermAnnouncementsManage = "announcements.manage"
+)
+
+// 管理角色，普通成员为 UserRoleUser，没有任何管理权限
+const (
+	UserRoleTreasurer = "treasurer"
+	UserRoleScheduler = "scheduler"
+	UserRoleViewer    = "viewer"
+)
+
+// AllPermissions 全部权限，管理员拥有全部权限
+var AllPermissions = []string{
+	PermUsersView,
+	PermUsersManage,
+	PermUsersRoles,
+	PermUsersPrice,
+	PermReservationsManage,
+	PermRecordsReview,
+	PermBillingDiscounts,
+	PermBillingCloseMonth,
+	PermReportsView,
+	PermUsageView,
+	PermReportsExport,
+	PermAuditView,
+	PermAnnouncementsManage,
+}
+
+// RolePermissions 角色对应的权限
+var RolePermissions = map[string][]string{
+	UserRoleAdmin: AllPermissions,
+	// 财务：电价、优惠、账单结算和报表，不能管理成员状态和角色
+	UserRoleTreasurer: {
+		PermUsersView,
+		PermUsersPrice,
+		PermBillingDiscounts,
+		PermBillingCloseMonth,
+		PermReportsView,
+		PermUsageView,
+		PermReportsExport,
+		PermAuditView,
+	},
+	// 调度：预约、充电记录审核、车位使用率和充电规则公告，不能查看费用
+	UserRoleScheduler: {
+		PermUsersView,
+		PermReservationsManage,
+		PermRecordsReview,
+		PermUsageView,
+		PermAnnouncementsManage,
+	},
+	// 只读：查看成员和车位使用率，不能查看费用
+	UserRoleViewer: {
+		PermUsersView,
+		PermUsageView,
+	},
+	UserRoleUser: {},
+}
+
+// Roles 可分配的角色，按权限从多到少排列
+var Roles = []string{UserRoleAdmin, UserRoleTreasurer, UserRoleScheduler, UserRoleViewer, UserRoleUser}
+
+// IsValidRole 是否为可分配的角色
+func IsValidRole(role string) bool {
+	_, ok := RolePermissions[role]
+	return ok
+}
+
+// RoleHasPermission 角色是否拥有指定权限
+func RoleHasPermission(role, permission string) bool {
+	for _, p := range RolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -1,32 +1,55 @@
 package models
 
 import (
-	"math"
+	"encoding/json"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
+// 充电记录审核状态
+const (
+	ReviewStatusSubmitted = "submitted"
+	ReviewStatusApproved  = "approved"
+	ReviewStatusRejected  = "rejected"
+)
+
 // Record 充电记录表
 type Record struct {
-	ID             uint           `json:"id" gorm:"primaryKey"`
-	UserID         uint           `json:"user_id" gorm:"not null;comment:用户ID"`
-	Date           time.Time      `json:"date" gorm:"type:date;not null;comment:充电日期(无时区)"`
-	KWH            float64        `json:"kwh" gorm:"not null;comment:充电度数(kWh)"`
-	Amount         int64          `json:"amount" gorm:"not null;comment:费用金额(分)"`
-	UnitPrice      float64        `json:"unit_price" gorm:"not null;comment:单价"`
-	ImageURL       string         `json:"image_url" gorm:"column:image_url;size:255;comment:电量截图URL"`
-	Remark         string         `json:"remark" gorm:"size:255;comment:备注"`
-	CreatedAt      time.Time      `json:"created_at"`
-	UpdatedAt      time.Time      `json:"updated_at"`
-	DeletedAt      gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggerignore:"true"`
-	ReservationID  uint           `json:"reservation_id"`
-	Timeslot       string         `json:"timeslot" gorm:"size:20;comment:班次:day,night"`
-	LicensePlateID *uint          `json:"license_plate_id" gorm:"comment:关联的车牌号ID"`
+	ID             uint             `json:"id" gorm:"primaryKey"`
+	UserID         uint             `json:"user_id" gorm:"not null;comment:用户ID"`
+	Date           time.Time        `json:"date" gorm:"type:date;not null;comment:充电日期(无时区)"`
+	KWH            decimal.Decimal  `json:"kwh" gorm:"type:decimal(10,2);not null;comment:充电度数(kWh)"`
+	Amount         int64            `json:"amount" gorm:"not null;comment:费用金额(分，已扣除优惠)"`
+	GrossAmount    int64            `json:"gross_amount" gorm:"not null;default:0;comment:优惠前费用金额(分)"`
+	DiscountAmount int64            `json:"discount_amount" gorm:"not null;default:0;comment:优惠金额(分)"`
+	UnitPrice      decimal.Decimal  `json:"unit_price" gorm:"type:decimal(12,4);not null;comment:单价(元/度)"`
+	ImageURL       string           `json:"image_url" gorm:"column:image_url;size:255;comment:电量截图URL"`
+	Remark         string           `json:"remark" gorm:"size:255;comment:备注"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	DeletedAt      gorm.DeletedAt   `json:"deleted_at" gorm:"index" swaggerignore:"true"`
+	ReservationID  uint             `json:"reservation_id"`
+	Timeslot       string           `json:"timeslot" gorm:"size:20;comment:班次:day,night"`
+	LicensePlateID *uint            `json:"license_plate_id" gorm:"comment:关联的车牌号ID"`
+	ReviewStatus   string           `json:"review_status" gorm:"size:20;not null;default:'submitted';comment:审核状态:submitted,approved,rejected"`
+	ReviewReason   string           `json:"review_reason" gorm:"size:255;comment:审核意见(驳回原因)"`
+	ReviewedBy     *uint            `json:"reviewed_by" gorm:"comment:审核人ID"`
+	ReviewedAt     *time.Time       `json:"reviewed_at" gorm:"comment:审核时间"`
+	AnomalyScore   int              `json:"anomaly_score" gorm:"not null;default:0;comment:异常分数"`
+	AnomalyReasons *string          `json:"anomaly_reasons" gorm:"type:jsonb;comment:异常原因列表(无异常时为NULL)"`
+	OCRKWH         *decimal.Decimal `json:"ocr_kwh" gorm:"column:ocr_kwh;type:decimal(10,2);comment:截图识别出的度数"`
+	OCRMeter       *decimal.Decimal `json:"ocr_meter_reading" gorm:"column:ocr_meter_reading;type:decimal(12,2);comment:截图识别出的电表读数"`
+	OCRConfidence  *float64         `json:"ocr_confidence" gorm:"column:ocr_confidence;comment:截图识别置信度(0-1)"`
+	VoidedAt       *time.Time       `json:"voided_at" gorm:"comment:作废时间"`
+	VoidedBy       *uint            `json:"voided_by" gorm:"comment:作废操作人ID"`
+	VoidReason     string           `json:"void_reason" gorm:"size:255;comment:作废原因"`
 
 	// 关联关系
-	User         User          `json:"user,omitempty" gorm:"foreignKey:UserID"`
-	LicensePlate *LicensePlate `json:"license_plate,omitempty" gorm:"foreignKey:LicensePlateID"`
+	User         User             `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	LicensePlate *LicensePlate    `json:"license_plate,omitempty" gorm:"foreignKey:LicensePlateID"`
+	Discounts    []RecordDiscount `json:"discounts,omitempty" gorm:"foreignKey:RecordID"`
 }
 
 // TableName 指定表名
@@ -34,26 +57,69 @@ func (Record) TableName() string {
 	return "records"
 }
 
-// CalculateAmount 计算费用
+// CalculateAmount 计算优惠前费用，优惠由 service 层按规则扣减
 func (r *Record) CalculateAmount() {
-	r.Amount = int64(math.Round(r.KWH * r.UnitPrice * 100)) // 单位为分
+	r.GrossAmount = CalculateAmountFen(r.KWH, r.UnitPrice) // 单位为分
+	r.DiscountAmount = 0
+	r.Amount = r.GrossAmount
+}
+
+// ActiveRecords 仅包含未作废的充电记录，统计与对账均应使用该条件
+func ActiveRecords(db *gorm.DB) *gorm.DB {
+	return db.Where("records.voided_at IS NULL")
+}
+
+// IsVoided 检查是否已作废
+func (r *Record) IsVoided() bool {
+	return r.VoidedAt != nil
+}
+
+// IsApproved 检查是否已审核通过
+func (r *Record) IsApproved() bool {
+	return r.ReviewStatus == ReviewStatusApproved
+}
+
+// IsRejected 检查是否已被驳回
+func (r *Record) IsRejected() bool {
+	return r.ReviewStatus == ReviewStatusRejected
+}
+
+// AnomalyReasonsJSON 返回异常原因的原始JSON，无异常时返回空数组
+func (r *Record) AnomalyReasonsJSON() json.RawMessage {
+	if r.AnomalyReasons == nil || *r.AnomalyReasons == "" {
+		return json.RawMessage("[]")
+	}
+	return json.RawMessage(*r.AnomalyReasons)
 }
 
 // FormatRecordInfo 格式化记录信息
 func (r *Record) FormatRecordInfo() map[string]interface{} {
 	result := map[string]interface{}{
-		"id":             r.ID,
-		"user_id":        r.UserID,
-		"date":           r.Date.Format("2006-01-02"),
-		"kwh":            r.KWH,
-		"amount":         r.Amount,
-		"unit_price":     r.UnitPrice,
-		"image_url":      r.ImageURL,
-		"remark":         r.Remark,
-		"timeslot":       r.Timeslot,
-		"reservation_id": r.ReservationID,
-		"created_at":     r.CreatedAt,
-		"updated_at":     r.UpdatedAt,
+		"id":                r.ID,
+		"user_id":           r.UserID,
+		"date":              r.Date.Format("2006-01-02"),
+		"kwh":               r.KWH,
+		"amount":            r.Amount,
+		"gross_amount":      r.GrossAmount,
+		"discount_amount":   r.DiscountAmount,
+		"unit_price":        r.UnitPrice,
+		"image_url":         r.ImageURL,
+		"remark":            r.Remark,
+		"timeslot":          r.Timeslot,
+		"reservation_id":    r.ReservationID,
+		"review_status":     r.ReviewStatus,
+		"review_reason":     r.ReviewReason,
+		"reviewed_at":       r.ReviewedAt,
+		"anomaly_score":     r.AnomalyScore,
+		"anomaly_reasons":   r.AnomalyReasonsJSON(),
+		"ocr_kwh":           r.OCRKWH,
+		"ocr_meter_reading": r.OCRMeter,
+		"ocr_confidence":    r.OCRConfidence,
+		"voided":            r.IsVoided(),
+		"voided_at":         r.VoidedAt,
+		"void_reason":       r.VoidReason,
+		"created_at":        r.CreatedAt,
+		"updated_at":        r.UpdatedAt,
 	}
 
 	// 添加车牌号信息
@@ -63,6 +129,9 @@ func (r *Record) FormatRecordInfo() map[string]interface{} {
 			"plate_number": r.LicensePlate.PlateNumber,
 		}
 	}
+	if len(r.Discounts) > 0 {
+		result["discounts"] = r.Discounts
+	}
 
 	return result
 }
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 修订来源
+const (
+	RevisionSourceMember     = "member"
+	RevisionSourceAdmin      = "admin"
+	RevisionSourceCorrection = "correction"
+)
+
+// 更正申请状态
+const (
+	CorrectionStatusPending  = "pending"
+	CorrectionStatusApproved = "approved"
+	CorrectionStatusRejected = "rejected"
+)
+
+// RecordRevision 充电记录修订历史表（只追加）
+type RecordRevision struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	RecordID  uint      `json:"record_id" gorm:"not null;index;comment:充电记录ID"`
+	ChangedBy uint      `json:"changed_by" gorm:"not null;comment:修改人ID"`
+	Source    string    `json:"source" gorm:"size:20;not null;comment:来源:member,admin,correction"`
+	OldValues string    `json:"old_values" gorm:"type:jsonb;comment:修改前的值"`
+	NewValues string    `json:"new_values" gorm:"type:jsonb;comment:修改后的值"`
+	Reason    string    `json:"reason" gorm:"size:255;comment:修改原因"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// 关联关系
+	User User `json:"user,omitempty" gorm:"foreignKey:ChangedBy"`
+}
+
+// TableName 指定表名
+func (RecordRevision) TableName() string {
+	return "record_revisions"
+}
+
+// RecordCorrectionRequest 充电记录更正申请表
+type RecordCorrectionRequest struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	RecordID     uint           `json:"record_id" gorm:"not null;index;comment:充电记录ID"`
+	UserID       uint           `json:"user_id" gorm:"not null;comment:申请人ID"`
+	Changes      string         `json:"changes" gorm:"type:jsonb;not null;comment:申请修改的值"`
+	Reason       string         `json:"reason" gorm:"size:255;comment:申请原因"`
+	Status       string         `json:"status" gorm:"size:20;not null;default:'pending';comment:状态:pending,approved,rejected"`
+	ReviewedBy   *uint          `json:"reviewed_by" gorm:"comment:审核人ID"`
+	ReviewedAt   *time.Time     `json:"reviewed_at" gorm:"comment:审核时间"`
+	ReviewReason string         `json:"review_reason" gorm:"size:255;comment:审核意见"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggerignore:"true"`
+
+	// 关联关系
+	User   User   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	Record Record `json:"record,omitempty" gorm:"foreignKey:RecordID"`
+}
+
+// TableName 指定表名
+func (RecordCorrectionRequest) TableName() string {
+	return "record_correction_requests"
+}
+
+// IsPending 检查是否待审核
+func (r *RecordCorrectionRequest) IsPending() bool {
+	return r.Status == CorrectionStatusPending
+}
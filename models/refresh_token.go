@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// 刷新令牌注销原因
+const (
+	TokenRevokeLogout = "logout"
+	TokenRevokeReuse  = "reuse"
+	TokenRevokeAdmin  = "admin"
+)
+
+// RefreshToken 刷新令牌，只保存哈希；每次刷新标记 UsedAt 并签发同一会话的新令牌
+// 已轮换的令牌再次使用视为泄露，整个会话被注销
+type RefreshToken struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	UserID          uint       `json:"user_id" gorm:"not null;index:idx_refresh_tokens_user_id;comment:用户ID"`
+	SessionID       string     `json:"session_id" gorm:"size:32;not null;index;comment:登录会话ID"`
+	TokenHash       string     `json:"-" gorm:"size:64;not null;uniqueIndex;comment:刷新令牌的SHA-256哈希"`
+	ExpiresAt       time.Time  `json:"expires_at" gorm:"not null;comment:过期时间"`
+	AccessJTI       string     `json:"-" gorm:"column:access_jti;size:32;comment:同时签发的访问令牌ID"`
+	AccessExpiresAt *time.Time `json:"-" gorm:"comment:同时签发的访问令牌过期时间"`
+	UsedAt          *time.Time `json:"used_at" gorm:"comment:已轮换的时间"`
+	RevokedAt       *time.Time `json:"revoked_at" gorm:"index:idx_refresh_tokens_user_id;comment:注销时间"`
+	RevokeReason    string     `json:"revoke_reason" gorm:"size:20;comment:注销原因:logout,reuse,admin"`
+	IP              string     `json:"ip" gorm:"size:64;comment:签发时的IP"`
+	UserAgent       string     `json:"user_agent" gorm:"size:255;comment:签发时的User-Agent"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// TableName 指定表名
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// IsUsable 刷新令牌是否仍可使用：未轮换、未注销且未过期
+func (t *RefreshToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -41,6 +41,36 @@ func (r *Reservation) TimeslotText() string {
 	}
 }
 
+// StartTime 预约时段开始时间（白班 08:00，夜班 20:00）
+func (r *Reservation) StartTime() time.Time {
+	hour := 0
+	switch r.Timeslot {
+	case "day":
+		hour = 8
+	case "night":
+		hour = 20
+	}
+	return time.Date(r.Date.Year(), r.Date.Month(), r.Date.Day(), hour, 0, 0, 0, time.Local)
+}
+
+// EndTime 预约时段结束时间（白班当天 20:00，夜班次日 08:00）
+func (r *Reservation) EndTime() time.Time {
+	switch r.Timeslot {
+	case "day":
+		return time.Date(r.Date.Year(), r.Date.Month(), r.Date.Day(), 20, 0, 0, 0, time.Local)
+	case "night":
+		nextDay := r.Date.AddDate(0, 0, 1)
+		return time.Date(nextDay.Year(), nextDay.Month(), nextDay.Day(), 8, 0, 0, 0, time.Local)
+	default:
+		return time.Date(r.Date.Year(), r.Date.Month(), r.Date.Day(), 0, 0, 0, 0, time.Local)
+	}
+}
+
+// HasEnded 预约时段是否已结束
+func (r *Reservation) HasEnded(now time.Time) bool {
+	return now.After(r.EndTime())
+}
+
 // IsConfirmed 检查是否已确认
 func (r *Reservation) IsConfirmed() bool {
 	return r.Status == "confirmed"
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// UnitPriceSchedule 用户电价调整计划，生效日当天起按新电价计费
+// 到期后写入 users.unit_price 并记录 applied_at
+type UnitPriceSchedule struct {
+	ID            uint            `json:"id" gorm:"primaryKey"`
+	UserID        uint            `json:"user_id" gorm:"not null;index;comment:用户ID"`
+	UnitPrice     decimal.Decimal `json:"unit_price" gorm:"type:decimal(12,4);not null;comment:新电价(元/度)"`
+	EffectiveDate time.Time       `json:"effective_date" gorm:"type:date;not null;comment:生效日期(无时区)"`
+	AppliedAt     *time.Time      `json:"applied_at" gorm:"comment:已写入用户电价的时间"`
+	CreatedBy     uint            `json:"created_by" gorm:"comment:创建人ID"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt  `json:"deleted_at" gorm:"index" swaggerignore:"true"`
+}
+
+// TableName 指定表名
+func (UnitPriceSchedule) TableName() string {
+	return "unit_price_schedules"
+}
+
+// IsApplied 是否已生效并写入用户电价
+func (s *UnitPriceSchedule) IsApplied() bool {
+	return s.AppliedAt != nil
+}
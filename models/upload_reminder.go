@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// UploadReminder 充电记录补传提醒表（只追加）
+type UploadReminder struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	ReservationID uint      `json:"reservation_id" gorm:"not null;index;comment:预约ID"`
+	UserID        uint      `json:"user_id" gorm:"not null;comment:被提醒用户ID"`
+	SentBy        uint      `json:"sent_by" gorm:"not null;comment:发送提醒的管理员ID"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// TableName 指定表名
+func (UploadReminder) TableName() string {
+	return "upload_reminders"
+}
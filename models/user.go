@@ -3,25 +3,51 @@ package models
 import (
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
 // User 用户模型
 type User struct {
-	ID         uint           `json:"id" gorm:"primaryKey"`
-	OpenID     string         `json:"openid" gorm:"column:openid;uniqueIndex;not null"`
-	Name       string         `json:"name" gorm:"not null"`
-	Phone      string         `json:"phone"`
-	Avatar     string         `json:"avatar"`
-	Role       string         `json:"role" gorm:"default:'user'"`
-	Status     string         `json:"status" gorm:"default:'active'"`
-	UnitPrice  float64        `json:"unit_price" gorm:"default:0.7"`
-	CanReserve bool           `json:"can_reserve" gorm:"column:can_reserve;default:false"`
-	CreatedAt  time.Time      `json:"created_at"`
-	UpdatedAt  time.Time      `json:"updated_at"`
-	DeletedAt  gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggerignore:"true"`
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	OpenID     string          `json:"openid" gorm:"column:openid;uniqueIndex;not null"`
+	Name       string          `json:"name" gorm:"not null"`
+	Phone      string          `json:"phone"`
+	Avatar     string          `json:"avatar"`
+	Role       string          `json:"role" gorm:"default:'user'"`
+	Status     string          `json:"status" gorm:"default:'active'"`
+	UnitPrice  decimal.Decimal `json:"unit_price" gorm:"type:decimal(12,4);default:0.7"`
+	CanReserve bool            `json:"can_reserve" gorm:"column:can_reserve;default:false"`
+
+	SuspendReason  string     `json:"suspend_reason" gorm:"size:255;comment:暂停原因"`
+	SuspendedUntil *time.Time `json:"suspended_until" gorm:"type:date;comment:暂停截止日期(含当天)"`
+	SuspendedAt    *time.Time `json:"suspended_at" gorm:"comment:暂停时间"`
+	SuspendedBy    *uint      `json:"suspended_by" gorm:"comment:暂停操作人ID"`
+	RemovedAt      *time.Time `json:"removed_at" gorm:"comment:移除时间"`
+	RemovedBy      *uint      `json:"removed_by" gorm:"comment:移除操作人ID"`
+	InviteCodeID   *uint      `json:"invite_code_id" gorm:"comment:注册时兑换的邀请码ID"`
+	ApprovedAt     *time.Time `json:"approved_at" gorm:"comment:审核通过时间"`
+	ApprovedBy     *uint      `json:"approved_by" gorm:"comment:审核人ID"`
+
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"deleted_at" gorm:"index" swaggerignore:"true"`
 }
 
+// 用户角色，其余管理角色见 permission.go
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
+// 用户状态
+const (
+	UserStatusPendingApproval = "pending_approval"
+	UserStatusActive          = "active"
+	UserStatusSuspended       = "suspended"
+	UserStatusRemoved         = "removed"
+)
+
 // TableName 指定表名
 func (User) TableName() string {
 	return "users"
@@ -29,7 +55,80 @@ func (User) TableName() string {
 
 // IsActive 检查用户是否激活
 func (u *User) IsActive() bool {
-	return u.Status == "active"
+	return u.Status == UserStatusActive
+}
+
+// IsAdmin 是否为管理员
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin
+}
+
+// HasPermission 用户角色是否拥有指定权限
+func (u *User) HasPermission(permission string) bool {
+	return RoleHasPermission(u.Role, permission)
+}
+
+// Permissions 用户角色拥有的全部权限
+func (u *User) Permissions() []string {
+	permissions := RolePermissions[u.Role]
+	if permissions == nil {
+		return []string{}
+	}
+	return permissions
+}
+
+// SuspensionExpired 暂停是否已过截止日期，截止日期当天仍处于暂停状态
+func (u *User) SuspensionExpired(now time.Time) bool {
+	if u.Status != UserStatusSuspended || u.SuspendedUntil == nil {
+		return false
+	}
+	return u.SuspendedUntil.Format("2006-01-02") < now.Format("2006-01-02")
+}
+
+// LiftExpiredSuspension 暂停已到期的用户恢复为正常状态，返回是否已恢复
+func (u *User) LiftExpiredSuspension(db *gorm.DB) (bool, error) {
+	if !u.SuspensionExpired(time.Now()) {
+		return false, nil
+	}
+	result := db.Model(&User{}).
+		Where("id = ? AND status = ? AND suspended_until < ?", u.ID, UserStatusSuspended, time.Now().Format("2006-01-02")).
+		Updates(map[string]interface{}{
+			"status":          UserStatusActive,
+			"suspend_reason":  "",
+			"suspended_until": nil,
+			"suspended_at":    nil,
+			"suspended_by":    nil,
+		})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	u.Status = UserStatusActive
+	u.SuspendReason = ""
+	u.SuspendedUntil = nil
+	u.SuspendedAt = nil
+	u.SuspendedBy = nil
+	return true, nil
+}
+
+// InactiveMessage 非正常状态用户被拒绝访问时的提示
+func (u *User) InactiveMessage() string {
+	switch u.Status {
+	case UserStatusSuspended:
+		message := "账号已暂停使用"
+		if u.SuspendReason != "" {
+			message += "，原因：" + u.SuspendReason
+		}
+		if u.SuspendedUntil != nil {
+			message += "，" + u.SuspendedUntil.Format("2006-01-02") + " 后恢复"
+		}
+		return message
+	case UserStatusRemoved:
+		return "账号已被移除，请联系管理员"
+	case UserStatusPendingApproval:
+		return "账号正在等待管理员审核，也可以使用邀请码直接加入"
+	default:
+		return "用户已被禁用"
+	}
 }
 
 // FormatUserInfo 格式化用户信息，保持API一致性
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// UserDayStat 用户按日、按车牌号用电统计，随充电记录增量维护，可由 cmd/rebuild-stats 重建
+type UserDayStat struct {
+	ID             uint            `json:"id" gorm:"primaryKey"`
+	UserID         uint            `json:"user_id" gorm:"not null;uniqueIndex:uniq_user_day_stats_user_date_plate;comment:用户ID"`
+	Date           time.Time       `json:"date" gorm:"type:date;not null;uniqueIndex:uniq_user_day_stats_user_date_plate;comment:日期(无时区)"`
+	LicensePlateID uint            `json:"license_plate_id" gorm:"not null;default:0;uniqueIndex:uniq_user_day_stats_user_date_plate;comment:车牌号ID，0表示未关联车牌号"`
+	DayKwh         decimal.Decimal `json:"day_kwh" gorm:"type:decimal(12,2);not null;default:0;comment:白班用电量(kWh)"`
+	NightKwh       decimal.Decimal `json:"night_kwh" gorm:"type:decimal(12,2);not null;default:0;comment:夜班用电量(kWh)"`
+	TotalKwh       decimal.Decimal `json:"total_kwh" gorm:"type:decimal(12,2);not null;default:0;comment:总用电量(kWh)"`
+	TotalAmount    int64           `json:"total_amount" gorm:"not null;default:0;comment:费用合计(分，已扣除优惠)"`
+	RecordCount    int64           `json:"record_count" gorm:"not null;default:0;comment:充电记录数"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserDayStat) TableName() string {
+	return "user_day_stats"
+}
+
+// UserMonthStat 用户按月用电统计
+type UserMonthStat struct {
+	ID          uint            `json:"id" gorm:"primaryKey"`
+	UserID      uint            `json:"user_id" gorm:"not null;uniqueIndex:uniq_user_month_stats_user_month;comment:用户ID"`
+	Month       string          `json:"month" gorm:"size:7;not null;uniqueIndex:uniq_user_month_stats_user_month;comment:统计月份(YYYY-MM)"`
+	DayKwh      decimal.Decimal `json:"day_kwh" gorm:"type:decimal(12,2);not null;default:0;comment:白班用电量(kWh)"`
+	NightKwh    decimal.Decimal `json:"night_kwh" gorm:"type:decimal(12,2);not null;default:0;comment:夜班用电量(kWh)"`
+	TotalKwh    decimal.Decimal `json:"total_kwh" gorm:"type:decimal(12,2);not null;default:0;comment:总用电量(kWh)"`
+	TotalAmount int64           `json:"total_amount" gorm:"not null;default:0;comment:费用合计(分，已扣除优惠)"`
+	RecordCount int64           `json:"record_count" gorm:"not null;default:0;comment:充电记录数"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (UserMonthStat) TableName() string {
+	return "user_month_stats"
+}
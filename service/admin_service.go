@@ -1,10 +1,15 @@
 package service
 
 import (
+	"errors"
 	"shared-charge/models"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // GetAllUsers 获取所有用户列表
@@ -16,143 +21,276 @@ func GetAllUsers(c *gin.Context) ([]map[string]interface{}, error) {
 	var result []map[string]interface{}
 	for _, user := range users {
 		result = append(result, map[string]interface{}{
-			"id":          user.ID,
-			"user_name":   user.Name,
-			"avatar":      user.Avatar,
-			"can_reserve": user.CanReserve,
-			"unit_price":  user.UnitPrice,
-			"role":        user.Role,
+			"id":              user.ID,
+			"user_name":       user.Name,
+			"avatar":          user.Avatar,
+			"can_reserve":     user.CanReserve,
+			"unit_price":      user.UnitPrice,
+			"role":            user.Role,
+			"status":          user.Status,
+			"suspend_reason":  user.SuspendReason,
+			"suspended_until": formatOptionalDate(user.SuspendedUntil),
+			"suspended_at":    user.SuspendedAt,
+			"removed_at":      user.RemovedAt,
 		})
 	}
 	return result, nil
 }
 
+// updateUserField 在事务内锁定用户、修改单个字段并写入审计日志
+func updateUserField(c *gin.Context, userID uint, action, column string, value interface{}, current func(models.User) interface{}) error {
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("用户不存在")
+			}
+			return err
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update(column, value).Error; err != nil {
+			return err
+		}
+		return writeAudit(c, tx, action, AuditTargetUser, userID,
+			map[string]interface{}{column: current(user)}, map[string]interface{}{column: value})
+	})
+}
+
 // UpdateUserCanReserve 更新用户可预约状态
 func UpdateUserCanReserve(c *gin.Context, userID uint, canReserve bool) error {
-	return models.DB.Model(&models.User{}).Where("id = ?", userID).Update("can_reserve", canReserve).Error
+	err := updateUserField(c, userID, AuditUserCanReserve, "can_reserve", canReserve, func(user models.User) interface{} {
+		return user.CanReserve
+	})
+	if err != nil {
+		return err
+	}
+	// 月度对账只列出可预约用户
+	invalidateAllMonthlyReports()
+	return nil
 }
 
 // UpdateUserUnitPrice 更新用户电价
-func UpdateUserUnitPrice(c *gin.Context, userID uint, unitPrice float64) error {
-	return models.DB.Model(&models.User{}).Where("id = ?", userID).Update("unit_price", unitPrice).Error
+func UpdateUserUnitPrice(c *gin.Context, userID uint, unitPrice decimal.Decimal) error {
+	price := models.RoundDecimal(unitPrice, models.PricePlaces)
+	return updateUserField(c, userID, AuditUserUnitPrice, "unit_price", price, func(user models.User) interface{} {
+		return user.UnitPrice
+	})
 }
 
-// GetMonthlyReport 获取月度对账数据
-func GetMonthlyReport(c *gin.Context, month string) (map[string]interface{}, error) {
-	startDate, _ := time.Parse("2006-01", month)
-	endDate := startDate.AddDate(0, 1, 0).Add(-time.Second)
+// MonthlyReportPlate 月度对账中单个车牌号的汇总，金额单位为分
+type MonthlyReportPlate struct {
+	LicensePlateID uint
+	PlateNumber    string
+	TotalAmount    int64
+	DiscountAmount int64
+	RecordCount    int64
+	HasUploaded    bool
+}
+
+// MonthlyReportUser 月度对账中单个用户的汇总，金额单位为分
+type MonthlyReportUser struct {
+	ID               uint
+	Name             string
+	Avatar           string
+	Plates           []MonthlyReportPlate
+	TotalAmount      int64
+	DiscountAmount   int64
+	HasUploaded      bool
+	OutstandingCount int64
+	UnapprovedCount  int64
+	PaymentStatus    string
+	PaidAt           *time.Time
+}
+
+// MonthlyReport 月度对账数据
+type MonthlyReport struct {
+	Month          string
+	StartDate      string
+	EndDate        string
+	Users          []MonthlyReportUser
+	TotalAmount    int64
+	DiscountAmount int64
+}
+
+// unboundPlateNumber 没有绑定车牌号时显示的名称
+const unboundPlateNumber = "未绑定车牌号"
+
+// buildMonthlyReport 汇总月度对账数据，JSON 接口与导出共用
+// 用户、车牌号金额、未审核记录数和账单各用一次查询，不随成员数增加查询次数
+func buildMonthlyReport(month string) (*MonthlyReport, error) {
+	startDate, endDate, err := getMonthDateRange(month)
+	if err != nil {
+		return nil, errors.New("月份格式错误")
+	}
+	report := &MonthlyReport{Month: month, StartDate: startDate, EndDate: endDate}
 
-	// 只查 can_reserve = true 的用户
+	// 只查 can_reserve = true 的用户，已移除的用户只出现在移除当月及之前
 	var users []models.User
-	if err := models.DB.Where("can_reserve = ?", true).Find(&users).Error; err != nil {
+	if err := models.DB.Where("can_reserve = ? AND (removed_at IS NULL OR removed_at >= ?)", true, startDate).Order("id ASC").Find(&users).Error; err != nil {
 		return nil, err
 	}
 
-	// 统计所有用户本月预约总数
-	var reservationStats []struct {
-		UserID uint
-		Total  int64
+	// 本月已结束但未上传充电记录的预约，按用户和车牌号汇总
+	outstanding, err := findOutstandingUploads(0, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	outstandingMap := make(map[uint]int64)
+	outstandingPlateMap := make(map[uint]map[uint]int64)
+	outstandingPlates := make(map[uint][]models.LicensePlate)
+	for _, item := range outstanding {
+		reservation := item.Reservation
+		outstandingMap[reservation.UserID]++
+		if reservation.LicensePlateID == nil {
+			continue
+		}
+		if outstandingPlateMap[reservation.UserID] == nil {
+			outstandingPlateMap[reservation.UserID] = make(map[uint]int64)
+		}
+		plateID := *reservation.LicensePlateID
+		if outstandingPlateMap[reservation.UserID][plateID] == 0 && reservation.LicensePlate != nil {
+			outstandingPlates[reservation.UserID] = append(outstandingPlates[reservation.UserID], *reservation.LicensePlate)
+		}
+		outstandingPlateMap[reservation.UserID][plateID]++
 	}
-	models.DB.Model(&models.Reservation{}).
-		Select("user_id, COUNT(*) as total").
-		Where("date >= ? AND date <= ? AND status != ?", startDate, endDate, "cancelled").
-		Group("user_id").
-		Scan(&reservationStats)
 
-	// 统计所有用户本月已上传预约数
-	var uploadedStats []struct {
-		UserID   uint
-		Uploaded int64
+	// 统计所有用户本月未审核通过的记录数（不计入结算）
+	var unapprovedStats []struct {
+		UserID     uint
+		Unapproved int64
+	}
+	err = models.DB.Model(&models.Record{}).Scopes(models.ActiveRecords).
+		Select("user_id, COUNT(*) as unapproved").
+		Where("date >= ? AND date <= ? AND review_status != ?", startDate, endDate, models.ReviewStatusApproved).
+		Group("user_id").
+		Scan(&unapprovedStats).Error
+	if err != nil {
+		return nil, err
+	}
+	unapprovedMap := make(map[uint]int64)
+	for _, stat := range unapprovedStats {
+		unapprovedMap[stat.UserID] = stat.Unapproved
 	}
-	models.DB.Table("reservations").
-		Select("reservations.user_id, COUNT(DISTINCT reservations.id) as uploaded").
-		Joins("JOIN records ON reservations.id = records.reservation_id").
-		Where("reservations.date >= ? AND reservations.date <= ? AND reservations.status != ?", startDate, endDate, "cancelled").
-		Group("reservations.user_id").
-		Scan(&uploadedStats)
 
-	// 构建 userId -> 预约数/上传数 map
-	reservationMap := make(map[uint]int64)
-	uploadedMap := make(map[uint]int64)
-	for _, stat := range reservationStats {
-		reservationMap[stat.UserID] = stat.Total
+	// 所有用户按车牌号统计审核通过的记录
+	var plateStats []struct {
+		UserID uint
+		MonthlyReportPlate
 	}
-	for _, stat := range uploadedStats {
-		uploadedMap[stat.UserID] = stat.Uploaded
+	err = models.DB.Model(&models.Record{}).Scopes(models.ActiveRecords).
+		Select("records.user_id, records.license_plate_id, license_plates.plate_number, COALESCE(SUM(records.amount), 0) as total_amount, COALESCE(SUM(records.discount_amount), 0) as discount_amount, COUNT(*) as record_count").
+		Joins("LEFT JOIN license_plates ON records.license_plate_id = license_plates.id").
+		Where("records.date >= ? AND records.date <= ? AND records.review_status = ?", startDate, endDate, models.ReviewStatusApproved).
+		Group("records.user_id, records.license_plate_id, license_plates.plate_number").
+		Order("records.user_id ASC, records.license_plate_id ASC").
+		Scan(&plateStats).Error
+	if err != nil {
+		return nil, err
+	}
+	plateStatMap := make(map[uint][]MonthlyReportPlate)
+	for _, stat := range plateStats {
+		plateStatMap[stat.UserID] = append(plateStatMap[stat.UserID], stat.MonthlyReportPlate)
+	}
+
+	bills, err := getMonthlyBillMap(month)
+	if err != nil {
+		return nil, err
 	}
 
-	var result []map[string]interface{}
 	for _, user := range users {
-		// 获取用户的车牌号列表
-		var licensePlates []models.LicensePlate
-		models.DB.Where("user_id = ?", user.ID).Find(&licensePlates)
-
-		// 按车牌号统计记录
-		var licensePlateStats []struct {
-			LicensePlateID uint
-			PlateNumber    string
-			TotalAmount    int64
-			RecordCount    int64
+		userData := MonthlyReportUser{
+			ID:     user.ID,
+			Name:   user.Name,
+			Avatar: user.Avatar,
+			// 本月所有已结束的预约都已上传记录
+			HasUploaded:      outstandingMap[user.ID] == 0,
+			OutstandingCount: outstandingMap[user.ID],
+			UnapprovedCount:  unapprovedMap[user.ID],
+			PaymentStatus:    models.BillStatusUnpaid,
 		}
-		models.DB.Model(&models.Record{}).
-			Select("records.license_plate_id, license_plates.plate_number, COALESCE(SUM(records.amount), 0) as total_amount, COUNT(*) as record_count").
-			Joins("LEFT JOIN license_plates ON records.license_plate_id = license_plates.id").
-			Where("records.user_id = ? AND records.date >= ? AND records.date <= ?", user.ID, startDate, endDate).
-			Group("records.license_plate_id, license_plates.plate_number").
-			Scan(&licensePlateStats)
-
-		// 计算总金额
-		type aggResult struct {
-			TotalAmount int64
+		if bill, ok := bills[user.ID]; ok {
+			userData.PaymentStatus = bill.Status
+			userData.PaidAt = bill.PaidAt
 		}
-		var agg aggResult
-		models.DB.Model(&models.Record{}).
-			Select("COALESCE(SUM(amount), 0) as total_amount").
-			Where("user_id = ? AND date >= ? AND date <= ?", user.ID, startDate, endDate).
-			Scan(&agg)
-
-		// 计算 has_uploaded
-		totalReservations := reservationMap[user.ID]
-		uploadedReservations := uploadedMap[user.ID]
-		hasUploaded := true
-		if totalReservations > 0 && uploadedReservations < totalReservations {
-			hasUploaded = false
+		reportedPlates := make(map[uint]bool)
+		// 用户总金额由各车牌号金额(分)累加，保证与明细完全一致
+		for _, stat := range plateStatMap[user.ID] {
+			reportedPlates[stat.LicensePlateID] = true
+			userData.TotalAmount += stat.TotalAmount
+			userData.DiscountAmount += stat.DiscountAmount
+			stat.HasUploaded = outstandingPlateMap[user.ID][stat.LicensePlateID] == 0
+			userData.Plates = append(userData.Plates, stat)
 		}
-
-		// 构建车牌号统计信息
-		var licensePlateData []map[string]interface{}
-		for _, stat := range licensePlateStats {
-			licensePlateData = append(licensePlateData, map[string]interface{}{
-				"plate_number":  stat.PlateNumber,
-				"total_amount":  float64(stat.TotalAmount) / 100.0,
-				"record_count":  stat.RecordCount,
-				"has_uploaded":  hasUploaded, // 暂时使用用户级别的上传状态
+		// 本月没有记录但有待上传预约的车牌号
+		plates := outstandingPlates[user.ID]
+		sort.Slice(plates, func(i, j int) bool { return plates[i].ID < plates[j].ID })
+		for _, plate := range plates {
+			if reportedPlates[plate.ID] {
+				continue
+			}
+			userData.Plates = append(userData.Plates, MonthlyReportPlate{
+				LicensePlateID: plate.ID,
+				PlateNumber:    plate.PlateNumber,
+				HasUploaded:    false,
 			})
 		}
 
 		// 如果没有车牌号记录，添加默认车牌号信息
-		if len(licensePlateData) == 0 {
-			licensePlateData = append(licensePlateData, map[string]interface{}{
-				"plate_number":  "未绑定车牌号",
-				"total_amount":  0.0,
-				"record_count":  0,
-				"has_uploaded":  hasUploaded,
+		if len(userData.Plates) == 0 {
+			userData.Plates = append(userData.Plates, MonthlyReportPlate{
+				PlateNumber: unboundPlateNumber,
+				HasUploaded: userData.HasUploaded,
 			})
 		}
 
-		userData := map[string]interface{}{
+		report.Users = append(report.Users, userData)
+		report.TotalAmount += userData.TotalAmount
+		report.DiscountAmount += userData.DiscountAmount
+	}
+	return report, nil
+}
+
+// GetMonthlyReport 获取月度对账数据（仅统计审核通过的记录）
+func GetMonthlyReport(c *gin.Context, month string) (map[string]interface{}, error) {
+	report, err := loadMonthlyReport(c, month)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []map[string]interface{}
+	for _, user := range report.Users {
+		var licensePlateData []map[string]interface{}
+		for _, plate := range user.Plates {
+			licensePlateData = append(licensePlateData, map[string]interface{}{
+				"plate_number":    plate.PlateNumber,
+				"total_amount":    models.FenToYuan(plate.TotalAmount),
+				"discount_amount": models.FenToYuan(plate.DiscountAmount),
+				"record_count":    plate.RecordCount,
+				"has_uploaded":    plate.HasUploaded,
+			})
+		}
+		result = append(result, map[string]interface{}{
 			"id":             user.ID,
 			"user_name":      user.Name,
 			"avatar":         user.Avatar,
 			"license_plates": licensePlateData,
-			"total_amount":   float64(agg.TotalAmount) / 100.0,
-			"has_uploaded":   hasUploaded,
-		}
-		result = append(result, userData)
+			"total_amount":   models.FenToYuan(user.TotalAmount),
+			// 已扣除的优惠金额
+			"discount_amount": models.FenToYuan(user.DiscountAmount),
+			"has_uploaded":    user.HasUploaded,
+			// 已结束但未上传充电记录的预约数
+			"outstanding_count": user.OutstandingCount,
+			// 未审核通过的记录不计入 total_amount
+			"unapproved_count": user.UnapprovedCount,
+			// 账单支付状态
+			"payment_status": user.PaymentStatus,
+			"paid_at":        user.PaidAt,
+		})
 	}
 
 	return map[string]interface{}{
-		"month": month,
-		"users": result,
+		"month":           report.Month,
+		"users":           result,
+		"total_amount":    models.FenToYuan(report.TotalAmount),
+		"discount_amount": models.FenToYuan(report.DiscountAmount),
 	}, nil
 }
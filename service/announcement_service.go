@@ -0,0 +1,294 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationAnnouncement 发布公告或充电规则的通知类型
+const NotificationAnnouncement = "announcement"
+
+// errRulesNotAccepted 未同意最新充电规则时不能预约
+var errRulesNotAccepted = errors.New("请先阅读并同意最新的充电规则")
+
+// currentRules 查询当前生效的充电规则（最新未撤回的版本），尚未发布时返回 nil
+func currentRules(db *gorm.DB) (*models.Announcement, error) {
+	var rules models.Announcement
+	err := db.Where("kind = ?", models.AnnouncementKindRules).Order("version DESC").First(&rules).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// findRuleAcceptance 查询用户对指定版本规则的同意记录，未同意时返回 nil
+func findRuleAcceptance(db *gorm.DB, userID uint, version int) (*models.RuleAcceptance, error) {
+	var acceptance models.RuleAcceptance
+	err := db.Where("user_id = ? AND version = ?", userID, version).First(&acceptance).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &acceptance, nil
+}
+
+// ensureRulesAccepted 检查用户已同意当前版本的充电规则，尚未发布规则时不限制
+func ensureRulesAccepted(c *gin.Context, userID uint) error {
+	rules, err := currentRules(models.DB)
+	if err != nil {
+		utils.ErrorCtx(c, "查询充电规则失败: %v", err)
+		return err
+	}
+	if rules == nil {
+		return nil
+	}
+	acceptance, err := findRuleAcceptance(models.DB, userID, rules.Version)
+	if err != nil {
+		utils.ErrorCtx(c, "查询充电规则同意记录失败: %v", err)
+		return err
+	}
+	if acceptance == nil {
+		utils.WarnCtx(c, "未同意最新充电规则: user_id=%d, version=%d", userID, rules.Version)
+		return errRulesNotAccepted
+	}
+	return nil
+}
+
+// formatAnnouncement 格式化公告
+func formatAnnouncement(announcement models.Announcement) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           announcement.ID,
+		"kind":         announcement.Kind,
+		"version":      announcement.Version,
+		"title":        announcement.Title,
+		"content":      announcement.Content,
+		"published_by": announcement.PublishedBy,
+		"published_at": announcement.CreatedAt,
+	}
+}
+
+// PublishAnnouncement 管理员发布公告或新版充电规则，版本号在同类型内递增，并通知正常状态的成员
+// 发布充电规则后，成员需同意新版本才能继续预约
+func PublishAnnouncement(c *gin.Context, adminID uint, kind, title, content string) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "发布公告: admin_id=%d, kind=%s, title=%s", adminID, kind, title)
+	if kind != models.AnnouncementKindNotice && kind != models.AnnouncementKindRules {
+		return nil, errors.New("公告类型无效，仅支持notice和rules")
+	}
+	if title == "" || content == "" {
+		return nil, errors.New("标题和内容不能为空")
+	}
+	announcement := models.Announcement{
+		Kind:        kind,
+		Title:       title,
+		Content:     content,
+		PublishedBy: adminID,
+	}
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		// 已撤回的版本号也不复用
+		var latest int
+		err := tx.Unscoped().Model(&models.Announcement{}).
+			Select("COALESCE(MAX(version), 0)").
+			Where("kind = ?", kind).
+			Scan(&latest).Error
+		if err != nil {
+			return err
+		}
+		announcement.Version = latest + 1
+		if err := tx.Create(&announcement).Error; err != nil {
+			return err
+		}
+		if err := writeAudit(c, tx, AuditAnnouncementPublish, AuditTargetAnnouncement, announcement.ID, nil, formatAnnouncement(announcement)); err != nil {
+			return err
+		}
+
+		notifyTitle := "群组公告：" + announcement.Title
+		notifyContent := "管理员发布了新的群组公告，请前往公告查看"
+		if announcement.IsRules() {
+			notifyTitle = "充电规则已更新"
+			notifyContent = fmt.Sprintf("充电规则已更新为第 %d 版，请阅读并同意后再预约", announcement.Version)
+		}
+		var userIDs []uint
+		if err := tx.Model(&models.User{}).Where("status = ?", models.UserStatusActive).Pluck("id", &userIDs).Error; err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			if err := CreateNotification(tx, userID, NotificationAnnouncement, notifyTitle, notifyContent, announcement.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "发布公告失败: %v", err)
+		return nil, err
+	}
+	utils.InfoCtx(c, "公告已发布: id=%d, kind=%s, version=%d", announcement.ID, announcement.Kind, announcement.Version)
+	return formatAnnouncement(announcement), nil
+}
+
+// WithdrawAnnouncement 管理员撤回公告；撤回当前版本的充电规则后，上一版本重新生效
+func WithdrawAnnouncement(c *gin.Context, id uint) error {
+	utils.InfoCtx(c, "撤回公告: id=%d", id)
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		var announcement models.Announcement
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&announcement, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("公告不存在")
+			}
+			return err
+		}
+		if err := tx.Delete(&announcement).Error; err != nil {
+			return err
+		}
+		return writeAudit(c, tx, AuditAnnouncementWithdraw, AuditTargetAnnouncement, announcement.ID, formatAnnouncement(announcement), nil)
+	})
+}
+
+// GetAnnouncements 获取公告列表，最新的在前；kind 为空时返回全部类型
+func GetAnnouncements(c *gin.Context, kind string) ([]map[string]interface{}, error) {
+	query := models.DB.Model(&models.Announcement{})
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	var announcements []models.Announcement
+	if err := query.Order("created_at DESC, id DESC").Limit(defaultLimit).Find(&announcements).Error; err != nil {
+		utils.ErrorCtx(c, "查询公告失败: %v", err)
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(announcements))
+	for _, announcement := range announcements {
+		result = append(result, formatAnnouncement(announcement))
+	}
+	return result, nil
+}
+
+// GetRulesStatus 获取当前充电规则及用户是否已同意，尚未发布规则时 rules 为空且视为已同意
+func GetRulesStatus(c *gin.Context, userID uint) (map[string]interface{}, error) {
+	rules, err := currentRules(models.DB)
+	if err != nil {
+		utils.ErrorCtx(c, "查询充电规则失败: %v", err)
+		return nil, err
+	}
+	if rules == nil {
+		return map[string]interface{}{"rules": nil, "accepted": true, "accepted_at": nil}, nil
+	}
+	acceptance, err := findRuleAcceptance(models.DB, userID, rules.Version)
+	if err != nil {
+		utils.ErrorCtx(c, "查询充电规则同意记录失败: %v", err)
+		return nil, err
+	}
+	var acceptedAt interface{}
+	if acceptance != nil {
+		acceptedAt = acceptance.AcceptedAt
+	}
+	return map[string]interface{}{
+		"rules":       formatAnnouncement(*rules),
+		"accepted":    acceptance != nil,
+		"accepted_at": acceptedAt,
+	}, nil
+}
+
+// AcceptRules 成员同意当前版本的充电规则，version 必须为当前版本，重复同意不报错
+func AcceptRules(c *gin.Context, userID uint, version int) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "同意充电规则: user_id=%d, version=%d", userID, version)
+	rules, err := currentRules(models.DB)
+	if err != nil {
+		utils.ErrorCtx(c, "查询充电规则失败: %v", err)
+		return nil, err
+	}
+	if rules == nil {
+		return nil, errors.New("暂无需要同意的充电规则")
+	}
+	if version != rules.Version {
+		return nil, errors.New("充电规则已更新，请阅读最新版本后再同意")
+	}
+	acceptance := models.RuleAcceptance{
+		UserID:         userID,
+		AnnouncementID: rules.ID,
+		Version:        rules.Version,
+		AcceptedAt:     time.Now(),
+	}
+	if err := models.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&acceptance).Error; err != nil {
+		utils.ErrorCtx(c, "保存充电规则同意记录失败: %v", err)
+		return nil, err
+	}
+	return GetRulesStatus(c, userID)
+}
+
+// GetRuleAcceptances 管理员查看成员对指定版本充电规则的同意情况，version 为 0 时查看当前版本
+// 只列出正常和暂停状态的成员，未同意的在前
+func GetRuleAcceptances(c *gin.Context, version int) (map[string]interface{}, error) {
+	if version == 0 {
+		rules, err := currentRules(models.DB)
+		if err != nil {
+			utils.ErrorCtx(c, "查询充电规则失败: %v", err)
+			return nil, err
+		}
+		if rules == nil {
+			return nil, errors.New("尚未发布充电规则")
+		}
+		version = rules.Version
+	}
+	var users []models.User
+	err := models.DB.Where("status IN ?", []string{models.UserStatusActive, models.UserStatusSuspended}).
+		Order("id ASC").
+		Find(&users).Error
+	if err != nil {
+		utils.ErrorCtx(c, "查询成员失败: %v", err)
+		return nil, err
+	}
+	var acceptances []models.RuleAcceptance
+	if err := models.DB.Order("version ASC").Find(&acceptances).Error; err != nil {
+		utils.ErrorCtx(c, "查询充电规则同意记录失败: %v", err)
+		return nil, err
+	}
+	acceptedAt := make(map[uint]time.Time)
+	latestVersion := make(map[uint]int)
+	for _, acceptance := range acceptances {
+		latestVersion[acceptance.UserID] = acceptance.Version
+		if acceptance.Version == version {
+			acceptedAt[acceptance.UserID] = acceptance.AcceptedAt
+		}
+	}
+
+	accepted := make([]map[string]interface{}, 0)
+	pending := make([]map[string]interface{}, 0)
+	for _, user := range users {
+		item := map[string]interface{}{
+			"id":                      user.ID,
+			"user_name":               user.Name,
+			"status":                  user.Status,
+			"accepted":                false,
+			"accepted_at":             nil,
+			"latest_accepted_version": nil,
+		}
+		if v, ok := latestVersion[user.ID]; ok {
+			item["latest_accepted_version"] = v
+		}
+		if at, ok := acceptedAt[user.ID]; ok {
+			item["accepted"] = true
+			item["accepted_at"] = at
+			accepted = append(accepted, item)
+			continue
+		}
+		pending = append(pending, item)
+	}
+	return map[string]interface{}{
+		"version":        version,
+		"total":          len(users),
+		"accepted_count": len(accepted),
+		"members":        append(pending, accepted...),
+	}, nil
+}
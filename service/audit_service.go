@@ -0,0 +1,258 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 审计操作
+const (
+	AuditUserCanReserve         = "user.can_reserve"
+	AuditUserUnitPrice          = "user.unit_price"
+	AuditUserUnitPriceScheduled = "user.unit_price.scheduled"
+	AuditUserSuspend            = "user.suspend"
+	AuditUserReactivate         = "user.reactivate"
+	AuditUserRole               = "user.role"
+	AuditUserRemove             = "user.remove"
+	AuditUserApprove            = "user.approve"
+	AuditUserReject             = "user.reject"
+	AuditUserRedeemInvite       = "user.redeem_invite"
+	AuditUserRevokeSessions     = "user.revoke_sessions"
+	AuditRecordCreate           = "record.create"
+	AuditRecordUpdate           = "record.update"
+	AuditRecordVoid             = "record.void"
+	AuditRecordApprove          = "record.approve"
+	AuditRecordReject           = "record.reject"
+	AuditCorrectionApprove      = "correction.approve"
+	AuditCorrectionReject       = "correction.reject"
+	AuditReservationRemind      = "reservation.remind"
+	AuditDiscountCreate         = "discount_rule.create"
+	AuditDiscountUpdate         = "discount_rule.update"
+	AuditDiscountDelete         = "discount_rule.delete"
+	AuditPriceScheduleCreate    = "price_schedule.create"
+	AuditPriceScheduleCancel    = "price_schedule.cancel"
+	AuditBillPayment            = "bill.payment"
+	AuditInviteCodeCreate       = "invite_code.create"
+	AuditInviteCodeDisable      = "invite_code.disable"
+	AuditAnnouncementPublish    = "announcement.publish"
+	AuditAnnouncementWithdraw   = "announcement.withdraw"
+	AuditAdminRequest           = "admin.request"
+)
+
+// 审计对象类型
+const (
+	AuditTargetUser          = "user"
+	AuditTargetRecord        = "record"
+	AuditTargetCorrection    = "correction"
+	AuditTargetReservation   = "reservation"
+	AuditTargetDiscountRule  = "discount_rule"
+	AuditTargetPriceSchedule = "price_schedule"
+	AuditTargetBill          = "bill"
+	AuditTargetInviteCode    = "invite_code"
+	AuditTargetAnnouncement  = "announcement"
+	AuditTargetRequest       = "request"
+)
+
+// auditWrittenKey 本次请求已写入并提交审计日志的标记，管理端兜底审计据此跳过
+const auditWrittenKey = "audit_written"
+
+// auditPendingKey 本次请求在事务内写入、尚未确认提交的审计日志ID
+// 事务可能回滚（如批量操作的 dry_run），兜底审计前需确认这些日志确实已提交
+const auditPendingKey = "audit_pending_ids"
+
+// auditLogDefaultPageSize 审计日志默认每页条数
+const auditLogDefaultPageSize = 50
+
+// auditLogMaxPageSize 审计日志每页最多条数
+const auditLogMaxPageSize = 200
+
+// writeAudit 写入一条审计日志，应与被审计的修改在同一事务内调用
+// 操作人、trace_id 和 IP 取自请求上下文，c 为空时记为系统操作
+func writeAudit(c *gin.Context, tx *gorm.DB, action, targetType string, targetID uint, before, after interface{}) error {
+	if tx == nil {
+		tx = models.DB
+	}
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return err
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return err
+	}
+	entry := models.AuditLog{
+		ActorRole:    models.AuditActorSystem,
+		Action:       action,
+		TargetType:   targetType,
+		TargetID:     targetID,
+		BeforeValues: string(beforeJSON),
+		AfterValues:  string(afterJSON),
+	}
+	if c != nil {
+		if value, exists := c.Get("user"); exists {
+			if user, ok := value.(models.User); ok {
+				actorID := user.ID
+				entry.ActorID = &actorID
+				entry.ActorRole = user.Role
+			}
+		}
+		entry.TraceID = utils.GetTraceID(c)
+		if c.Request != nil {
+			entry.IP = c.ClientIP()
+		}
+	}
+	if err := tx.Create(&entry).Error; err != nil {
+		return err
+	}
+	if c != nil {
+		if tx == models.DB {
+			c.Set(auditWrittenKey, true)
+		} else {
+			pending, _ := c.Get(auditPendingKey)
+			ids, _ := pending.([]uint)
+			c.Set(auditPendingKey, append(ids, entry.ID))
+		}
+	}
+	return nil
+}
+
+// auditWritten 本次请求是否已有提交的审计日志，事务内写入的日志在请求结束后按ID确认是否已提交
+func auditWritten(c *gin.Context) bool {
+	if c.GetBool(auditWrittenKey) {
+		return true
+	}
+	pending, _ := c.Get(auditPendingKey)
+	ids, _ := pending.([]uint)
+	if len(ids) == 0 {
+		return false
+	}
+	var count int64
+	if err := models.DB.Model(&models.AuditLog{}).Where("id IN ?", ids).Count(&count).Error; err != nil {
+		utils.ErrorCtx(c, "确认审计日志提交状态失败: %v", err)
+		return false
+	}
+	return count > 0
+}
+
+// RecordAdminRequestAudit 管理端接口的兜底审计：修改类请求和数据导出如果没有提交更具体的审计日志，记录请求本身
+// 因权限不足被拒绝(403)的请求没有执行任何操作，不记录
+func RecordAdminRequestAudit(c *gin.Context) {
+	if c.Writer.Status() == http.StatusForbidden {
+		return
+	}
+	path := c.FullPath()
+	if c.Request.Method == "GET" && !strings.Contains(path, "/export/") && !strings.HasSuffix(path, "/statements") {
+		return
+	}
+	if auditWritten(c) {
+		return
+	}
+	after := map[string]interface{}{
+		"method": c.Request.Method,
+		"path":   path,
+		"query":  c.Request.URL.RawQuery,
+		"status": c.Writer.Status(),
+	}
+	if err := writeAudit(c, nil, AuditAdminRequest, AuditTargetRequest, 0, nil, after); err != nil {
+		utils.ErrorCtx(c, "写入审计日志失败: %v", err)
+	}
+}
+
+// AuditLogFilter 审计日志查询条件，Action 以 * 结尾时按前缀匹配
+type AuditLogFilter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   uint
+	From       string
+	To         string
+	Page       int
+	PageSize   int
+}
+
+// formatAuditLog 格式化审计日志
+func formatAuditLog(entry models.AuditLog) map[string]interface{} {
+	return map[string]interface{}{
+		"id":            entry.ID,
+		"actor_id":      entry.ActorID,
+		"actor_role":    entry.ActorRole,
+		"action":        entry.Action,
+		"target_type":   entry.TargetType,
+		"target_id":     entry.TargetID,
+		"before_values": json.RawMessage(entry.BeforeValues),
+		"after_values":  json.RawMessage(entry.AfterValues),
+		"trace_id":      entry.TraceID,
+		"ip":            entry.IP,
+		"created_at":    entry.CreatedAt,
+	}
+}
+
+// GetAuditLogs 管理员按条件分页查看审计日志，最新的在前
+func GetAuditLogs(c *gin.Context, filter AuditLogFilter) ([]map[string]interface{}, int64, error) {
+	query := models.DB.Model(&models.AuditLog{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		if strings.HasSuffix(filter.Action, "*") {
+			query = query.Where("action LIKE ?", strings.TrimSuffix(filter.Action, "*")+"%")
+		} else {
+			query = query.Where("action = ?", filter.Action)
+		}
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.From != "" {
+		from, err := time.ParseInLocation("2006-01-02", filter.From, time.Local)
+		if err != nil {
+			return nil, 0, errors.New("日期格式错误，应为YYYY-MM-DD")
+		}
+		query = query.Where("created_at >= ?", from)
+	}
+	if filter.To != "" {
+		to, err := time.ParseInLocation("2006-01-02", filter.To, time.Local)
+		if err != nil {
+			return nil, 0, errors.New("日期格式错误，应为YYYY-MM-DD")
+		}
+		query = query.Where("created_at < ?", to.AddDate(0, 0, 1))
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		utils.ErrorCtx(c, "统计审计日志失败: %v", err)
+		return nil, 0, err
+	}
+	page, pageSize := filter.Page, filter.PageSize
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = auditLogDefaultPageSize
+	}
+	if pageSize > auditLogMaxPageSize {
+		pageSize = auditLogMaxPageSize
+	}
+	var entries []models.AuditLog
+	err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&entries).Error
+	if err != nil {
+		utils.ErrorCtx(c, "查询审计日志失败: %v", err)
+		return nil, 0, err
+	}
+	result := make([]map[string]interface{}, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, formatAuditLog(entry))
+	}
+	return result, total, nil
+}
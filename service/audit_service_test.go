@@ -0,0 +1,83 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"shared-charge/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+func newAdminRequestContext(method string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(method, "/api/admin/users/bulk/status", nil)
+	c.Set("user", models.User{ID: 1, Role: models.UserRoleAdmin})
+	return c
+}
+
+func TestRecordAdminRequestAuditAfterRollback(t *testing.T) {
+	mock := setupMockDB(t)
+	c := newAdminRequestContext(http.MethodPost)
+
+	// 事务内写入审计日志后回滚（如 dry_run）
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectRollback()
+	errDryRun := errors.New("dry run")
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := writeAudit(c, tx, AuditUserSuspend, AuditTargetUser, 2, nil, nil); err != nil {
+			return err
+		}
+		return errDryRun
+	})
+	if !errors.Is(err, errDryRun) {
+		t.Fatalf("err = %v, want %v", err, errDryRun)
+	}
+
+	// 回滚的日志不存在，仍需写入兜底审计
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "audit_logs" WHERE id IN \(\$1\)`).
+		WithArgs(uint(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).
+		WithArgs(sqlmock.AnyArg(), models.UserRoleAdmin, AuditAdminRequest, AuditTargetRequest, 0,
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(8))
+	mock.ExpectCommit()
+	RecordAdminRequestAudit(c)
+}
+
+func TestRecordAdminRequestAuditAfterCommit(t *testing.T) {
+	mock := setupMockDB(t)
+	c := newAdminRequestContext(http.MethodPost)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		return writeAudit(c, tx, AuditUserSuspend, AuditTargetUser, 2, nil, nil)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 已提交的具体审计日志存在，不再写入兜底审计
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "audit_logs" WHERE id IN \(\$1\)`).
+		WithArgs(uint(7)).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	RecordAdminRequestAudit(c)
+}
+
+func TestRecordAdminRequestAuditSkipsForbidden(t *testing.T) {
+	setupMockDB(t)
+	c := newAdminRequestContext(http.MethodPost)
+	c.Writer.WriteHeader(http.StatusForbidden)
+
+	// 权限不足的请求不执行任何 SQL
+	RecordAdminRequestAudit(c)
+}
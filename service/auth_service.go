@@ -3,6 +3,10 @@ package service
 import (
 	"shared-charge/config"
 	"shared-charge/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // 通过openid查找用户
@@ -14,7 +18,11 @@ func GetUserByOpenID(openid string) (models.User, error) {
 
 // 创建新用户
 func CreateUser(user *models.User) error {
-	return models.DB.Create(user).Error
+	if err := models.DB.Create(user).Error; err != nil {
+		return err
+	}
+	invalidateAllMonthlyReports()
+	return nil
 }
 
 // 新增：用于封装新建用户输入参数
@@ -24,10 +32,12 @@ type UserCreateInput struct {
 	Phone  string
 	Role   string
 	Status string
+	// InviteCode 不为空时先兑换邀请码，兑换成功后按邀请码设置状态、电价和可预约
+	InviteCode string
 }
 
 // 新增：根据 UserCreateInput 创建用户
-func CreateUserWithInput(input UserCreateInput) (models.User, error) {
+func CreateUserWithInput(c *gin.Context, input UserCreateInput) (models.User, error) {
 	cfg := config.GetConfig()
 	user := models.User{
 		OpenID:    input.OpenID,
@@ -37,6 +47,24 @@ func CreateUserWithInput(input UserCreateInput) (models.User, error) {
 		Status:    input.Status,
 		UnitPrice: cfg.App.DefaultUnitPrice, // 设置默认电价
 	}
-	err := models.DB.Create(&user).Error
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if input.InviteCode != "" {
+			code, err := redeemInviteCode(tx, input.InviteCode)
+			if err != nil {
+				return err
+			}
+			applyInviteCode(&user, code, time.Now())
+		}
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if user.InviteCodeID == nil {
+			return nil
+		}
+		return writeAudit(c, tx, AuditUserRedeemInvite, AuditTargetUser, user.ID, nil, inviteRedeemAudit(user))
+	})
+	if err == nil {
+		invalidateAllMonthlyReports()
+	}
 	return user, err
 }
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"errors"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// monthlyBillAmount 统计用户当月审核通过且未作废的记录应付金额(分)，与月度对账口径一致
+func monthlyBillAmount(userID uint, startDate, endDate string) (int64, error) {
+	var amount int64
+	err := models.DB.Model(&models.Record{}).Scopes(models.ActiveRecords).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("user_id = ? AND date >= ? AND date <= ? AND review_status = ?", userID, startDate, endDate, models.ReviewStatusApproved).
+		Row().Scan(&amount)
+	return amount, err
+}
+
+// getMonthlyBillMap 查询某月全部用户的账单支付状态
+func getMonthlyBillMap(month string) (map[uint]models.MonthlyBill, error) {
+	var bills []models.MonthlyBill
+	if err := models.DB.Where("month = ?", month).Find(&bills).Error; err != nil {
+		return nil, err
+	}
+	billMap := make(map[uint]models.MonthlyBill, len(bills))
+	for _, bill := range bills {
+		billMap[bill.UserID] = bill
+	}
+	return billMap, nil
+}
+
+// GetMonthlyBill 获取用户月度账单，未标记过时返回未支付状态
+func GetMonthlyBill(userID uint, month string) (models.MonthlyBill, error) {
+	bill := models.MonthlyBill{UserID: userID, Month: month, Status: models.BillStatusUnpaid}
+	err := models.DB.Where("user_id = ? AND month = ?", userID, month).First(&bill).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return bill, err
+	}
+	return bill, nil
+}
+
+// billAuditValues 账单中写入审计日志的字段
+func billAuditValues(bill models.MonthlyBill) map[string]interface{} {
+	return map[string]interface{}{
+		"user_id": bill.UserID,
+		"month":   bill.Month,
+		"status":  bill.Status,
+		"amount":  bill.Amount,
+		"paid_at": bill.PaidAt,
+		"remark":  bill.Remark,
+	}
+}
+
+// SetBillPaymentStatus 管理员标记用户月度账单的支付状态，同时记录当时的应付金额
+func SetBillPaymentStatus(c *gin.Context, adminID, userID uint, month string, paid bool, remark string) (*models.MonthlyBill, error) {
+	utils.InfoCtx(c, "标记账单支付状态: admin_id=%d, user_id=%d, month=%s, paid=%v", adminID, userID, month, paid)
+	startDate, endDate, err := getMonthDateRange(month)
+	if err != nil {
+		return nil, errors.New("月份格式错误")
+	}
+	var user models.User
+	if err := models.DB.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+	amount, err := monthlyBillAmount(userID, startDate, endDate)
+	if err != nil {
+		utils.ErrorCtx(c, "统计账单金额失败: %v", err)
+		return nil, err
+	}
+
+	bill, err := GetMonthlyBill(userID, month)
+	if err != nil {
+		return nil, err
+	}
+	before := billAuditValues(bill)
+	bill.Amount = amount
+	bill.Remark = remark
+	bill.MarkedBy = &adminID
+	if paid {
+		now := time.Now()
+		bill.Status = models.BillStatusPaid
+		bill.PaidAt = &now
+	} else {
+		bill.Status = models.BillStatusUnpaid
+		bill.PaidAt = nil
+	}
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&bill).Error; err != nil {
+			return err
+		}
+		return writeAudit(c, tx, AuditBillPayment, AuditTargetBill, bill.ID, before, billAuditValues(bill))
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "保存账单支付状态失败: %v", err)
+		return nil, err
+	}
+	invalidateMonthlyReport(month)
+	utils.InfoCtx(c, "账单支付状态已更新: bill_id=%d, status=%s, amount=%d", bill.ID, bill.Status, bill.Amount)
+	return &bill, nil
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"errors"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// errBulkDryRun 预览模式下回滚事务的标记
+var errBulkDryRun = errors.New("bulk dry run")
+
+// BulkUserFilter 批量操作的成员筛选条件，多个条件同时满足
+// 已移除的用户始终排除；未指定 Status 时只选择正常和暂停状态的成员
+type BulkUserFilter struct {
+	UserIDs    []uint `json:"user_ids"`
+	Role       string `json:"role"`
+	Status     string `json:"status"`
+	CanReserve *bool  `json:"can_reserve"`
+	// All 未指定其他条件时必须为 true，避免误操作全部成员
+	All bool `json:"all"`
+}
+
+// query 按筛选条件构造用户查询
+func (f BulkUserFilter) query(tx *gorm.DB) (*gorm.DB, error) {
+	if len(f.UserIDs) == 0 && f.Role == "" && f.Status == "" && f.CanReserve == nil && !f.All {
+		return nil, errors.New("请指定筛选条件，或设置 all 为 true 选择全部成员")
+	}
+	query := tx.Model(&models.User{}).Where("status <> ?", models.UserStatusRemoved)
+	if len(f.UserIDs) > 0 {
+		query = query.Where("id IN ?", f.UserIDs)
+	}
+	if f.Role != "" {
+		if !models.IsValidRole(f.Role) {
+			return nil, errors.New("角色无效")
+		}
+		query = query.Where("role = ?", f.Role)
+	}
+	switch f.Status {
+	case "":
+		query = query.Where("status IN ?", []string{models.UserStatusActive, models.UserStatusSuspended})
+	case models.UserStatusActive, models.UserStatusSuspended, models.UserStatusPendingApproval:
+		query = query.Where("status = ?", f.Status)
+	default:
+		return nil, errors.New("状态无效，仅支持active、suspended和pending_approval")
+	}
+	if f.CanReserve != nil {
+		query = query.Where("can_reserve = ?", *f.CanReserve)
+	}
+	return query, nil
+}
+
+// bulkUserChange 单个成员的变更，skip 不为空表示跳过该成员
+type bulkUserChange struct {
+	before map[string]interface{}
+	after  map[string]interface{}
+	skip   string
+}
+
+// runBulkUserOperation 在一个事务内锁定筛选出的成员并逐个执行 apply，任一成员失败则全部回滚
+// 预览模式下执行相同的修改后回滚事务，返回结果与实际执行一致；返回实际变更的成员ID
+func runBulkUserOperation(c *gin.Context, filter BulkUserFilter, dryRun bool, apply func(tx *gorm.DB, user *models.User) (bulkUserChange, error)) (map[string]interface{}, []uint, error) {
+	changed := make([]map[string]interface{}, 0)
+	skipped := make([]map[string]interface{}, 0)
+	var changedIDs []uint
+	var matched int
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		query, err := filter.query(tx)
+		if err != nil {
+			return err
+		}
+		var users []models.User
+		if err := query.Clauses(clause.Locking{Strength: "UPDATE"}).Order("id ASC").Find(&users).Error; err != nil {
+			return err
+		}
+		matched = len(users)
+		for i := range users {
+			user := &users[i]
+			change, err := apply(tx, user)
+			if err != nil {
+				utils.WarnCtx(c, "批量操作失败: user_id=%d, err=%v", user.ID, err)
+				return errors.New(user.Name + "：" + err.Error())
+			}
+			if change.skip != "" {
+				skipped = append(skipped, map[string]interface{}{
+					"id":        user.ID,
+					"user_name": user.Name,
+					"reason":    change.skip,
+				})
+				continue
+			}
+			changedIDs = append(changedIDs, user.ID)
+			changed = append(changed, map[string]interface{}{
+				"id":        user.ID,
+				"user_name": user.Name,
+				"before":    change.before,
+				"after":     change.after,
+			})
+		}
+		if dryRun {
+			return errBulkDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errBulkDryRun) {
+		return nil, nil, err
+	}
+	result := map[string]interface{}{
+		"dry_run":  dryRun,
+		"matched":  matched,
+		"affected": len(changed),
+		"users":    changed,
+		"skipped":  skipped,
+	}
+	if dryRun {
+		return result, nil, nil
+	}
+	return result, changedIDs, nil
+}
+
+// bulkAuditValues 批量操作写入审计日志的附加字段
+func bulkAuditValues(values map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{"bulk": true}
+	for key, value := range values {
+		result[key] = value
+	}
+	return result
+}
+
+// BulkUpdateUnitPrice 批量修改成员电价；effectiveDate 为空时立即生效，否则为每个成员创建电价计划
+// 每个成员写入一条审计日志，dryRun 为 true 时只返回预览
+func BulkUpdateUnitPrice(c *gin.Context, adminID uint, filter BulkUserFilter, unitPrice decimal.Decimal, effectiveDate string, dryRun bool) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "批量修改电价: admin_id=%d, unit_price=%s, effective_date=%s, dry_run=%v", adminID, unitPrice, effectiveDate, dryRun)
+	price := models.RoundDecimal(unitPrice, models.PricePlaces)
+	if effectiveDate == "" {
+		if !price.IsPositive() {
+			return nil, errors.New("电价必须为正数")
+		}
+		result, _, err := runBulkUserOperation(c, filter, dryRun, func(tx *gorm.DB, user *models.User) (bulkUserChange, error) {
+			if user.UnitPrice.Equal(price) {
+				return bulkUserChange{skip: "电价未变化"}, nil
+			}
+			before := map[string]interface{}{"unit_price": user.UnitPrice}
+			after := map[string]interface{}{"unit_price": price}
+			if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("unit_price", price).Error; err != nil {
+				return bulkUserChange{}, err
+			}
+			if err := writeAudit(c, tx, AuditUserUnitPrice, AuditTargetUser, user.ID, before, bulkAuditValues(after)); err != nil {
+				return bulkUserChange{}, err
+			}
+			return bulkUserChange{before: before, after: after}, nil
+		})
+		if err != nil {
+			utils.ErrorCtx(c, "批量修改电价失败: %v", err)
+		}
+		return result, err
+	}
+
+	date, err := parsePriceScheduleInput(price, effectiveDate)
+	if err != nil {
+		return nil, err
+	}
+	result, _, err := runBulkUserOperation(c, filter, dryRun, func(tx *gorm.DB, user *models.User) (bulkUserChange, error) {
+		schedule := models.UnitPriceSchedule{
+			UserID:        user.ID,
+			UnitPrice:     price,
+			EffectiveDate: date,
+			CreatedBy:     adminID,
+		}
+		if err := tx.Create(&schedule).Error; err != nil {
+			return bulkUserChange{}, err
+		}
+		after := formatPriceSchedule(schedule)
+		if err := writeAudit(c, tx, AuditPriceScheduleCreate, AuditTargetPriceSchedule, schedule.ID, nil, bulkAuditValues(after)); err != nil {
+			return bulkUserChange{}, err
+		}
+		return bulkUserChange{
+			before: map[string]interface{}{"unit_price": user.UnitPrice},
+			after:  map[string]interface{}{"unit_price": price, "effective_date": effectiveDate},
+		}, nil
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "批量创建电价计划失败: %v", err)
+	}
+	return result, err
+}
+
+// BulkUpdateCanReserve 批量修改成员可预约状态，每个成员写入一条审计日志，dryRun 为 true 时只返回预览
+func BulkUpdateCanReserve(c *gin.Context, adminID uint, filter BulkUserFilter, canReserve bool, dryRun bool) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "批量修改可预约状态: admin_id=%d, can_reserve=%v, dry_run=%v", adminID, canReserve, dryRun)
+	result, changed, err := runBulkUserOperation(c, filter, dryRun, func(tx *gorm.DB, user *models.User) (bulkUserChange, error) {
+		if user.CanReserve == canReserve {
+			return bulkUserChange{skip: "可预约状态未变化"}, nil
+		}
+		before := map[string]interface{}{"can_reserve": user.CanReserve}
+		after := map[string]interface{}{"can_reserve": canReserve}
+		if err := tx.Model(&models.User{}).Where("id = ?", user.ID).Update("can_reserve", canReserve).Error; err != nil {
+			return bulkUserChange{}, err
+		}
+		if err := writeAudit(c, tx, AuditUserCanReserve, AuditTargetUser, user.ID, before, bulkAuditValues(after)); err != nil {
+			return bulkUserChange{}, err
+		}
+		return bulkUserChange{before: before, after: after}, nil
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "批量修改可预约状态失败: %v", err)
+		return nil, err
+	}
+	if len(changed) > 0 {
+		// 月度对账只列出可预约用户
+		invalidateAllMonthlyReports()
+	}
+	return result, nil
+}
+
+// BulkUpdateStatus 批量暂停(suspended)或恢复(active)成员，规则与单个暂停、恢复相同，操作人自己会被跳过
+// 每个成员写入一条审计日志，dryRun 为 true 时只返回预览
+func BulkUpdateStatus(c *gin.Context, adminID uint, filter BulkUserFilter, status, reason, until string, dryRun bool) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "批量修改成员状态: admin_id=%d, status=%s, until=%s, dry_run=%v", adminID, status, until, dryRun)
+	var untilDate *time.Time
+	switch status {
+	case models.UserStatusSuspended:
+		if reason == "" {
+			return nil, errors.New("暂停原因不能为空")
+		}
+		var err error
+		if untilDate, err = parseSuspendUntil(until); err != nil {
+			return nil, err
+		}
+	case models.UserStatusActive:
+	default:
+		return nil, errors.New("状态无效，仅支持active和suspended")
+	}
+
+	var cancelled []time.Time
+	result, changed, err := runBulkUserOperation(c, filter, dryRun, func(tx *gorm.DB, user *models.User) (bulkUserChange, error) {
+		if user.ID == adminID {
+			return bulkUserChange{skip: "不能修改自己的账号"}, nil
+		}
+		if user.Status == status {
+			return bulkUserChange{skip: "状态未变化"}, nil
+		}
+		before := formatUserLifecycle(*user)
+		if status == models.UserStatusActive {
+			if user.Status != models.UserStatusSuspended {
+				return bulkUserChange{skip: "用户未处于暂停状态"}, nil
+			}
+			if err := reactivateLockedUser(c, tx, user, bulkAuditValues(nil)); err != nil {
+				return bulkUserChange{}, err
+			}
+			return bulkUserChange{before: before, after: formatUserLifecycle(*user)}, nil
+		}
+		dates, err := suspendLockedUser(c, tx, adminID, user, reason, untilDate, bulkAuditValues(nil))
+		if err != nil {
+			return bulkUserChange{}, err
+		}
+		cancelled = append(cancelled, dates...)
+		after := formatUserLifecycle(*user)
+		after["cancelled_reservations"] = len(dates)
+		return bulkUserChange{before: before, after: after}, nil
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "批量修改成员状态失败: %v", err)
+		return nil, err
+	}
+	for _, userID := range changed {
+		afterUserLifecycleChange(c, userID, nil)
+	}
+	invalidateMonthlyReportDates(cancelled...)
+	return result, nil
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"shared-charge/config"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// dashboardSectionTimeout 仪表盘各模块的最长等待时间，超时的模块返回错误，不影响其他模块
+const dashboardSectionTimeout = 5 * time.Second
+
+// dashboardSection 仪表盘模块
+type dashboardSection struct {
+	name string
+	load func(db *gorm.DB, now time.Time) (interface{}, error)
+}
+
+// dashboardSectionResult 仪表盘模块的加载结果
+type dashboardSectionResult struct {
+	name string
+	data interface{}
+	err  error
+}
+
+// dashboardSections 管理端首页的模块，各模块并发加载
+var dashboardSections = []dashboardSection{
+	{name: "bookings", load: dashboardBookings},
+	{name: "pending_uploads", load: dashboardPendingUploads},
+	{name: "flagged_records", load: dashboardFlaggedRecords},
+	{name: "unpaid_balances", load: dashboardUnpaidBalances},
+	{name: "pending_approvals", load: dashboardPendingApprovals},
+	{name: "month_to_date", load: dashboardMonthToDate},
+}
+
+// GetAdminDashboard 管理端首页概览，各模块并发加载
+// 某个模块失败或超时时该模块返回 null，并在 errors 中说明，其余模块正常返回
+func GetAdminDashboard(c *gin.Context) map[string]interface{} {
+	now := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), dashboardSectionTimeout)
+	defer cancel()
+	db := models.DB.WithContext(ctx)
+
+	results := make(chan dashboardSectionResult, len(dashboardSections))
+	for _, section := range dashboardSections {
+		go func(section dashboardSection) {
+			defer func() {
+				if r := recover(); r != nil {
+					results <- dashboardSectionResult{name: section.name, err: fmt.Errorf("panic: %v", r)}
+				}
+			}()
+			data, err := section.load(db, now)
+			results <- dashboardSectionResult{name: section.name, data: data, err: err}
+		}(section)
+	}
+
+	dashboard := map[string]interface{}{"generated_at": now}
+	errs := make(map[string]string)
+	pending := make(map[string]bool, len(dashboardSections))
+	for _, section := range dashboardSections {
+		pending[section.name] = true
+	}
+	for len(pending) > 0 {
+		select {
+		case result := <-results:
+			delete(pending, result.name)
+			if result.err != nil {
+				utils.ErrorCtx(c, "加载仪表盘模块失败: section=%s, err=%v", result.name, result.err)
+				dashboard[result.name] = nil
+				errs[result.name] = "加载失败"
+				continue
+			}
+			dashboard[result.name] = result.data
+		case <-ctx.Done():
+			for name := range pending {
+				utils.WarnCtx(c, "加载仪表盘模块超时: section=%s", name)
+				dashboard[name] = nil
+				errs[name] = "加载超时"
+			}
+			pending = nil
+		}
+	}
+	dashboard["errors"] = errs
+	return dashboard
+}
+
+// dashboardBookings 今天和明天未取消的预约
+func dashboardBookings(db *gorm.DB, now time.Time) (interface{}, error) {
+	today := now.Format("2006-01-02")
+	tomorrow := now.AddDate(0, 0, 1).Format("2006-01-02")
+	var reservations []models.Reservation
+	err := db.Where("status != ? AND date IN ?", "cancelled", []string{today, tomorrow}).
+		Preload("User").
+		Preload("LicensePlate").
+		Order("date ASC, timeslot ASC, id ASC").
+		Find(&reservations).Error
+	if err != nil {
+		return nil, err
+	}
+	byDate := map[string][]map[string]interface{}{
+		today:    {},
+		tomorrow: {},
+	}
+	for i := range reservations {
+		date := reservations[i].Date.Format("2006-01-02")
+		byDate[date] = append(byDate[date], FormatReservationDate(&reservations[i]))
+	}
+	return map[string]interface{}{
+		"today":    map[string]interface{}{"date": today, "reservations": byDate[today]},
+		"tomorrow": map[string]interface{}{"date": tomorrow, "reservations": byDate[tomorrow]},
+	}, nil
+}
+
+// dashboardPendingUploads 已结束但未上传充电记录的预约数
+func dashboardPendingUploads(_ *gorm.DB, _ time.Time) (interface{}, error) {
+	outstanding, err := findOutstandingUploads(0, "", "")
+	if err != nil {
+		return nil, err
+	}
+	members := make(map[uint]bool)
+	var oldestDate interface{}
+	for _, item := range outstanding {
+		members[item.Reservation.UserID] = true
+		if oldestDate == nil {
+			oldestDate = item.Reservation.Date.Format("2006-01-02")
+		}
+	}
+	return map[string]interface{}{
+		"count":        len(outstanding),
+		"member_count": len(members),
+		"oldest_date":  oldestDate,
+	}, nil
+}
+
+// dashboardFlaggedRecords 待审核的异常记录、待审核记录和待处理的更正申请数
+func dashboardFlaggedRecords(db *gorm.DB, _ time.Time) (interface{}, error) {
+	var counts struct {
+		Flagged        int64
+		AwaitingReview int64
+	}
+	err := db.Model(&models.Record{}).Scopes(models.ActiveRecords).
+		Select("COUNT(*) FILTER (WHERE anomaly_score >= ? AND anomaly_score > 0) as flagged, COUNT(*) as awaiting_review", config.GetConfig().Anomaly.FlagScoreThreshold).
+		Where("review_status = ?", models.ReviewStatusSubmitted).
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	var corrections int64
+	err = db.Model(&models.RecordCorrectionRequest{}).Where("status = ?", models.CorrectionStatusPending).Count(&corrections).Error
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"flagged":             counts.Flagged,
+		"awaiting_review":     counts.AwaitingReview,
+		"pending_corrections": corrections,
+	}, nil
+}
+
+// dashboardUnpaidBalances 上个月有应付金额但未标记支付的成员，数据与月度对账一致
+func dashboardUnpaidBalances(_ *gorm.DB, now time.Time) (interface{}, error) {
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()).AddDate(0, -1, 0).Format("2006-01")
+	report, err := loadMonthlyReport(nil, month)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]map[string]interface{}, 0)
+	var total int64
+	for _, user := range report.Users {
+		if user.TotalAmount <= 0 || user.PaymentStatus == models.BillStatusPaid {
+			continue
+		}
+		total += user.TotalAmount
+		members = append(members, map[string]interface{}{
+			"id":        user.ID,
+			"user_name": user.Name,
+			"amount":    models.FenToYuan(user.TotalAmount),
+		})
+	}
+	return map[string]interface{}{
+		"month":        month,
+		"total_amount": models.FenToYuan(total),
+		"member_count": len(members),
+		"members":      members,
+	}, nil
+}
+
+// dashboardPendingApprovals 等待审核的新用户数
+func dashboardPendingApprovals(db *gorm.DB, _ time.Time) (interface{}, error) {
+	var result struct {
+		Count  int64
+		Oldest *time.Time
+	}
+	err := db.Model(&models.User{}).
+		Select("COUNT(*) as count, MIN(created_at) as oldest").
+		Where("status = ?", models.UserStatusPendingApproval).
+		Scan(&result).Error
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"count":             result.Count,
+		"oldest_created_at": result.Oldest,
+	}, nil
+}
+
+// dashboardMonthToDate 本月截至今天的用电量、费用、记录数和预约情况
+func dashboardMonthToDate(db *gorm.DB, now time.Time) (interface{}, error) {
+	month := now.Format("2006-01")
+	startDate := month + "-01"
+	today := now.Format("2006-01-02")
+	var totals struct {
+		TotalKwh    decimal.Decimal
+		TotalAmount int64
+		RecordCount int64
+	}
+	err := db.Model(&models.UserMonthStat{}).
+		Select("COALESCE(SUM(total_kwh), 0) as total_kwh, COALESCE(SUM(total_amount), 0) as total_amount, COALESCE(SUM(record_count), 0) as record_count").
+		Where("month = ?", month).
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+	utilization, err := querySlotUtilization(startDate, today)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"month":                  month,
+		"total_kwh":              totals.TotalKwh,
+		"total_amount":           models.FenToYuan(totals.TotalAmount),
+		"record_count":           totals.RecordCount,
+		"total_reservations":     utilization.TotalReservations,
+		"cancelled_reservations": utilization.CancelledReservations,
+		"active_members":         utilization.ActiveMembers,
+	}, nil
+}
@@ -0,0 +1,312 @@
+package service
+
+import (
+	"errors"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// discountTypeOrder 同一记录上多条优惠的应用顺序：先抵扣免费度数，再打折，最后扣减固定额度
+var discountTypeOrder = map[string]int{
+	models.DiscountTypeFreeKWH:     0,
+	models.DiscountTypePercentage:  1,
+	models.DiscountTypeFixedCredit: 2,
+}
+
+// DiscountRuleRequest 创建/更新优惠规则请求
+type DiscountRuleRequest struct {
+	Name           string
+	Type           string
+	Value          decimal.Decimal
+	Scope          string
+	UserID         *uint
+	LicensePlateID *uint
+	StartDate      string
+	EndDate        string
+	Active         *bool
+}
+
+// lockDiscountAllowance 以事务级咨询锁串行化同一用户对同一规则每月额度的扣减，不同用户之间互不等待
+func lockDiscountAllowance(tx *gorm.DB, ruleID, userID uint) error {
+	return tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", int32(ruleID), int32(userID)).Error
+}
+
+// findApplicableDiscountRules 查找适用于记录的优惠规则
+// 规则行只加共享锁，防止计算期间被修改；有每月额度的规则再按规则+用户加咨询锁
+func findApplicableDiscountRules(tx *gorm.DB, record *models.Record) ([]models.DiscountRule, error) {
+	scope := tx.Where("scope = ?", models.DiscountScopeAll).
+		Or("scope = ? AND user_id = ?", models.DiscountScopeUser, record.UserID)
+	if record.LicensePlateID != nil {
+		scope = scope.Or("scope = ? AND license_plate_id = ?", models.DiscountScopePlate, *record.LicensePlateID)
+	}
+	var rules []models.DiscountRule
+	err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+		Where("active = ?", true).
+		Where(scope).
+		Order("id ASC").
+		Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	applicable := make([]models.DiscountRule, 0, len(rules))
+	for _, rule := range rules {
+		if !rule.AppliesOn(record.Date) {
+			continue
+		}
+		// 按规则ID顺序加锁，避免并发事务之间死锁
+		if rule.Type == models.DiscountTypeFreeKWH || rule.Type == models.DiscountTypeFixedCredit {
+			if err := lockDiscountAllowance(tx, rule.ID, record.UserID); err != nil {
+				return nil, err
+			}
+		}
+		applicable = append(applicable, rule)
+	}
+	sort.SliceStable(applicable, func(i, j int) bool {
+		return discountTypeOrder[applicable[i].Type] < discountTypeOrder[applicable[j].Type]
+	})
+	return applicable, nil
+}
+
+// monthlyDiscountUsage 统计规则在记录所在月份已被其他有效记录使用的度数与金额，已作废和已驳回的记录不占用额度
+func monthlyDiscountUsage(tx *gorm.DB, ruleID, userID, recordID uint, date time.Time) (decimal.Decimal, int64, error) {
+	monthStart := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.Local)
+	monthEnd := monthStart.AddDate(0, 1, -1)
+	var usage struct {
+		KWH    decimal.Decimal
+		Amount int64
+	}
+	err := tx.Model(&models.RecordDiscount{}).
+		Select("COALESCE(SUM(record_discounts.kwh), 0) as kwh, COALESCE(SUM(record_discounts.amount), 0) as amount").
+		Joins("JOIN records ON records.id = record_discounts.record_id AND records.voided_at IS NULL AND records.deleted_at IS NULL").
+		Where("record_discounts.rule_id = ? AND records.user_id = ? AND records.id != ?", ruleID, userID, recordID).
+		Where("records.review_status != ?", models.ReviewStatusRejected).
+		Where("records.date >= ? AND records.date <= ?", monthStart.Format("2006-01-02"), monthEnd.Format("2006-01-02")).
+		Scan(&usage).Error
+	return usage.KWH, usage.Amount, err
+}
+
+// calculateRecordAmount 计算优惠前费用并按规则扣减优惠，返回需保存的优惠明细
+// 免费度数与固定抵扣按用户每月重置
+func calculateRecordAmount(tx *gorm.DB, record *models.Record) ([]models.RecordDiscount, error) {
+	record.CalculateAmount()
+	rules, err := findApplicableDiscountRules(tx, record)
+	if err != nil {
+		return nil, err
+	}
+
+	discounts := make([]models.RecordDiscount, 0)
+	remaining := record.GrossAmount
+	for _, rule := range rules {
+		if remaining <= 0 {
+			break
+		}
+		discount := models.RecordDiscount{RuleID: rule.ID, RuleName: rule.Name, Type: rule.Type, KWH: decimal.Zero}
+		switch rule.Type {
+		case models.DiscountTypeFreeKWH:
+			usedKWH, _, err := monthlyDiscountUsage(tx, rule.ID, record.UserID, record.ID, record.Date)
+			if err != nil {
+				return nil, err
+			}
+			freeKWH := decimal.Min(rule.Value.Sub(usedKWH), record.KWH)
+			if !freeKWH.IsPositive() {
+				continue
+			}
+			discount.KWH = freeKWH
+			discount.Amount = models.CalculateAmountFen(freeKWH, record.UnitPrice)
+		case models.DiscountTypePercentage:
+			discount.Amount = models.RoundDecimal(decimal.NewFromInt(remaining).Mul(rule.Value).Div(decimal.NewFromInt(100)), 0).IntPart()
+		case models.DiscountTypeFixedCredit:
+			_, usedFen, err := monthlyDiscountUsage(tx, rule.ID, record.UserID, record.ID, record.Date)
+			if err != nil {
+				return nil, err
+			}
+			discount.Amount = rule.Value.Shift(2).IntPart() - usedFen
+		default:
+			continue
+		}
+		if discount.Amount > remaining {
+			discount.Amount = remaining
+		}
+		if discount.Amount <= 0 {
+			continue
+		}
+		remaining -= discount.Amount
+		discounts = append(discounts, discount)
+	}
+	record.DiscountAmount = record.GrossAmount - remaining
+	record.Amount = remaining
+	return discounts, nil
+}
+
+// saveRecordDiscounts 替换记录的优惠明细
+func saveRecordDiscounts(tx *gorm.DB, recordID uint, discounts []models.RecordDiscount) error {
+	if err := tx.Where("record_id = ?", recordID).Delete(&models.RecordDiscount{}).Error; err != nil {
+		return err
+	}
+	if len(discounts) == 0 {
+		return nil
+	}
+	for i := range discounts {
+		discounts[i].ID = 0
+		discounts[i].RecordID = recordID
+	}
+	return tx.Create(&discounts).Error
+}
+
+// buildDiscountRule 校验请求并填充规则字段
+func buildDiscountRule(rule *models.DiscountRule, req DiscountRuleRequest) error {
+	if req.Name == "" {
+		return errors.New("规则名称不能为空")
+	}
+	if _, ok := discountTypeOrder[req.Type]; !ok {
+		return errors.New("不支持的优惠类型")
+	}
+	if !req.Value.IsPositive() {
+		return errors.New("优惠数值必须大于0")
+	}
+	if req.Type == models.DiscountTypePercentage && req.Value.GreaterThan(decimal.NewFromInt(100)) {
+		return errors.New("折扣百分比不能超过100")
+	}
+	rule.Name = req.Name
+	rule.Type = req.Type
+	rule.Value = req.Value
+	rule.Scope = req.Scope
+	rule.UserID = nil
+	rule.LicensePlateID = nil
+	switch req.Scope {
+	case models.DiscountScopeAll:
+	case models.DiscountScopeUser:
+		if req.UserID == nil {
+			return errors.New("请指定适用用户")
+		}
+		rule.UserID = req.UserID
+	case models.DiscountScopePlate:
+		if req.LicensePlateID == nil {
+			return errors.New("请指定适用车牌号")
+		}
+		var plate models.LicensePlate
+		if err := models.DB.First(&plate, *req.LicensePlateID).Error; err != nil {
+			return errors.New("车牌号不存在")
+		}
+		rule.LicensePlateID = req.LicensePlateID
+		rule.UserID = &plate.UserID
+	default:
+		return errors.New("不支持的适用范围")
+	}
+	rule.StartDate = nil
+	rule.EndDate = nil
+	if req.StartDate != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.StartDate, time.Local)
+		if err != nil {
+			return errors.New("生效日期格式错误")
+		}
+		rule.StartDate = &date
+	}
+	if req.EndDate != "" {
+		date, err := time.ParseInLocation("2006-01-02", req.EndDate, time.Local)
+		if err != nil {
+			return errors.New("失效日期格式错误")
+		}
+		rule.EndDate = &date
+	}
+	if rule.StartDate != nil && rule.EndDate != nil && rule.EndDate.Before(*rule.StartDate) {
+		return errors.New("失效日期不能早于生效日期")
+	}
+	if req.Active != nil {
+		rule.Active = *req.Active
+	}
+	return nil
+}
+
+// GetDiscountRules 管理员获取优惠规则列表
+func GetDiscountRules(c *gin.Context) ([]map[string]interface{}, error) {
+	var rules []models.DiscountRule
+	if err := models.DB.Order("id DESC").Find(&rules).Error; err != nil {
+		utils.ErrorCtx(c, "查询优惠规则失败: %v", err)
+		return nil, err
+	}
+	resp := make([]map[string]interface{}, 0, len(rules))
+	for _, rule := range rules {
+		resp = append(resp, rule.FormatDiscountRuleInfo())
+	}
+	return resp, nil
+}
+
+// CreateDiscountRule 管理员创建优惠规则，仅对之后计算费用的记录生效
+func CreateDiscountRule(c *gin.Context, adminID uint, req DiscountRuleRequest) (*models.DiscountRule, error) {
+	rule := &models.DiscountRule{Active: true, CreatedBy: adminID}
+	if err := buildDiscountRule(rule, req); err != nil {
+		return nil, err
+	}
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rule).Error; err != nil {
+			return err
+		}
+		return writeAudit(c, tx, AuditDiscountCreate, AuditTargetDiscountRule, rule.ID, nil, rule.FormatDiscountRuleInfo())
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "创建优惠规则失败: %v", err)
+		return nil, err
+	}
+	utils.InfoCtx(c, "优惠规则已创建: rule_id=%d, type=%s, scope=%s, value=%s", rule.ID, rule.Type, rule.Scope, rule.Value)
+	return rule, nil
+}
+
+// UpdateDiscountRule 管理员修改优惠规则，已计算的记录不受影响
+func UpdateDiscountRule(c *gin.Context, ruleID uint, req DiscountRuleRequest) (*models.DiscountRule, error) {
+	var rule models.DiscountRule
+	if err := models.DB.First(&rule, ruleID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("优惠规则不存在")
+		}
+		return nil, err
+	}
+	before := rule.FormatDiscountRuleInfo()
+	if err := buildDiscountRule(&rule, req); err != nil {
+		return nil, err
+	}
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("*").Omit("created_at", "created_by").Save(&rule).Error; err != nil {
+			return err
+		}
+		return writeAudit(c, tx, AuditDiscountUpdate, AuditTargetDiscountRule, rule.ID, before, rule.FormatDiscountRuleInfo())
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "更新优惠规则失败: rule_id=%d, err=%v", ruleID, err)
+		return nil, err
+	}
+	utils.InfoCtx(c, "优惠规则已更新: rule_id=%d", rule.ID)
+	return &rule, nil
+}
+
+// DeleteDiscountRule 管理员删除优惠规则，已使用的优惠明细保留
+func DeleteDiscountRule(c *gin.Context, ruleID uint) error {
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var rule models.DiscountRule
+		if err := tx.First(&rule, ruleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("优惠规则不存在")
+			}
+			return err
+		}
+		if err := tx.Delete(&rule).Error; err != nil {
+			return err
+		}
+		return writeAudit(c, tx, AuditDiscountDelete, AuditTargetDiscountRule, rule.ID, rule.FormatDiscountRuleInfo(), nil)
+	})
+	if err != nil {
+		if err.Error() != "优惠规则不存在" {
+			utils.ErrorCtx(c, "删除优惠规则失败: rule_id=%d, err=%v", ruleID, err)
+		}
+		return err
+	}
+	utils.InfoCtx(c, "优惠规则已删除: rule_id=%d", ruleID)
+	return nil
+}
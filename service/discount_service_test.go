@@ -0,0 +1,43 @@
+package service
+
+import (
+	"shared-charge/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+)
+
+func TestCalculateRecordAmountFreeKWHAllowance(t *testing.T) {
+	mock := setupMockDB(t)
+	record := &models.Record{
+		ID:        11,
+		UserID:    3,
+		Date:      time.Date(2024, 5, 20, 0, 0, 0, 0, time.Local),
+		KWH:       decimal.RequireFromString("10"),
+		UnitPrice: decimal.RequireFromString("0.5"),
+	}
+	// 规则行只加共享锁，额度按规则+用户加咨询锁
+	mock.ExpectQuery(`SELECT \* FROM "discount_rules" WHERE .* FOR SHARE`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "type", "value", "scope", "active"}).
+			AddRow(2, "每月免费", models.DiscountTypeFreeKWH, "20", models.DiscountScopeAll, true))
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1, \$2\)`).
+		WithArgs(int32(2), int32(3)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	// 已驳回的记录不占用额度
+	mock.ExpectQuery(`FROM "record_discounts" JOIN records .* AND records.review_status != \$4`).
+		WithArgs(uint(2), uint(3), uint(11), models.ReviewStatusRejected, "2024-05-01", "2024-05-31").
+		WillReturnRows(sqlmock.NewRows([]string{"kwh", "amount"}).AddRow("15", 750))
+
+	discounts, err := calculateRecordAmount(models.DB, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(discounts) != 1 || !discounts[0].KWH.Equal(decimal.RequireFromString("5")) {
+		t.Fatalf("应抵扣剩余的 5 度，实际为 %+v", discounts)
+	}
+	if record.GrossAmount != 500 || record.DiscountAmount != 250 || record.Amount != 250 {
+		t.Fatalf("金额 = %d/%d/%d, want 500/250/250", record.GrossAmount, record.DiscountAmount, record.Amount)
+	}
+}
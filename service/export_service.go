@@ -0,0 +1,437 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// RecordExportFilter 充电记录导出筛选条件，零值表示不限
+type RecordExportFilter struct {
+	UserID         uint
+	LicensePlateID uint
+	StartDate      string
+	EndDate        string
+	ReviewStatus   string
+	Timeslot       string
+	IncludeVoided  bool
+}
+
+// ReservationExportFilter 预约导出筛选条件，零值表示不限
+type ReservationExportFilter struct {
+	UserID    uint
+	StartDate string
+	EndDate   string
+	Status    string
+}
+
+var recordExportColumns = []exportColumn{
+	{ZH: "记录ID", EN: "Record ID", Kind: exportInt},
+	{ZH: "充电日期", EN: "Date", Kind: exportText},
+	{ZH: "用户", EN: "Member", Kind: exportText},
+	{ZH: "车牌号", EN: "License plate", Kind: exportText},
+	{ZH: "班次", EN: "Timeslot", Kind: exportText},
+	{ZH: "度数(kWh)", EN: "Energy (kWh)", Kind: exportKWH},
+	{ZH: "单价(元/度)", EN: "Unit price (CNY/kWh)", Kind: exportPrice},
+	{ZH: "原价(元)", EN: "Gross amount (CNY)", Kind: exportYuan},
+	{ZH: "优惠(元)", EN: "Discount (CNY)", Kind: exportYuan},
+	{ZH: "金额(元)", EN: "Amount (CNY)", Kind: exportYuan},
+	{ZH: "审核状态", EN: "Review status", Kind: exportText},
+	{ZH: "已作废", EN: "Voided", Kind: exportText},
+	{ZH: "作废原因", EN: "Void reason", Kind: exportText},
+	{ZH: "备注", EN: "Remark", Kind: exportText},
+}
+
+var reservationExportColumns = []exportColumn{
+	{ZH: "预约ID", EN: "Reservation ID", Kind: exportInt},
+	{ZH: "预约日期", EN: "Date", Kind: exportText},
+	{ZH: "用户", EN: "Member", Kind: exportText},
+	{ZH: "车牌号", EN: "License plate", Kind: exportText},
+	{ZH: "班次", EN: "Timeslot", Kind: exportText},
+	{ZH: "状态", EN: "Status", Kind: exportText},
+	{ZH: "已上传记录", EN: "Record uploaded", Kind: exportText},
+	{ZH: "备注", EN: "Remark", Kind: exportText},
+	{ZH: "创建时间", EN: "Created at", Kind: exportText},
+}
+
+var reportSummaryColumns = []exportColumn{
+	{ZH: "用户", EN: "Member", Kind: exportText},
+	{ZH: "车牌号", EN: "License plate", Kind: exportText},
+	{ZH: "记录数", EN: "Records", Kind: exportInt},
+	{ZH: "优惠(元)", EN: "Discount (CNY)", Kind: exportYuan},
+	{ZH: "金额(元)", EN: "Amount (CNY)", Kind: exportYuan},
+	{ZH: "已全部上传", EN: "All uploaded", Kind: exportText},
+	{ZH: "待上传预约数", EN: "Outstanding uploads", Kind: exportInt},
+	{ZH: "未审核记录数", EN: "Unapproved records", Kind: exportInt},
+	{ZH: "支付状态", EN: "Payment status", Kind: exportText},
+}
+
+var reportPlateColumns = []exportColumn{
+	{ZH: "充电日期", EN: "Date", Kind: exportText},
+	{ZH: "用户", EN: "Member", Kind: exportText},
+	{ZH: "班次", EN: "Timeslot", Kind: exportText},
+	{ZH: "度数(kWh)", EN: "Energy (kWh)", Kind: exportKWH},
+	{ZH: "单价(元/度)", EN: "Unit price (CNY/kWh)", Kind: exportPrice},
+	{ZH: "优惠(元)", EN: "Discount (CNY)", Kind: exportYuan},
+	{ZH: "金额(元)", EN: "Amount (CNY)", Kind: exportYuan},
+	{ZH: "备注", EN: "Remark", Kind: exportText},
+}
+
+// exportTotalLabel 合计行的本地化文本
+func exportTotalLabel(lang string) string {
+	if lang == ExportLangEN {
+		return "Total"
+	}
+	return "合计"
+}
+
+// ParseExportDateRange 解析导出日期范围：优先使用 from/to，其次 month，均为空表示不限
+func ParseExportDateRange(from, to, month string) (string, string, error) {
+	if from != "" || to != "" {
+		for _, value := range []string{from, to} {
+			if value == "" {
+				continue
+			}
+			if _, err := time.Parse("2006-01-02", value); err != nil {
+				return "", "", errors.New("日期格式错误，应为YYYY-MM-DD")
+			}
+		}
+		if from != "" && to != "" && to < from {
+			return "", "", errors.New("结束日期不能早于开始日期")
+		}
+		return from, to, nil
+	}
+	if month != "" {
+		startDate, endDate, err := getMonthDateRange(month)
+		if err != nil {
+			return "", "", errors.New("月份格式错误")
+		}
+		return startDate, endDate, nil
+	}
+	return "", "", nil
+}
+
+// exportFileName 生成导出文件名，包含日期范围
+func exportFileName(prefix, startDate, endDate string) string {
+	parts := []string{prefix}
+	if startDate != "" {
+		parts = append(parts, startDate)
+	}
+	if endDate != "" {
+		parts = append(parts, endDate)
+	}
+	return strings.Join(parts, "_")
+}
+
+// startExport 设置下载响应头并创建导出写入器
+func startExport(c *gin.Context, filename, format, lang string) (exportWriter, error) {
+	contentType := "text/csv; charset=utf-8"
+	if format == ExportFormatXLSX {
+		contentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	c.Header("Content-Type", contentType)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, filename, format))
+	c.Status(200)
+	return newExportWriter(c.Writer, format, lang)
+}
+
+// exportRecordRow 导出的充电记录行
+type exportRecordRow struct {
+	ID             uint
+	Date           time.Time
+	UserName       string
+	PlateNumber    string
+	Timeslot       string
+	KWH            decimal.Decimal
+	UnitPrice      decimal.Decimal
+	GrossAmount    int64
+	DiscountAmount int64
+	Amount         int64
+	ReviewStatus   string
+	VoidedAt       *time.Time
+	VoidReason     string
+	Remark         string
+}
+
+// exportRecordQuery 构造充电记录导出查询，班次优先取记录上的值，否则取关联预约的时段
+func exportRecordQuery() *gorm.DB {
+	return models.DB.Model(&models.Record{}).
+		Select(`records.id, records.date, COALESCE(users.name, '') as user_name, COALESCE(license_plates.plate_number, '') as plate_number,
+			COALESCE(NULLIF(records.timeslot, ''), reservations.timeslot, '') as timeslot,
+			records.kwh, records.unit_price, records.gross_amount, records.discount_amount, records.amount,
+			records.review_status, records.voided_at, COALESCE(records.void_reason, '') as void_reason, COALESCE(records.remark, '') as remark`).
+		Joins("LEFT JOIN users ON users.id = records.user_id").
+		Joins("LEFT JOIN license_plates ON license_plates.id = records.license_plate_id").
+		Joins("LEFT JOIN reservations ON reservations.id = records.reservation_id")
+}
+
+// ExportRecords 按筛选条件流式导出充电记录
+func ExportRecords(c *gin.Context, filter RecordExportFilter, format, lang string) error {
+	utils.InfoCtx(c, "导出充电记录: filter=%+v, format=%s, lang=%s", filter, format, lang)
+	query := exportRecordQuery()
+	if !filter.IncludeVoided {
+		query = query.Scopes(models.ActiveRecords)
+	}
+	if filter.UserID != 0 {
+		query = query.Where("records.user_id = ?", filter.UserID)
+	}
+	if filter.LicensePlateID != 0 {
+		query = query.Where("records.license_plate_id = ?", filter.LicensePlateID)
+	}
+	if filter.StartDate != "" {
+		query = query.Where("records.date >= ?", filter.StartDate)
+	}
+	if filter.EndDate != "" {
+		query = query.Where("records.date <= ?", filter.EndDate)
+	}
+	if filter.ReviewStatus != "" {
+		query = query.Where("records.review_status = ?", filter.ReviewStatus)
+	}
+	if filter.Timeslot != "" {
+		query = query.Where("COALESCE(NULLIF(records.timeslot, ''), reservations.timeslot) = ?", filter.Timeslot)
+	}
+	rows, err := query.Order("records.date ASC, records.id ASC").Rows()
+	if err != nil {
+		utils.ErrorCtx(c, "查询导出充电记录失败: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	writer, err := startExport(c, exportFileName("records", filter.StartDate, filter.EndDate), format, lang)
+	if err != nil {
+		return err
+	}
+	if err := writer.StartSheet(exportSheetName(lang, "充电记录", "Records"), recordExportColumns); err != nil {
+		return err
+	}
+	count := 0
+	for rows.Next() {
+		var row exportRecordRow
+		if err := models.DB.ScanRows(rows, &row); err != nil {
+			utils.ErrorCtx(c, "读取导出充电记录失败: %v", err)
+			return err
+		}
+		err := writer.WriteRow(
+			row.ID,
+			row.Date.Format("2006-01-02"),
+			row.UserName,
+			row.PlateNumber,
+			exportLabel(lang, row.Timeslot),
+			row.KWH,
+			row.UnitPrice,
+			models.FenToYuan(row.GrossAmount),
+			models.FenToYuan(row.DiscountAmount),
+			models.FenToYuan(row.Amount),
+			exportLabel(lang, row.ReviewStatus),
+			exportBool(lang, row.VoidedAt != nil),
+			row.VoidReason,
+			row.Remark,
+		)
+		if err != nil {
+			return err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	utils.InfoCtx(c, "充电记录导出完成: count=%d", count)
+	return writer.Close()
+}
+
+// ExportReservations 按筛选条件流式导出预约
+func ExportReservations(c *gin.Context, filter ReservationExportFilter, format, lang string) error {
+	utils.InfoCtx(c, "导出预约: filter=%+v, format=%s, lang=%s", filter, format, lang)
+	query := models.DB.Model(&models.Reservation{}).
+		Select(`reservations.id, reservations.date, COALESCE(users.name, '') as user_name, COALESCE(license_plates.plate_number, '') as plate_number,
+			reservations.timeslot, reservations.status, COALESCE(reservations.remark, '') as remark, reservations.created_at,
+			` + activeRecordExistsCondition + ` as has_record`).
+		Joins("LEFT JOIN users ON users.id = reservations.user_id").
+		Joins("LEFT JOIN license_plates ON license_plates.id = reservations.license_plate_id")
+	if filter.UserID != 0 {
+		query = query.Where("reservations.user_id = ?", filter.UserID)
+	}
+	if filter.StartDate != "" {
+		query = query.Where("reservations.date >= ?", filter.StartDate)
+	}
+	if filter.EndDate != "" {
+		query = query.Where("reservations.date <= ?", filter.EndDate)
+	}
+	if filter.Status != "" {
+		query = query.Where("reservations.status = ?", filter.Status)
+	}
+	rows, err := query.Order("reservations.date ASC, reservations.timeslot ASC, reservations.id ASC").Rows()
+	if err != nil {
+		utils.ErrorCtx(c, "查询导出预约失败: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	writer, err := startExport(c, exportFileName("reservations", filter.StartDate, filter.EndDate), format, lang)
+	if err != nil {
+		return err
+	}
+	if err := writer.StartSheet(exportSheetName(lang, "预约", "Reservations"), reservationExportColumns); err != nil {
+		return err
+	}
+	for rows.Next() {
+		var row struct {
+			ID          uint
+			Date        time.Time
+			UserName    string
+			PlateNumber string
+			Timeslot    string
+			Status      string
+			Remark      string
+			CreatedAt   time.Time
+			HasRecord   bool
+		}
+		if err := models.DB.ScanRows(rows, &row); err != nil {
+			utils.ErrorCtx(c, "读取导出预约失败: %v", err)
+			return err
+		}
+		err := writer.WriteRow(
+			row.ID,
+			row.Date.Format("2006-01-02"),
+			row.UserName,
+			row.PlateNumber,
+			exportLabel(lang, row.Timeslot),
+			exportLabel(lang, row.Status),
+			exportBool(lang, row.HasRecord),
+			row.Remark,
+			row.CreatedAt.Format("2006-01-02 15:04:05"),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return writer.Close()
+}
+
+// exportSheetName 返回本地化的 sheet 名称
+func exportSheetName(lang, zh, en string) string {
+	if lang == ExportLangEN {
+		return en
+	}
+	return zh
+}
+
+// ExportMonthlyReport 导出月度对账：第一个 sheet 为汇总，之后每个车牌号一个 sheet 列出审核通过的记录
+func ExportMonthlyReport(c *gin.Context, month, format, lang string) error {
+	utils.InfoCtx(c, "导出月度对账: month=%s, format=%s, lang=%s", month, format, lang)
+	report, err := loadMonthlyReport(c, month)
+	if err != nil {
+		utils.ErrorCtx(c, "汇总月度对账失败: %v", err)
+		return err
+	}
+	// 与汇总口径一致：仅可预约且未在本月之前移除的用户、审核通过且未作废的记录
+	rows, err := exportRecordQuery().Scopes(models.ActiveRecords).
+		Where("users.can_reserve = ? AND (users.removed_at IS NULL OR users.removed_at >= ?)", true, report.StartDate).
+		Where("records.date >= ? AND records.date <= ? AND records.review_status = ?", report.StartDate, report.EndDate, models.ReviewStatusApproved).
+		Order("plate_number ASC, records.date ASC, records.id ASC").
+		Rows()
+	if err != nil {
+		utils.ErrorCtx(c, "查询月度对账明细失败: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	writer, err := startExport(c, exportFileName("monthly_report", month, ""), format, lang)
+	if err != nil {
+		return err
+	}
+	if err := writer.StartSheet(exportSheetName(lang, "汇总", "Summary"), reportSummaryColumns); err != nil {
+		return err
+	}
+	for _, user := range report.Users {
+		for _, plate := range user.Plates {
+			err := writer.WriteRow(
+				user.Name,
+				plate.PlateNumber,
+				plate.RecordCount,
+				models.FenToYuan(plate.DiscountAmount),
+				models.FenToYuan(plate.TotalAmount),
+				exportBool(lang, plate.HasUploaded),
+				user.OutstandingCount,
+				user.UnapprovedCount,
+				exportLabel(lang, user.PaymentStatus),
+			)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	err = writer.WriteRow(exportTotalLabel(lang), "", nil,
+		models.FenToYuan(report.DiscountAmount), models.FenToYuan(report.TotalAmount), "", nil, nil, "")
+	if err != nil {
+		return err
+	}
+
+	// 按车牌号分 sheet，每个 sheet 末尾写合计行
+	currentPlate := ""
+	started := false
+	var kwh decimal.Decimal
+	var discount, amount int64
+	writeTotal := func() error {
+		return writer.WriteRow(exportTotalLabel(lang), "", "", kwh, nil, models.FenToYuan(discount), models.FenToYuan(amount), "")
+	}
+	for rows.Next() {
+		var row exportRecordRow
+		if err := models.DB.ScanRows(rows, &row); err != nil {
+			utils.ErrorCtx(c, "读取月度对账明细失败: %v", err)
+			return err
+		}
+		plate := row.PlateNumber
+		if plate == "" {
+			plate = unboundPlateNumber
+		}
+		if !started || plate != currentPlate {
+			if started {
+				if err := writeTotal(); err != nil {
+					return err
+				}
+			}
+			if err := writer.StartSheet(plate, reportPlateColumns); err != nil {
+				return err
+			}
+			started = true
+			currentPlate = plate
+			kwh, discount, amount = decimal.Zero, 0, 0
+		}
+		kwh = kwh.Add(row.KWH)
+		discount += row.DiscountAmount
+		amount += row.Amount
+		err := writer.WriteRow(
+			row.Date.Format("2006-01-02"),
+			row.UserName,
+			exportLabel(lang, row.Timeslot),
+			row.KWH,
+			row.UnitPrice,
+			models.FenToYuan(row.DiscountAmount),
+			models.FenToYuan(row.Amount),
+			row.Remark,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if started {
+		if err := writeTotal(); err != nil {
+			return err
+		}
+	}
+	return writer.Close()
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"shared-charge/models"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"github.com/xuri/excelize/v2"
+)
+
+// 导出文件格式
+const (
+	ExportFormatCSV  = "csv"
+	ExportFormatXLSX = "xlsx"
+)
+
+// 导出表头语言
+const (
+	ExportLangZH = "zh"
+	ExportLangEN = "en"
+)
+
+// exportColumnKind 导出列的数据类型，决定数值的格式
+type exportColumnKind int
+
+const (
+	exportText exportColumnKind = iota
+	exportInt
+	exportKWH   // 度数，保留两位小数
+	exportPrice // 单价，保留四位小数
+	exportYuan  // 金额(元)，保留两位小数
+)
+
+// exportColumn 导出列定义，表头按语言本地化
+type exportColumn struct {
+	ZH   string
+	EN   string
+	Kind exportColumnKind
+}
+
+// exportValueLabels 导出内容中枚举值的本地化文本
+var exportValueLabels = map[string]map[string]string{
+	ExportLangZH: {
+		"day":                        "白班",
+		"night":                      "夜班",
+		"pending":                    "待确认",
+		"confirmed":                  "已确认",
+		"cancelled":                  "已取消",
+		"completed":                  "已完成",
+		models.ReviewStatusSubmitted: "待审核",
+		models.ReviewStatusApproved:  "已通过",
+		models.ReviewStatusRejected:  "已驳回",
+		"yes":                        "是",
+		"no":                         "否",
+		models.BillStatusUnpaid:      "未支付",
+		models.BillStatusPaid:        "已支付",
+	},
+	ExportLangEN: {
+		"day":                        "Day",
+		"night":                      "Night",
+		"pending":                    "Pending",
+		"confirmed":                  "Confirmed",
+		"cancelled":                  "Cancelled",
+		"completed":                  "Completed",
+		models.ReviewStatusSubmitted: "Submitted",
+		models.ReviewStatusApproved:  "Approved",
+		models.ReviewStatusRejected:  "Rejected",
+		"yes":                        "Yes",
+		"no":                         "No",
+		models.BillStatusUnpaid:      "Unpaid",
+		models.BillStatusPaid:        "Paid",
+	},
+}
+
+// exportLabel 返回枚举值的本地化文本，未定义时原样返回
+func exportLabel(lang, value string) string {
+	if label, ok := exportValueLabels[lang][value]; ok {
+		return label
+	}
+	return value
+}
+
+// exportBool 返回布尔值的本地化文本
+func exportBool(lang string, value bool) string {
+	if value {
+		return exportLabel(lang, "yes")
+	}
+	return exportLabel(lang, "no")
+}
+
+// exportHeaders 返回指定语言的表头
+func exportHeaders(columns []exportColumn, lang string) []string {
+	headers := make([]string, 0, len(columns))
+	for _, column := range columns {
+		if lang == ExportLangEN {
+			headers = append(headers, column.EN)
+		} else {
+			headers = append(headers, column.ZH)
+		}
+	}
+	return headers
+}
+
+// exportPlaces 数值列保留的小数位数
+func exportPlaces(kind exportColumnKind) int32 {
+	switch kind {
+	case exportPrice:
+		return models.PricePlaces
+	case exportKWH:
+		return models.KWHPlaces
+	default:
+		return models.YuanPlaces
+	}
+}
+
+// exportWriter 逐行写出导出内容，XLSX 每个 sheet 独立表头，CSV 各部分以空行分隔
+type exportWriter interface {
+	StartSheet(name string, columns []exportColumn) error
+	WriteRow(values ...interface{}) error
+	Close() error
+}
+
+// newExportWriter 创建指定格式的导出写入器
+func newExportWriter(w io.Writer, format, lang string) (exportWriter, error) {
+	switch format {
+	case ExportFormatCSV:
+		// 写入 UTF-8 BOM，避免 Excel 打开中文乱码
+		if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+			return nil, err
+		}
+		return &csvExportWriter{writer: csv.NewWriter(w), lang: lang}, nil
+	case ExportFormatXLSX:
+		return newXLSXExportWriter(w, lang)
+	default:
+		return nil, errors.New("不支持的导出格式")
+	}
+}
+
+// csvFormulaPrefixes 以这些字符开头的文本会被 Excel 当作公式执行
+const csvFormulaPrefixes = "=+-@"
+
+// escapeCSVFormula 为可能被当作公式的文本加上单引号前缀，防止成员填写的备注、车牌等内容在 Excel 中执行
+func escapeCSVFormula(value string) string {
+	if value != "" && strings.ContainsRune(csvFormulaPrefixes, rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// csvExportWriter CSV 导出
+type csvExportWriter struct {
+	writer  *csv.Writer
+	lang    string
+	columns []exportColumn
+	started bool
+}
+
+func (w *csvExportWriter) StartSheet(name string, columns []exportColumn) error {
+	if w.started {
+		if err := w.writer.Write([]string{}); err != nil {
+			return err
+		}
+		if err := w.writer.Write([]string{name}); err != nil {
+			return err
+		}
+	}
+	w.started = true
+	w.columns = columns
+	return w.writer.Write(exportHeaders(columns, w.lang))
+}
+
+func (w *csvExportWriter) WriteRow(values ...interface{}) error {
+	row := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case decimal.Decimal:
+			row[i] = v.StringFixed(exportPlaces(w.columns[i].Kind))
+		case nil:
+			row[i] = ""
+		case string:
+			row[i] = escapeCSVFormula(strings.TrimSpace(v))
+		default:
+			row[i] = strings.TrimSpace(fmt.Sprint(v))
+		}
+	}
+	return w.writer.Write(row)
+}
+
+func (w *csvExportWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// xlsxExportWriter XLSX 导出，使用流式写入以控制内存占用
+type xlsxExportWriter struct {
+	out     io.Writer
+	file    *excelize.File
+	stream  *excelize.StreamWriter
+	lang    string
+	columns []exportColumn
+	row     int
+	sheets  int
+	styles  map[exportColumnKind]int
+	header  int
+}
+
+func newXLSXExportWriter(out io.Writer, lang string) (*xlsxExportWriter, error) {
+	file := excelize.NewFile()
+	w := &xlsxExportWriter{out: out, file: file, lang: lang, styles: make(map[exportColumnKind]int)}
+	formats := map[exportColumnKind]string{
+		exportKWH:   "0.00",
+		exportPrice: "0.0000",
+		exportYuan:  "0.00",
+	}
+	for kind, format := range formats {
+		numFmt := format
+		style, err := file.NewStyle(&excelize.Style{CustomNumFmt: &numFmt})
+		if err != nil {
+			return nil, err
+		}
+		w.styles[kind] = style
+	}
+	header, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Bold: true}})
+	if err != nil {
+		return nil, err
+	}
+	w.header = header
+	return w, nil
+}
+
+// xlsxSheetName 生成合法且不重复的 sheet 名称（最长31个字符）
+func (w *xlsxExportWriter) xlsxSheetName(name string) string {
+	name = strings.NewReplacer(":", "", "\\", "", "/", "", "?", "", "*", "", "[", "", "]", "").Replace(name)
+	if name == "" {
+		name = "Sheet"
+	}
+	if runes := []rune(name); len(runes) > 31 {
+		name = string(runes[:31])
+	}
+	candidate := name
+	for i := 2; ; i++ {
+		if index, _ := w.file.GetSheetIndex(candidate); index == -1 {
+			return candidate
+		}
+		suffix := "_" + strconv.Itoa(i)
+		runes := []rune(name)
+		if len(runes)+len(suffix) > 31 {
+			runes = runes[:31-len(suffix)]
+		}
+		candidate = string(runes) + suffix
+	}
+}
+
+func (w *xlsxExportWriter) StartSheet(name string, columns []exportColumn) error {
+	if err := w.flushSheet(); err != nil {
+		return err
+	}
+	name = w.xlsxSheetName(name)
+	if w.sheets == 0 {
+		// 复用新文件自带的默认 sheet
+		if err := w.file.SetSheetName("Sheet1", name); err != nil {
+			return err
+		}
+	} else {
+		if _, err := w.file.NewSheet(name); err != nil {
+			return err
+		}
+	}
+	w.sheets++
+	stream, err := w.file.NewStreamWriter(name)
+	if err != nil {
+		return err
+	}
+	if err := stream.SetColWidth(1, len(columns), 16); err != nil {
+		return err
+	}
+	headers := exportHeaders(columns, w.lang)
+	cells := make([]interface{}, len(headers))
+	for i, header := range headers {
+		cells[i] = excelize.Cell{StyleID: w.header, Value: header}
+	}
+	if err := stream.SetRow("A1", cells); err != nil {
+		return err
+	}
+	w.stream = stream
+	w.columns = columns
+	w.row = 1
+	return nil
+}
+
+func (w *xlsxExportWriter) WriteRow(values ...interface{}) error {
+	if w.stream == nil {
+		return errors.New("导出sheet未初始化")
+	}
+	w.row++
+	cells := make([]interface{}, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case decimal.Decimal:
+			rounded := v.Round(exportPlaces(w.columns[i].Kind))
+			cells[i] = excelize.Cell{StyleID: w.styles[w.columns[i].Kind], Value: rounded.InexactFloat64()}
+		case string:
+			// 文本显式写为内联字符串单元格，以 = + - @ 开头的内容不会被当作公式
+			cells[i] = excelize.Cell{Value: v}
+		default:
+			cells[i] = v
+		}
+	}
+	cell, err := excelize.CoordinatesToCellName(1, w.row)
+	if err != nil {
+		return err
+	}
+	return w.stream.SetRow(cell, cells)
+}
+
+func (w *xlsxExportWriter) flushSheet() error {
+	if w.stream == nil {
+		return nil
+	}
+	err := w.stream.Flush()
+	w.stream = nil
+	return err
+}
+
+func (w *xlsxExportWriter) Close() error {
+	defer w.file.Close()
+	if err := w.flushSheet(); err != nil {
+		return err
+	}
+	_, err := w.file.WriteTo(w.out)
+	return err
+}
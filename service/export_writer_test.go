@@ -0,0 +1,136 @@
+package service
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/xuri/excelize/v2"
+)
+
+// formulaInjectionValues 成员可填写的文本中可能被 Excel 当作公式执行的内容
+var formulaInjectionValues = []string{
+	"=HYPERLINK(\"http://evil\",\"x\")",
+	"+1+1",
+	"-2+3",
+	"@SUM(A1:A2)",
+}
+
+var exportTestColumns = []exportColumn{
+	{ZH: "备注", EN: "Remark", Kind: exportText},
+	{ZH: "度数", EN: "kWh", Kind: exportKWH},
+	{ZH: "次数", EN: "Count", Kind: exportInt},
+}
+
+func TestEscapeCSVFormula(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", ""},
+		{"粤B12345", "粤B12345"},
+		{"正常备注", "正常备注"},
+		{"=1+1", "'=1+1"},
+		{"+86 138", "'+86 138"},
+		{"-5", "'-5"},
+		{"@cmd", "'@cmd"},
+		{"a=b", "a=b"},
+	}
+	for _, tt := range tests {
+		if got := escapeCSVFormula(tt.in); got != tt.want {
+			t.Errorf("escapeCSVFormula(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCSVExportWriterEscapesFormulas(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newExportWriter(&buf, ExportFormatCSV, ExportLangZH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.StartSheet("充电记录", exportTestColumns); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range formulaInjectionValues {
+		if err := writer.WriteRow(value, decimal.RequireFromString("-1.5"), -3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(strings.TrimPrefix(buf.String(), "\xEF\xBB\xBF"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != len(formulaInjectionValues)+1 {
+		t.Fatalf("行数 = %d, want %d", len(rows), len(formulaInjectionValues)+1)
+	}
+	for i, value := range formulaInjectionValues {
+		row := rows[i+1]
+		if row[0] != "'"+value {
+			t.Errorf("文本列 = %q, want %q", row[0], "'"+value)
+		}
+		// 数值列不受影响
+		if row[1] != "-1.50" || row[2] != "-3" {
+			t.Errorf("数值列 = %q, %q, want -1.50, -3", row[1], row[2])
+		}
+	}
+}
+
+func TestXLSXExportWriterWritesTextAsString(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newExportWriter(&buf, ExportFormatXLSX, ExportLangZH)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.StartSheet("充电记录", exportTestColumns); err != nil {
+		t.Fatal(err)
+	}
+	for _, value := range formulaInjectionValues {
+		if err := writer.WriteRow(value, decimal.RequireFromString("-1.5"), -3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	for i, value := range formulaInjectionValues {
+		cell, _ := excelize.CoordinatesToCellName(1, i+2)
+		formula, err := file.GetCellFormula("充电记录", cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if formula != "" {
+			t.Errorf("%s 不应包含公式，实际为 %q", cell, formula)
+		}
+		cellType, err := file.GetCellType("充电记录", cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cellType != excelize.CellTypeInlineString {
+			t.Errorf("%s 类型 = %v, want 内联字符串", cell, cellType)
+		}
+		got, err := file.GetCellValue("充电记录", cell)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != value {
+			t.Errorf("%s = %q, want %q", cell, got, value)
+		}
+		numCell, _ := excelize.CoordinatesToCellName(2, i+2)
+		if numType, _ := file.GetCellType("充电记录", numCell); numType == excelize.CellTypeInlineString {
+			t.Errorf("%s 数值列不应写为字符串", numCell)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// forecastZScore 预测区间使用的正态分位数，对应约80%的置信区间
+const forecastZScore = 1.28
+
+// forecastConfidence 预测区间的置信度(%)
+const forecastConfidence = 80
+
+// forecastHistoryDays 计算平均每次充电度数时回溯的天数
+const forecastHistoryDays = 90
+
+// forecastMinSessions 成员历史充电次数少于该值时改用全体成员的平均值
+const forecastMinSessions = 3
+
+// 平均每次充电度数的来源
+const (
+	forecastSourceMember = "member"
+	forecastSourceGroup  = "group"
+	forecastSourceNone   = "none"
+)
+
+// sessionHistory 历史单次充电度数的均值与标准差
+type sessionHistory struct {
+	UserID       uint
+	AvgKwh       decimal.Decimal
+	StddevKwh    decimal.Decimal
+	SessionCount int64
+}
+
+// expectedSession 本月尚未上传充电记录的有效预约
+type expectedSession struct {
+	UserID uint
+	Date   time.Time
+}
+
+// memberForecast 单个成员的月末用电量与费用预测，金额单位为分
+type memberForecast struct {
+	UserID           uint
+	UserName         string
+	ActualKwh        decimal.Decimal
+	ActualAmount     int64
+	ActualSessions   int64
+	ExpectedSessions int64
+	AvgKwh           decimal.Decimal
+	StddevKwh        decimal.Decimal
+	AvgSource        string
+	UnitPrice        decimal.Decimal
+	PriceChanges     []models.UnitPriceSchedule
+	ExtraKwh         decimal.Decimal
+	ExtraAmount      int64
+	Variance         float64
+}
+
+// ProjectedKwh 预测月末用电量
+func (f memberForecast) ProjectedKwh() decimal.Decimal {
+	return f.ActualKwh.Add(f.ExtraKwh)
+}
+
+// ProjectedAmount 预测月末费用(分)
+func (f memberForecast) ProjectedAmount() int64 {
+	return f.ActualAmount + f.ExtraAmount
+}
+
+// forecastRange 在已发生部分之上按方差计算预测区间，下限不低于已发生值
+func forecastRange(actualKwh, extraKwh decimal.Decimal, actualAmount, extraAmount int64, variance float64) map[string]interface{} {
+	margin := models.RoundDecimal(decimal.NewFromFloat(forecastZScore*math.Sqrt(variance)), models.KWHPlaces)
+	lowExtra := decimal.Max(extraKwh.Sub(margin), decimal.Zero)
+	highExtra := extraKwh.Add(margin)
+	// 预测部分的平均单价，用于将度数区间换算为费用区间
+	lowAmount, highAmount := actualAmount+extraAmount, actualAmount+extraAmount
+	if extraKwh.IsPositive() {
+		pricePerKwh := decimal.NewFromInt(extraAmount).Div(extraKwh)
+		lowAmount = actualAmount + models.RoundDecimal(lowExtra.Mul(pricePerKwh), 0).IntPart()
+		highAmount = actualAmount + models.RoundDecimal(highExtra.Mul(pricePerKwh), 0).IntPart()
+	}
+	return map[string]interface{}{
+		"kwh_low":     actualKwh.Add(lowExtra),
+		"kwh_high":    actualKwh.Add(highExtra),
+		"amount_low":  models.FenToYuan(lowAmount),
+		"amount_high": models.FenToYuan(highAmount),
+		"confidence":  forecastConfidence,
+	}
+}
+
+// querySessionHistory 统计历史单次充电度数的均值与标准差
+// groupByUser 为 false 时返回全体成员合并后的一行
+func querySessionHistory(userID uint, since string, groupByUser bool) ([]sessionHistory, error) {
+	selectUser := "0 as user_id,"
+	if groupByUser {
+		selectUser = "records.user_id,"
+	}
+	query := models.DB.Model(&models.Record{}).Scopes(models.ActiveRecords).
+		Select(selectUser+`
+			COALESCE(AVG(records.kwh), 0) as avg_kwh,
+			COALESCE(STDDEV_SAMP(records.kwh), 0) as stddev_kwh,
+			COUNT(*) as session_count
+		`).
+		Where("records.date >= ?", since)
+	if userID != 0 {
+		query = query.Where("records.user_id = ?", userID)
+	}
+	if groupByUser {
+		query = query.Group("records.user_id")
+	}
+	var rows []sessionHistory
+	err := query.Scan(&rows).Error
+	return rows, err
+}
+
+// queryExpectedSessions 查询日期范围内未取消且尚未上传充电记录的预约，视为还会发生的充电
+func queryExpectedSessions(userID uint, startDate, endDate string) ([]expectedSession, error) {
+	query := models.DB.Model(&models.Reservation{}).
+		Select("reservations.user_id, reservations.date").
+		Where("reservations.status != ? AND reservations.date >= ? AND reservations.date <= ?", "cancelled", startDate, endDate).
+		Where(noActiveRecordCondition)
+	if userID != 0 {
+		query = query.Where("reservations.user_id = ?", userID)
+	}
+	var rows []expectedSession
+	err := query.Order("reservations.date ASC").Scan(&rows).Error
+	return rows, err
+}
+
+// buildForecasts 计算月末预测：已发生部分读取按月统计表，未上传的预约按平均每次度数和当日适用电价估算
+// userID 为 0 时计算本月有记录或预约的全部成员
+func buildForecasts(month string, userID uint) ([]memberForecast, error) {
+	startDate, endDate, err := getMonthDateRange(month)
+	if err != nil {
+		return nil, errors.New("月份格式错误")
+	}
+	if err := applyDuePriceSchedules(userID); err != nil {
+		return nil, err
+	}
+
+	statsQuery := models.DB.Where("month = ?", month)
+	if userID != 0 {
+		statsQuery = statsQuery.Where("user_id = ?", userID)
+	}
+	var stats []models.UserMonthStat
+	if err := statsQuery.Find(&stats).Error; err != nil {
+		return nil, err
+	}
+	sessions, err := queryExpectedSessions(userID, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+	since := time.Now().AddDate(0, 0, -forecastHistoryDays).Format("2006-01-02")
+	histories, err := querySessionHistory(userID, since, true)
+	if err != nil {
+		return nil, err
+	}
+	groupHistory, err := querySessionHistory(0, since, false)
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := pendingPriceSchedules(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	forecasts := make(map[uint]*memberForecast)
+	var order []uint
+	forecastFor := func(id uint) *memberForecast {
+		if f, ok := forecasts[id]; ok {
+			return f
+		}
+		forecasts[id] = &memberForecast{UserID: id, AvgSource: forecastSourceNone}
+		order = append(order, id)
+		return forecasts[id]
+	}
+	if userID != 0 {
+		forecastFor(userID)
+	}
+	for _, stat := range stats {
+		f := forecastFor(stat.UserID)
+		f.ActualKwh = stat.TotalKwh
+		f.ActualAmount = stat.TotalAmount
+		f.ActualSessions = stat.RecordCount
+	}
+	sessionDates := make(map[uint][]time.Time)
+	for _, session := range sessions {
+		forecastFor(session.UserID).ExpectedSessions++
+		sessionDates[session.UserID] = append(sessionDates[session.UserID], session.Date)
+	}
+	historyMap := make(map[uint]sessionHistory, len(histories))
+	for _, history := range histories {
+		historyMap[history.UserID] = history
+	}
+	scheduleMap := make(map[uint][]models.UnitPriceSchedule)
+	for _, schedule := range schedules {
+		scheduleMap[schedule.UserID] = append(scheduleMap[schedule.UserID], schedule)
+	}
+	var users []models.User
+	if len(order) > 0 {
+		if err := models.DB.Select("id, name, unit_price").Where("id IN ?", order).Find(&users).Error; err != nil {
+			return nil, err
+		}
+	}
+	userMap := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		userMap[user.ID] = user
+	}
+
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+	result := make([]memberForecast, 0, len(order))
+	for _, id := range order {
+		f := forecasts[id]
+		f.UserName = userMap[id].Name
+		f.UnitPrice = GetUserUnitPrice(userMap[id])
+		for _, schedule := range scheduleMap[id] {
+			if day := schedule.EffectiveDate.Format("2006-01-02"); day >= startDate && day <= endDate {
+				f.PriceChanges = append(f.PriceChanges, schedule)
+			}
+		}
+		if history, ok := historyMap[id]; ok && history.SessionCount >= forecastMinSessions {
+			f.AvgKwh, f.StddevKwh, f.AvgSource = history.AvgKwh, history.StddevKwh, forecastSourceMember
+		} else if len(groupHistory) > 0 && groupHistory[0].SessionCount > 0 {
+			f.AvgKwh, f.StddevKwh, f.AvgSource = groupHistory[0].AvgKwh, groupHistory[0].StddevKwh, forecastSourceGroup
+		}
+		f.AvgKwh = models.RoundDecimal(f.AvgKwh, models.KWHPlaces)
+		f.StddevKwh = models.RoundDecimal(f.StddevKwh, models.KWHPlaces)
+		// 每个预约按其日期适用的电价估算费用
+		for _, date := range sessionDates[id] {
+			f.ExtraKwh = f.ExtraKwh.Add(f.AvgKwh)
+			f.ExtraAmount += models.CalculateAmountFen(f.AvgKwh, unitPriceOn(f.UnitPrice, scheduleMap[id], date))
+		}
+		sd := f.StddevKwh.InexactFloat64()
+		f.Variance = float64(f.ExpectedSessions) * sd * sd
+		result = append(result, *f)
+	}
+	return result, nil
+}
+
+// formatMemberForecast 格式化单个成员的预测
+func formatMemberForecast(f memberForecast) map[string]interface{} {
+	priceChanges := make([]map[string]interface{}, 0, len(f.PriceChanges))
+	for _, schedule := range f.PriceChanges {
+		priceChanges = append(priceChanges, map[string]interface{}{
+			"effective_date": schedule.EffectiveDate.Format("2006-01-02"),
+			"unit_price":     schedule.UnitPrice,
+		})
+	}
+	return map[string]interface{}{
+		"user_id":           f.UserID,
+		"user_name":         f.UserName,
+		"actual_kwh":        f.ActualKwh,
+		"actual_amount":     models.FenToYuan(f.ActualAmount),
+		"actual_sessions":   f.ActualSessions,
+		"expected_sessions": f.ExpectedSessions,
+		"avg_kwh":           f.AvgKwh,
+		"stddev_kwh":        f.StddevKwh,
+		"avg_source":        f.AvgSource,
+		"projected_kwh":     f.ProjectedKwh(),
+		"projected_amount":  models.FenToYuan(f.ProjectedAmount()),
+		"range":             forecastRange(f.ActualKwh, f.ExtraKwh, f.ActualAmount, f.ExtraAmount, f.Variance),
+		"unit_price":        f.UnitPrice,
+		"price_changes":     priceChanges,
+	}
+}
+
+// GetMemberForecast 预测成员本月月末的用电量和费用
+// 已发生部分按实际记录（含优惠）；未上传的预约按平均每次度数和当日适用电价估算，不计优惠
+func GetMemberForecast(c *gin.Context, userID uint, month string) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "查询月末预测: user_id=%d, month=%s", userID, month)
+	forecasts, err := buildForecasts(month, userID)
+	if err != nil {
+		utils.ErrorCtx(c, "计算月末预测失败: %v", err)
+		return nil, err
+	}
+	result := formatMemberForecast(forecasts[0])
+	result["month"] = month
+	return result, nil
+}
+
+// GetGroupForecast 预测全体成员本月月末的用电量和费用
+// 成员视图只包含总用电量预测；管理员(detailed)额外包含费用和按成员明细
+func GetGroupForecast(c *gin.Context, month string, detailed bool) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "查询群组月末预测: month=%s, detailed=%v", month, detailed)
+	forecasts, err := buildForecasts(month, 0)
+	if err != nil {
+		utils.ErrorCtx(c, "计算群组月末预测失败: %v", err)
+		return nil, err
+	}
+	var total memberForecast
+	for _, f := range forecasts {
+		total.ActualKwh = total.ActualKwh.Add(f.ActualKwh)
+		total.ActualAmount += f.ActualAmount
+		total.ActualSessions += f.ActualSessions
+		total.ExpectedSessions += f.ExpectedSessions
+		total.ExtraKwh = total.ExtraKwh.Add(f.ExtraKwh)
+		total.ExtraAmount += f.ExtraAmount
+		total.Variance += f.Variance
+	}
+	ranges := forecastRange(total.ActualKwh, total.ExtraKwh, total.ActualAmount, total.ExtraAmount, total.Variance)
+	result := map[string]interface{}{
+		"month":             month,
+		"actual_kwh":        total.ActualKwh,
+		"actual_sessions":   total.ActualSessions,
+		"expected_sessions": total.ExpectedSessions,
+		"projected_kwh":     total.ProjectedKwh(),
+		"member_count":      len(forecasts),
+	}
+	if !detailed {
+		// 成员视图不包含费用和其他成员的明细
+		delete(ranges, "amount_low")
+		delete(ranges, "amount_high")
+		result["range"] = ranges
+		return result, nil
+	}
+	result["range"] = ranges
+	result["actual_amount"] = models.FenToYuan(total.ActualAmount)
+	result["projected_amount"] = models.FenToYuan(total.ProjectedAmount())
+	members := make([]map[string]interface{}, 0, len(forecasts))
+	for _, f := range forecasts {
+		members = append(members, formatMemberForecast(f))
+	}
+	result["members"] = members
+	return result, nil
+}
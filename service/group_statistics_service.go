@@ -0,0 +1,153 @@
+package service
+
+import (
+	"errors"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// slotsPerDay 每天可预约的时段数（白班、夜班）
+const slotsPerDay = 2
+
+// slotUtilization 月度时段使用情况
+type slotUtilization struct {
+	TotalReservations     int64
+	CancelledReservations int64
+	BookedSlots           int64
+	UsedSlots             int64
+	ActiveMembers         int64
+}
+
+// percentOf 计算百分比，保留两位小数
+func percentOf(part, total int64) decimal.Decimal {
+	if total == 0 {
+		return decimal.Zero
+	}
+	return decimal.NewFromInt(part).Mul(decimal.NewFromInt(100)).DivRound(decimal.NewFromInt(total), 2)
+}
+
+// querySlotUtilization 单次查询统计预约、已预约时段、已使用时段和活跃成员数
+func querySlotUtilization(startDate, endDate string) (slotUtilization, error) {
+	var result slotUtilization
+	err := models.DB.Model(&models.Reservation{}).
+		Select(`
+			COUNT(*) as total_reservations,
+			COUNT(*) FILTER (WHERE reservations.status = 'cancelled') as cancelled_reservations,
+			COUNT(DISTINCT (reservations.date, reservations.timeslot)) FILTER (WHERE reservations.status != 'cancelled') as booked_slots,
+			COUNT(DISTINCT (reservations.date, reservations.timeslot)) FILTER (WHERE reservations.status != 'cancelled' AND `+activeRecordExistsCondition+`) as used_slots,
+			COUNT(DISTINCT reservations.user_id) FILTER (WHERE reservations.status != 'cancelled') as active_members
+		`).
+		Where("reservations.date >= ? AND reservations.date <= ?", startDate, endDate).
+		Scan(&result).Error
+	return result, err
+}
+
+// GetGroupStatistics 获取全体成员的月度统计
+// 管理员(detailed)可看到费用和按成员明细；成员仅看到用电量、时段使用率以及自己的占比
+func GetGroupStatistics(c *gin.Context, month string, viewerID uint, detailed bool) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "查询群组统计: month=%s, viewer_id=%d, detailed=%v", month, viewerID, detailed)
+	startDate, endDate, err := getMonthDateRange(month)
+	if err != nil {
+		return nil, errors.New("月份格式错误")
+	}
+
+	rows, err := aggregateShiftStatistics(0, startDate, endDate, shiftGroupUser)
+	if err != nil {
+		utils.ErrorCtx(c, "查询群组用电量失败: %v", err)
+		return nil, err
+	}
+	utilization, err := querySlotUtilization(startDate, endDate)
+	if err != nil {
+		utils.ErrorCtx(c, "查询时段使用率失败: %v", err)
+		return nil, err
+	}
+
+	var total ShiftAggregate
+	var viewer ShiftAggregate
+	for _, row := range rows {
+		total.DayKwh = total.DayKwh.Add(row.DayKwh)
+		total.NightKwh = total.NightKwh.Add(row.NightKwh)
+		total.TotalAmount += row.TotalAmount
+		total.RecordCount += row.RecordCount
+		if row.UserID == viewerID {
+			viewer = row
+		}
+	}
+
+	monthStart, _ := time.Parse("2006-01-02", startDate)
+	totalSlots := int64(monthStart.AddDate(0, 1, -1).Day() * slotsPerDay)
+	totalKwh := total.TotalKwh()
+	result := map[string]interface{}{
+		"month":        month,
+		"total_kwh":    totalKwh,
+		"day_kwh":      total.DayKwh,
+		"night_kwh":    total.NightKwh,
+		"day_ratio":    percentOfDecimal(total.DayKwh, totalKwh),
+		"night_ratio":  percentOfDecimal(total.NightKwh, totalKwh),
+		"record_count": total.RecordCount,
+		"slots": map[string]interface{}{
+			"total":       totalSlots,
+			"booked":      utilization.BookedSlots,
+			"used":        utilization.UsedSlots,
+			"booked_rate": percentOf(utilization.BookedSlots, totalSlots),
+			"used_rate":   percentOf(utilization.UsedSlots, totalSlots),
+		},
+		"reservations": map[string]interface{}{
+			"total":       utilization.TotalReservations,
+			"cancelled":   utilization.CancelledReservations,
+			"cancel_rate": percentOf(utilization.CancelledReservations, utilization.TotalReservations),
+		},
+		"active_members": utilization.ActiveMembers,
+	}
+
+	if !detailed {
+		// 成员视图不包含费用和其他成员的明细
+		result["my_kwh"] = viewer.TotalKwh()
+		result["my_share"] = percentOfDecimal(viewer.TotalKwh(), totalKwh)
+		return result, nil
+	}
+
+	result["total_amount"] = models.FenToYuan(total.TotalAmount)
+	userIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		userIDs = append(userIDs, row.UserID)
+	}
+	var users []models.User
+	if len(userIDs) > 0 {
+		if err := models.DB.Select("id, name, avatar").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return nil, err
+		}
+	}
+	userMap := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		userMap[user.ID] = user
+	}
+	members := make([]map[string]interface{}, 0, len(rows))
+	for _, row := range rows {
+		members = append(members, map[string]interface{}{
+			"user_id":      row.UserID,
+			"user_name":    userMap[row.UserID].Name,
+			"avatar":       userMap[row.UserID].Avatar,
+			"total_kwh":    row.TotalKwh(),
+			"day_kwh":      row.DayKwh,
+			"night_kwh":    row.NightKwh,
+			"total_amount": models.FenToYuan(row.TotalAmount),
+			"record_count": row.RecordCount,
+			"share":        percentOfDecimal(row.TotalKwh(), totalKwh),
+		})
+	}
+	result["members"] = members
+	return result, nil
+}
+
+// percentOfDecimal 计算度数占比，保留两位小数
+func percentOfDecimal(part, total decimal.Decimal) decimal.Decimal {
+	if total.IsZero() {
+		return decimal.Zero
+	}
+	return part.Mul(decimal.NewFromInt(100)).DivRound(total, 2)
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// heatmapTimeslots 热力图的时段顺序
+var heatmapTimeslots = []string{"day", "night"}
+
+// heatmapReservationRow 按星期与时段汇总的预约情况
+type heatmapReservationRow struct {
+	Weekday           int
+	Timeslot          string
+	TotalReservations int64
+	Cancelled         int64
+	BookedSlots       int64
+	Ended             int64
+	NoShows           int64
+}
+
+// heatmapRecordRow 按星期与时段汇总的充电记录
+type heatmapRecordRow struct {
+	Weekday     int
+	Timeslot    string
+	TotalKwh    decimal.Decimal
+	RecordCount int64
+}
+
+// heatmapKey 星期(1=周一 … 7=周日)与时段
+type heatmapKey struct {
+	Weekday  int
+	Timeslot string
+}
+
+// queryHeatmapReservations 按星期与时段统计预约数、取消数、已预约时段数和爽约数
+// 爽约指时段已结束、未取消且没有未作废充电记录的预约
+func queryHeatmapReservations(startDate, endDate string, now time.Time) ([]heatmapReservationRow, error) {
+	endedCondition := "(reservations.date + CASE WHEN reservations.timeslot = 'day' THEN INTERVAL '20 hours' ELSE INTERVAL '32 hours' END) <= @now"
+	var rows []heatmapReservationRow
+	err := models.DB.Raw(`
+		SELECT
+			EXTRACT(ISODOW FROM reservations.date)::int as weekday,
+			reservations.timeslot,
+			COUNT(*) as total_reservations,
+			COUNT(*) FILTER (WHERE reservations.status = 'cancelled') as cancelled,
+			COUNT(DISTINCT reservations.date) FILTER (WHERE reservations.status != 'cancelled') as booked_slots,
+			COUNT(*) FILTER (WHERE reservations.status != 'cancelled' AND `+endedCondition+`) as ended,
+			COUNT(*) FILTER (WHERE reservations.status != 'cancelled' AND `+endedCondition+` AND `+noActiveRecordCondition+`) as no_shows
+		FROM reservations
+		WHERE reservations.deleted_at IS NULL AND reservations.date >= @start AND reservations.date <= @end
+		GROUP BY 1, 2`,
+		map[string]interface{}{"start": startDate, "end": endDate, "now": now.Format("2006-01-02 15:04:05")},
+	).Scan(&rows).Error
+	return rows, err
+}
+
+// queryHeatmapRecords 按星期与时段统计未作废充电记录的用电量
+// 关联预约的记录按预约日期归入格子（夜班记录常记在次日），与同一格的预约率、爽约率口径一致
+func queryHeatmapRecords(startDate, endDate string) ([]heatmapRecordRow, error) {
+	var rows []heatmapRecordRow
+	err := models.DB.Model(&models.Record{}).Scopes(models.ActiveRecords).
+		Select(`
+			EXTRACT(ISODOW FROM COALESCE(r.date, records.date))::int as weekday,
+			COALESCE(r.timeslot, records.timeslot) as timeslot,
+			COALESCE(SUM(records.kwh), 0) as total_kwh,
+			COUNT(*) as record_count
+		`).
+		Joins("LEFT JOIN reservations r ON records.reservation_id = r.id").
+		Where("COALESCE(r.date, records.date) >= ? AND COALESCE(r.date, records.date) <= ?", startDate, endDate).
+		Group("1, 2").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// GetUtilizationHeatmap 星期 × 时段的使用率热力图
+// 每格包含可预约时段数、预约率、取消率、爽约率和平均每次充电度数；当前没有候补功能，waitlist_count 为 null
+func GetUtilizationHeatmap(c *gin.Context, r StatisticsRange) (map[string]interface{}, error) {
+	startDate, endDate := r.From.Format("2006-01-02"), r.To.Format("2006-01-02")
+	utils.InfoCtx(c, "查询使用率热力图: from=%s, to=%s", startDate, endDate)
+	reservationRows, err := queryHeatmapReservations(startDate, endDate, time.Now().In(r.Location))
+	if err != nil {
+		utils.ErrorCtx(c, "查询预约热力图失败: %v", err)
+		return nil, err
+	}
+	recordRows, err := queryHeatmapRecords(startDate, endDate)
+	if err != nil {
+		utils.ErrorCtx(c, "查询充电记录热力图失败: %v", err)
+		return nil, err
+	}
+
+	// 范围内每个星期几出现的天数，即该星期每个时段可预约的次数
+	availableDays := make(map[int]int64, 7)
+	for day := r.From; !day.After(r.To); day = day.AddDate(0, 0, 1) {
+		availableDays[isoWeekday(day)]++
+	}
+	reservationMap := make(map[heatmapKey]heatmapReservationRow, len(reservationRows))
+	for _, row := range reservationRows {
+		reservationMap[heatmapKey{row.Weekday, row.Timeslot}] = row
+	}
+	recordMap := make(map[heatmapKey]heatmapRecordRow, len(recordRows))
+	for _, row := range recordRows {
+		recordMap[heatmapKey{row.Weekday, row.Timeslot}] = row
+	}
+
+	cells := make([]map[string]interface{}, 0, 7*len(heatmapTimeslots))
+	for weekday := 1; weekday <= 7; weekday++ {
+		for _, timeslot := range heatmapTimeslots {
+			key := heatmapKey{weekday, timeslot}
+			reservation := reservationMap[key]
+			record := recordMap[key]
+			avgKwh := decimal.Zero
+			if record.RecordCount > 0 {
+				avgKwh = record.TotalKwh.DivRound(decimal.NewFromInt(record.RecordCount), models.KWHPlaces)
+			}
+			cells = append(cells, map[string]interface{}{
+				"weekday":        weekday,
+				"timeslot":       timeslot,
+				"available":      availableDays[weekday],
+				"reservations":   reservation.TotalReservations,
+				"booked":         reservation.BookedSlots,
+				"cancelled":      reservation.Cancelled,
+				"no_shows":       reservation.NoShows,
+				"booking_rate":   percentOf(reservation.BookedSlots, availableDays[weekday]),
+				"cancel_rate":    percentOf(reservation.Cancelled, reservation.TotalReservations),
+				"no_show_rate":   percentOf(reservation.NoShows, reservation.Ended),
+				"record_count":   record.RecordCount,
+				"total_kwh":      record.TotalKwh,
+				"avg_kwh":        avgKwh,
+				"waitlist_count": nil,
+			})
+		}
+	}
+	return map[string]interface{}{
+		"from":               startDate,
+		"to":                 endDate,
+		"timeslots":          heatmapTimeslots,
+		"cells":              cells,
+		"waitlist_available": false,
+	}, nil
+}
+
+// isoWeekday ISO星期几，周一为1，周日为7
+func isoWeekday(t time.Time) int {
+	return (int(t.Weekday())+6)%7 + 1
+}
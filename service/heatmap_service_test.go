@@ -0,0 +1,24 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestQueryHeatmapRecordsBucketsByReservationDate(t *testing.T) {
+	mock := setupMockDB(t)
+	// 星期与日期范围都按预约日期，没有关联预约时才使用记录日期
+	mock.ExpectQuery(`EXTRACT\(ISODOW FROM COALESCE\(r.date, records.date\)\)::int as weekday.*LEFT JOIN reservations r ON records.reservation_id = r.id WHERE .*COALESCE\(r.date, records.date\) >= \$1 AND COALESCE\(r.date, records.date\) <= \$2`).
+		WithArgs("2024-05-01", "2024-05-31").
+		WillReturnRows(sqlmock.NewRows([]string{"weekday", "timeslot", "total_kwh", "record_count"}).
+			AddRow(5, "night", "18.5", 2))
+
+	rows, err := queryHeatmapRecords("2024-05-01", "2024-05-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].Weekday != 5 || rows[0].Timeslot != "night" || rows[0].RecordCount != 2 {
+		t.Fatalf("结果不正确: %+v", rows)
+	}
+}
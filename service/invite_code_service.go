@@ -0,0 +1,378 @@
+package service
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"regexp"
+	"shared-charge/config"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationAccountApproved 账号审核通过通知
+const NotificationAccountApproved = "account_approved"
+
+// inviteCodeAlphabet 自动生成邀请码使用的字符，去掉了容易混淆的 0/O、1/I/L
+const inviteCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+// inviteCodeLength 自动生成邀请码的长度
+const inviteCodeLength = 8
+
+// inviteCodePattern 自定义邀请码格式
+var inviteCodePattern = regexp.MustCompile(`^[A-Z0-9]{4,32}$`)
+
+// errInvalidInviteCode 邀请码不存在、已停用、已过期或次数已用完
+var errInvalidInviteCode = errors.New("邀请码无效或已过期")
+
+// InviteCodeInput 创建邀请码参数
+type InviteCodeInput struct {
+	Code       string
+	Remark     string
+	ExpiresOn  string
+	MaxUses    int
+	UnitPrice  *decimal.Decimal
+	CanReserve bool
+}
+
+// normalizeInviteCode 邀请码不区分大小写，统一转为大写
+func normalizeInviteCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// generateInviteCode 生成随机邀请码
+func generateInviteCode() (string, error) {
+	var sb strings.Builder
+	alphabetSize := big.NewInt(int64(len(inviteCodeAlphabet)))
+	for i := 0; i < inviteCodeLength; i++ {
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(inviteCodeAlphabet[n.Int64()])
+	}
+	return sb.String(), nil
+}
+
+// formatInviteCode 格式化邀请码
+func formatInviteCode(code models.InviteCode) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          code.ID,
+		"code":        code.Code,
+		"remark":      code.Remark,
+		"expires_at":  code.ExpiresAt,
+		"max_uses":    code.MaxUses,
+		"used_count":  code.UsedCount,
+		"uses_left":   code.UsesLeft(),
+		"unit_price":  code.UnitPrice,
+		"can_reserve": code.CanReserve,
+		"disabled_at": code.DisabledAt,
+		"usable":      code.IsUsable(time.Now()),
+		"created_by":  code.CreatedBy,
+		"created_at":  code.CreatedAt,
+	}
+}
+
+// CreateInviteCode 管理员创建邀请码，未指定 Code 时自动生成；ExpiresOn 为最后可用日期（含当天）
+func CreateInviteCode(c *gin.Context, adminID uint, input InviteCodeInput) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "创建邀请码: admin_id=%d, expires_on=%s, max_uses=%d, can_reserve=%t", adminID, input.ExpiresOn, input.MaxUses, input.CanReserve)
+	if input.MaxUses < 0 {
+		return nil, errors.New("可用次数不能为负数")
+	}
+	if input.UnitPrice != nil {
+		if !input.UnitPrice.IsPositive() {
+			return nil, errors.New("电价必须为正数")
+		}
+		price := models.RoundDecimal(*input.UnitPrice, models.PricePlaces)
+		input.UnitPrice = &price
+	}
+	code := models.InviteCode{
+		Remark:     input.Remark,
+		MaxUses:    input.MaxUses,
+		UnitPrice:  input.UnitPrice,
+		CanReserve: input.CanReserve,
+		CreatedBy:  adminID,
+	}
+	if input.ExpiresOn != "" {
+		date, err := time.ParseInLocation("2006-01-02", input.ExpiresOn, time.Local)
+		if err != nil {
+			return nil, errors.New("日期格式错误，应为YYYY-MM-DD")
+		}
+		if input.ExpiresOn < time.Now().Format("2006-01-02") {
+			return nil, errors.New("过期日期不能早于今天")
+		}
+		expiresAt := date.AddDate(0, 0, 1)
+		code.ExpiresAt = &expiresAt
+	}
+	if input.Code != "" {
+		code.Code = normalizeInviteCode(input.Code)
+		if !inviteCodePattern.MatchString(code.Code) {
+			return nil, errors.New("邀请码只能包含4-32位字母或数字")
+		}
+	} else {
+		generated, err := generateInviteCode()
+		if err != nil {
+			return nil, err
+		}
+		code.Code = generated
+	}
+
+	var exists int64
+	if err := models.DB.Unscoped().Model(&models.InviteCode{}).Where("code = ?", code.Code).Count(&exists).Error; err != nil {
+		return nil, err
+	}
+	if exists > 0 {
+		return nil, errors.New("邀请码已存在")
+	}
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&code).Error; err != nil {
+			return err
+		}
+		return writeAudit(c, tx, AuditInviteCodeCreate, AuditTargetInviteCode, code.ID, nil, formatInviteCode(code))
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "创建邀请码失败: %v", err)
+		return nil, err
+	}
+	return formatInviteCode(code), nil
+}
+
+// GetInviteCodes 管理员查看邀请码，最新创建的在前
+func GetInviteCodes(c *gin.Context) ([]map[string]interface{}, error) {
+	var codes []models.InviteCode
+	if err := models.DB.Order("id DESC").Find(&codes).Error; err != nil {
+		utils.ErrorCtx(c, "查询邀请码失败: %v", err)
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(codes))
+	for _, code := range codes {
+		result = append(result, formatInviteCode(code))
+	}
+	return result, nil
+}
+
+// DisableInviteCode 管理员停用邀请码，已兑换的用户不受影响
+func DisableInviteCode(c *gin.Context, id uint) error {
+	utils.InfoCtx(c, "停用邀请码: id=%d", id)
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.InviteCode{}).
+			Where("id = ? AND disabled_at IS NULL", id).
+			Update("disabled_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("邀请码不存在或已停用")
+		}
+		return writeAudit(c, tx, AuditInviteCodeDisable, AuditTargetInviteCode, id,
+			map[string]interface{}{"disabled_at": nil}, map[string]interface{}{"disabled_at": now})
+	})
+}
+
+// redeemInviteCode 在事务内锁定并兑换邀请码，使用次数加一
+func redeemInviteCode(tx *gorm.DB, rawCode string) (models.InviteCode, error) {
+	var code models.InviteCode
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ?", normalizeInviteCode(rawCode)).
+		First(&code).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return code, errInvalidInviteCode
+		}
+		return code, err
+	}
+	if !code.IsUsable(time.Now()) {
+		return code, errInvalidInviteCode
+	}
+	code.UsedCount++
+	if err := tx.Model(&code).Update("used_count", code.UsedCount).Error; err != nil {
+		return code, err
+	}
+	return code, nil
+}
+
+// applyInviteCode 按邀请码设置用户为正常成员，电价为空时使用默认电价
+func applyInviteCode(user *models.User, code models.InviteCode, now time.Time) {
+	user.Status = models.UserStatusActive
+	user.CanReserve = code.CanReserve
+	user.UnitPrice = config.GetConfig().App.DefaultUnitPrice
+	if code.UnitPrice != nil {
+		user.UnitPrice = *code.UnitPrice
+	}
+	user.InviteCodeID = &code.ID
+	user.ApprovedAt = &now
+	user.ApprovedBy = nil
+}
+
+// inviteRedeemAudit 兑换邀请码后的用户设置，写入审计日志
+func inviteRedeemAudit(user models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"status":         user.Status,
+		"invite_code_id": user.InviteCodeID,
+		"unit_price":     user.UnitPrice,
+		"can_reserve":    user.CanReserve,
+	}
+}
+
+// RedeemInviteCodeForUser 待审核用户登录时兑换邀请码，兑换成功后直接成为正常成员
+func RedeemInviteCodeForUser(c *gin.Context, user *models.User, rawCode string) error {
+	utils.InfoCtx(c, "待审核用户兑换邀请码: user_id=%d", user.ID)
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var locked models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, user.ID).Error; err != nil {
+			return err
+		}
+		if locked.Status != models.UserStatusPendingApproval {
+			*user = locked
+			return nil
+		}
+		code, err := redeemInviteCode(tx, rawCode)
+		if err != nil {
+			return err
+		}
+		applyInviteCode(&locked, code, time.Now())
+		err = tx.Model(&models.User{}).Where("id = ?", locked.ID).Updates(map[string]interface{}{
+			"status":         locked.Status,
+			"can_reserve":    locked.CanReserve,
+			"unit_price":     locked.UnitPrice,
+			"invite_code_id": locked.InviteCodeID,
+			"approved_at":    locked.ApprovedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+		*user = locked
+		return writeAudit(c, tx, AuditUserRedeemInvite, AuditTargetUser, locked.ID, nil, inviteRedeemAudit(locked))
+	})
+	if err != nil {
+		utils.WarnCtx(c, "兑换邀请码失败: user_id=%d, err=%v", user.ID, err)
+		return err
+	}
+	afterUserLifecycleChange(c, user.ID, nil)
+	invalidateAllMonthlyReports()
+	return nil
+}
+
+// GetPendingUsers 管理员查看待审核的新用户，先注册的在前
+func GetPendingUsers(c *gin.Context) ([]map[string]interface{}, error) {
+	var users []models.User
+	if err := models.DB.Where("status = ?", models.UserStatusPendingApproval).Order("created_at ASC, id ASC").Find(&users).Error; err != nil {
+		utils.ErrorCtx(c, "查询待审核用户失败: %v", err)
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(users))
+	for _, user := range users {
+		result = append(result, map[string]interface{}{
+			"id":         user.ID,
+			"user_name":  user.Name,
+			"phone":      user.Phone,
+			"avatar":     user.Avatar,
+			"created_at": user.CreatedAt,
+		})
+	}
+	return result, nil
+}
+
+// lockPendingUser 在事务内锁定待审核用户
+func lockPendingUser(tx *gorm.DB, userID uint) (models.User, error) {
+	user, err := lockManagedUser(tx, userID)
+	if err != nil {
+		return user, err
+	}
+	if user.Status != models.UserStatusPendingApproval {
+		return user, errors.New("用户不在待审核状态")
+	}
+	return user, nil
+}
+
+// ApproveUser 管理员审核通过新用户，unitPrice 为空时保留默认电价
+func ApproveUser(c *gin.Context, adminID, userID uint, canReserve bool, unitPrice *decimal.Decimal) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "审核通过用户: admin_id=%d, user_id=%d, can_reserve=%t", adminID, userID, canReserve)
+	if unitPrice != nil && !unitPrice.IsPositive() {
+		return nil, errors.New("电价必须为正数")
+	}
+	var user models.User
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockPendingUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		before := map[string]interface{}{"status": user.Status, "can_reserve": user.CanReserve, "unit_price": user.UnitPrice}
+		now := time.Now()
+		user.Status = models.UserStatusActive
+		user.CanReserve = canReserve
+		if unitPrice != nil {
+			user.UnitPrice = models.RoundDecimal(*unitPrice, models.PricePlaces)
+		}
+		user.ApprovedAt = &now
+		user.ApprovedBy = &adminID
+		err = tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"status":      user.Status,
+			"can_reserve": user.CanReserve,
+			"unit_price":  user.UnitPrice,
+			"approved_at": user.ApprovedAt,
+			"approved_by": user.ApprovedBy,
+		}).Error
+		if err != nil {
+			return err
+		}
+		after := map[string]interface{}{"status": user.Status, "can_reserve": user.CanReserve, "unit_price": user.UnitPrice}
+		if err := writeAudit(c, tx, AuditUserApprove, AuditTargetUser, userID, before, after); err != nil {
+			return err
+		}
+		return CreateNotification(tx, userID, NotificationAccountApproved,
+			"账号已通过审核", "管理员已通过你的加入申请，现在可以使用共享充电桩了", userID)
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "审核通过用户失败: user_id=%d, err=%v", userID, err)
+		return nil, err
+	}
+	afterUserLifecycleChange(c, userID, nil)
+	invalidateAllMonthlyReports()
+	result := formatUserLifecycle(user)
+	result["can_reserve"] = user.CanReserve
+	result["unit_price"] = user.UnitPrice
+	return result, nil
+}
+
+// RejectUser 管理员拒绝新用户，用户标记为已移除，之后无法再登录
+func RejectUser(c *gin.Context, adminID, userID uint) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "拒绝待审核用户: admin_id=%d, user_id=%d", adminID, userID)
+	var user models.User
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockPendingUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		before := formatUserLifecycle(user)
+		now := time.Now()
+		user.Status = models.UserStatusRemoved
+		user.RemovedAt = &now
+		user.RemovedBy = &adminID
+		err = tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"status":     user.Status,
+			"removed_at": user.RemovedAt,
+			"removed_by": user.RemovedBy,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return writeAudit(c, tx, AuditUserReject, AuditTargetUser, userID, before, formatUserLifecycle(user))
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "拒绝待审核用户失败: user_id=%d, err=%v", userID, err)
+		return nil, err
+	}
+	afterUserLifecycleChange(c, userID, nil)
+	return formatUserLifecycle(user), nil
+}
@@ -87,7 +87,12 @@ func (s *LicensePlateService) UpdateLicensePlate(userID uint, plateID uint, plat
 		return err
 	}
 
-	return models.DB.Model(&licensePlate).Update("plate_number", plateNumber).Error
+	if err := models.DB.Model(&licensePlate).Update("plate_number", plateNumber).Error; err != nil {
+		return err
+	}
+	// 月度对账按车牌号显示
+	invalidateAllMonthlyReports()
+	return nil
 }
 
 // DeleteLicensePlate 删除车牌号
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"shared-charge/config"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/minio/minio-go/v7"
+	"github.com/shopspring/decimal"
+)
+
+// MeterReading 从电量截图中识别出的读数
+type MeterReading struct {
+	KWH          decimal.Decimal  `json:"kwh"`
+	MeterReading *decimal.Decimal `json:"meter_reading"`
+	Confidence   float64          `json:"confidence"`
+	RawText      string           `json:"raw_text"`
+}
+
+// MeterExtractor 电量截图识别接口，不同 OCR 引擎实现该接口即可接入
+type MeterExtractor interface {
+	Name() string
+	Extract(ctx context.Context, image []byte) (*MeterReading, error)
+}
+
+// ocrWord OCR 引擎输出的单个词及其置信度(0-1)
+type ocrWord struct {
+	Text       string
+	Confidence float64
+}
+
+var meterExtractor MeterExtractor
+
+// InitMeterExtractor 根据配置初始化电量截图识别引擎
+func InitMeterExtractor() error {
+	cfg := config.GetConfig().OCR
+	switch strings.ToLower(cfg.Engine) {
+	case "", "none":
+		meterExtractor = nil
+		return nil
+	case "tesseract":
+		extractor, err := NewTesseractExtractor(cfg.TesseractPath, cfg.Language)
+		if err != nil {
+			return err
+		}
+		meterExtractor = extractor
+		return nil
+	default:
+		return fmt.Errorf("不支持的OCR引擎: %s", cfg.Engine)
+	}
+}
+
+// GetMeterExtractor 获取当前识别引擎，未启用时返回 nil
+func GetMeterExtractor() MeterExtractor {
+	return meterExtractor
+}
+
+// SetMeterExtractor 替换识别引擎
+func SetMeterExtractor(extractor MeterExtractor) {
+	meterExtractor = extractor
+}
+
+// extractMeterReading 识别记录截图中的度数，失败时仅记录日志不影响记录创建
+func extractMeterReading(c *gin.Context, record *models.Record) {
+	extractor := GetMeterExtractor()
+	if extractor == nil || record.ImageURL == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(config.GetConfig().OCR.TimeoutSeconds)*time.Second)
+	defer cancel()
+	image, err := loadUploadedImage(ctx, record.ImageURL)
+	if err != nil {
+		utils.WarnCtx(c, "读取电量截图失败: image_url=%s, err=%v", record.ImageURL, err)
+		return
+	}
+	reading, err := extractor.Extract(ctx, image)
+	if err != nil {
+		utils.WarnCtx(c, "电量截图识别失败: engine=%s, err=%v", extractor.Name(), err)
+		return
+	}
+	if !reading.KWH.IsPositive() {
+		utils.InfoCtx(c, "电量截图未识别出度数: engine=%s, text=%s", extractor.Name(), reading.RawText)
+		return
+	}
+	kwh := models.RoundDecimal(reading.KWH, models.KWHPlaces)
+	record.OCRKWH = &kwh
+	if reading.MeterReading != nil {
+		meter := models.RoundDecimal(*reading.MeterReading, models.KWHPlaces)
+		record.OCRMeter = &meter
+	}
+	record.OCRConfidence = &reading.Confidence
+	utils.InfoCtx(c, "电量截图识别成功: engine=%s, kwh=%s, confidence=%.2f", extractor.Name(), kwh, reading.Confidence)
+}
+
+// IsOCRMismatch 识别度数可信且与填写度数差异超过允许误差
+func IsOCRMismatch(record *models.Record) bool {
+	cfg := config.GetConfig().OCR
+	if record.OCRKWH == nil || record.OCRConfidence == nil || *record.OCRConfidence < cfg.MinConfidence {
+		return false
+	}
+	return record.OCRKWH.Sub(record.KWH).Abs().GreaterThan(decimal.NewFromFloat(cfg.KWHTolerance))
+}
+
+// loadUploadedImage 根据上传接口返回的URL从MinIO读取图片内容
+func loadUploadedImage(ctx context.Context, imageURL string) ([]byte, error) {
+	objectName := strings.TrimPrefix(imageURL, "/api/image/")
+	if objectName == "" || objectName == imageURL {
+		return nil, fmt.Errorf("无法识别的图片地址: %s", imageURL)
+	}
+	cfg := config.GetConfig()
+	client := utils.GetMinioClient()
+	if client == nil {
+		return nil, fmt.Errorf("MinIO客户端未初始化")
+	}
+	obj, err := client.GetObject(ctx, cfg.MinIO.BucketName, objectName, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return io.ReadAll(io.LimitReader(obj, cfg.App.MaxFileSize))
+}
+
+var (
+	numberPattern  = regexp.MustCompile(`\d+(?:[.,]\d+)?`)
+	kwhUnitPattern = regexp.MustCompile(`(?i)^(kwh|kw·h|kw\.h|度)$`)
+	kwhLabels      = []string{"电量", "充电量", "本次", "energy", "charged"}
+	readingLabels  = []string{"读数", "表数", "累计", "总电量", "reading", "total", "meter"}
+)
+
+// parseMeterWords 从OCR结果中提取充电度数与电表读数
+// 优先取紧跟 kWh/度 单位或跟在“电量”等标签后的数字（标签可以是前一个词，也可以与数字连在同一个词中，中文识别结果常不分词）；
+// 否则若只有一个小数则作为度数并降低置信度
+func parseMeterWords(words []ocrWord) *MeterReading {
+	texts := make([]string, 0, len(words))
+	for _, w := range words {
+		texts = append(texts, w.Text)
+	}
+	reading := &MeterReading{RawText: strings.Join(texts, " ")}
+
+	kwhIndex := -1
+	for i, w := range words {
+		number, prefix, suffix, ok := splitNumber(w.Text)
+		if !ok {
+			continue
+		}
+		// 标签取数字前的文字；没有时取前一个词，前一个词本身带数字时它的标签属于那个数字
+		previous := prefix
+		if previous == "" && i > 0 {
+			if _, _, _, isNumber := splitNumber(words[i-1].Text); !isNumber {
+				previous = words[i-1].Text
+			}
+		}
+		unitFollows := kwhUnitPattern.MatchString(suffix) ||
+			(i+1 < len(words) && kwhUnitPattern.MatchString(strings.TrimSpace(words[i+1].Text)))
+		if hasLabel(previous, readingLabels) {
+			if reading.MeterReading == nil {
+				value := number
+				reading.MeterReading = &value
+			}
+			continue
+		}
+		if kwhIndex < 0 && (unitFollows || hasLabel(previous, kwhLabels)) {
+			kwhIndex = i
+			reading.KWH = number
+			reading.Confidence = w.Confidence
+		}
+	}
+	if kwhIndex >= 0 {
+		return reading
+	}
+
+	// 回退：全部数字中只有一个小数时视为度数
+	candidate := -1
+	for i, w := range words {
+		number, _, _, ok := splitNumber(w.Text)
+		if !ok || !strings.ContainsAny(w.Text, ".,") {
+			continue
+		}
+		if reading.MeterReading != nil && number.Equal(*reading.MeterReading) {
+			continue
+		}
+		if candidate >= 0 {
+			return reading
+		}
+		candidate = i
+	}
+	if candidate >= 0 {
+		reading.KWH, _, _, _ = splitNumber(words[candidate].Text)
+		reading.Confidence = words[candidate].Confidence * 0.6
+	}
+	return reading
+}
+
+// splitNumber 拆分形如 "电量:12.34kWh" 的词为前缀、数字与后缀，数字按十进制解析避免浮点误差
+func splitNumber(text string) (decimal.Decimal, string, string, bool) {
+	text = strings.TrimSpace(text)
+	loc := numberPattern.FindStringIndex(text)
+	if loc == nil {
+		return decimal.Zero, "", "", false
+	}
+	value, err := decimal.NewFromString(strings.ReplaceAll(text[loc[0]:loc[1]], ",", "."))
+	if err != nil {
+		return decimal.Zero, "", "", false
+	}
+	return value, strings.TrimSpace(text[:loc[0]]), strings.TrimSpace(text[loc[1]:]), true
+}
+
+// hasLabel 判断词中是否包含任一标签
+func hasLabel(text string, labels []string) bool {
+	lower := strings.ToLower(text)
+	for _, label := range labels {
+		if strings.Contains(lower, label) {
+			return true
+		}
+	}
+	return false
+}
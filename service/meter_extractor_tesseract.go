@@ -0,0 +1,73 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// TesseractExtractor 基于本机 tesseract 命令行的识别实现（仅使用CPU）
+type TesseractExtractor struct {
+	binary   string
+	language string
+}
+
+// NewTesseractExtractor 创建 tesseract 识别实例
+func NewTesseractExtractor(binary, language string) (*TesseractExtractor, error) {
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		return nil, fmt.Errorf("未找到tesseract可执行文件: %v", err)
+	}
+	if language == "" {
+		language = "chi_sim+eng"
+	}
+	return &TesseractExtractor{binary: path, language: language}, nil
+}
+
+// Name 引擎名称
+func (t *TesseractExtractor) Name() string {
+	return "tesseract"
+}
+
+// Extract 识别图片中的充电度数和电表读数
+func (t *TesseractExtractor) Extract(ctx context.Context, image []byte) (*MeterReading, error) {
+	// stdin 输入图片，stdout 输出带置信度的 TSV
+	cmd := exec.CommandContext(ctx, t.binary, "stdin", "stdout", "-l", t.language, "--psm", "6", "tsv")
+	cmd.Stdin = bytes.NewReader(image)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract识别失败: %v, %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return parseMeterWords(parseTesseractTSV(stdout.Bytes())), nil
+}
+
+// parseTesseractTSV 解析 tesseract TSV 输出中的词及置信度
+// 列: level page_num block_num par_num line_num word_num left top width height conf text
+func parseTesseractTSV(output []byte) []ocrWord {
+	var words []ocrWord
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	header := true
+	for scanner.Scan() {
+		if header {
+			header = false
+			continue
+		}
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 12 {
+			continue
+		}
+		text := strings.TrimSpace(fields[11])
+		conf, err := strconv.ParseFloat(fields[10], 64)
+		if text == "" || err != nil || conf < 0 {
+			continue
+		}
+		words = append(words, ocrWord{Text: text, Confidence: conf / 100})
+	}
+	return words
+}
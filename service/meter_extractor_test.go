@@ -0,0 +1,64 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestParseMeterWords(t *testing.T) {
+	words := func(texts ...string) []ocrWord {
+		result := make([]ocrWord, 0, len(texts))
+		for _, text := range texts {
+			result = append(result, ocrWord{Text: text, Confidence: 0.9})
+		}
+		return result
+	}
+	tests := []struct {
+		name       string
+		words      []ocrWord
+		kwh        string
+		meter      string // 为空表示未识别出读数
+		confidence float64
+	}{
+		{name: "单位与数字相连", words: words("充电", "12.34kWh"), kwh: "12.34", confidence: 0.9},
+		{name: "单位为下一个词", words: words("Charged", "8.5", "kWh"), kwh: "8.5", confidence: 0.9},
+		{name: "中文单位度", words: words("共", "20.05度"), kwh: "20.05", confidence: 0.9},
+		{name: "中文标签在前一个词", words: words("本次充电量", "15.2"), kwh: "15.2", confidence: 0.9},
+		{name: "中文标签与数字连在一起", words: words("电量:7.25"), kwh: "7.25", confidence: 0.9},
+		{name: "英文标签", words: words("Energy", "3.3"), kwh: "3.3", confidence: 0.9},
+		{name: "逗号作小数点", words: words("电量", "9,75"), kwh: "9.75", confidence: 0.9},
+		{
+			name:       "读数与度数",
+			words:      words("电表读数", "1234.56", "本次电量", "10.01", "kWh"),
+			kwh:        "10.01",
+			meter:      "1234.56",
+			confidence: 0.9,
+		},
+		{name: "总电量视为读数", words: words("总电量:5678.9", "12.5度"), kwh: "12.5", meter: "5678.9", confidence: 0.9},
+		{name: "小数精确保留", words: words("0.1", "kWh"), kwh: "0.1", confidence: 0.9},
+		{name: "回退：唯一小数", words: words("订单", "2024", "18.88"), kwh: "18.88", confidence: 0.54},
+		{name: "回退：多个小数不识别", words: words("1.5", "2.5"), kwh: "0"},
+		{name: "没有数字", words: words("充电完成"), kwh: "0"},
+		{name: "空结果", words: nil, kwh: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reading := parseMeterWords(tt.words)
+			if want := decimal.RequireFromString(tt.kwh); !reading.KWH.Equal(want) {
+				t.Errorf("kwh = %s, want %s", reading.KWH, want)
+			}
+			switch {
+			case tt.meter == "" && reading.MeterReading != nil:
+				t.Errorf("meter_reading = %s, want nil", reading.MeterReading)
+			case tt.meter != "" && reading.MeterReading == nil:
+				t.Errorf("meter_reading = nil, want %s", tt.meter)
+			case tt.meter != "" && !reading.MeterReading.Equal(decimal.RequireFromString(tt.meter)):
+				t.Errorf("meter_reading = %s, want %s", reading.MeterReading, tt.meter)
+			}
+			if diff := reading.Confidence - tt.confidence; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("confidence = %v, want %v", reading.Confidence, tt.confidence)
+			}
+		})
+	}
+}
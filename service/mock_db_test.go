@@ -0,0 +1,43 @@
+package service
+
+import (
+	"shared-charge/config"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// setupMockDB 使用 sqlmock 替换 models.DB，测试结束时校验全部预期 SQL 均已执行
+func setupMockDB(t *testing.T) sqlmock.Sqlmock {
+	t.Helper()
+	config.LoadConfig()
+	if err := utils.InitLogger("dev", "error", ""); err != nil {
+		t.Fatalf("初始化日志失败: %v", err)
+	}
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("创建 sqlmock 失败: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger:               logger.Default.LogMode(logger.Silent),
+		DisableAutomaticPing: true,
+	})
+	if err != nil {
+		t.Fatalf("打开 gorm 失败: %v", err)
+	}
+	previous := models.DB
+	models.DB = db
+	t.Cleanup(func() {
+		models.DB = previous
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("SQL 预期未满足: %v", err)
+		}
+		sqlDB.Close()
+	})
+	return mock
+}
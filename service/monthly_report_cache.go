@@ -0,0 +1,162 @@
+package service
+
+import (
+	"encoding/json"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+)
+
+// monthlyReportCachePrefix 月度对账缓存键前缀，每个月份按缓存代数一个键
+const monthlyReportCachePrefix = "monthly_report:"
+
+// monthlyReportGenPrefix 月度对账缓存代数键前缀，失效时递增代数而不是删除缓存
+// 汇总开始前读取代数，写入缓存时使用该代数对应的键；汇总期间发生失效时代数已变化，写入的旧数据不会再被读取
+const monthlyReportGenPrefix = "monthly_report_gen:"
+
+// monthlyReportGenAll 全部月份共用的缓存代数，用于用户、车牌号等跨月份数据变更
+const monthlyReportGenAll = monthlyReportGenPrefix + "all"
+
+// monthlyReportCacheMaxTTL 月度对账缓存的最长有效期
+const monthlyReportCacheMaxTTL = 24 * time.Hour
+
+// monthlyReportGenKey 指定月份的缓存代数键
+func monthlyReportGenKey(month string) string {
+	return monthlyReportGenPrefix + month
+}
+
+// monthlyReportCacheKey 月度对账缓存键，generation 为读取缓存时的代数
+func monthlyReportCacheKey(month, generation string) string {
+	return monthlyReportCachePrefix + month + ":" + generation
+}
+
+// monthlyReportGeneration 读取月份当前的缓存代数（全局代数.月份代数），尚未失效过时为 0.0
+func monthlyReportGeneration(rdb *redis.Client, month string) (string, error) {
+	values, err := rdb.MGet(utils.RedisCtx(), monthlyReportGenAll, monthlyReportGenKey(month)).Result()
+	if err != nil {
+		return "", err
+	}
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = "0"
+		if str, ok := value.(string); ok {
+			parts[i] = str
+		}
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// monthlyReportCacheTTL 计算缓存有效期
+// 月内仍有未结束的时段时，待上传状态会随时段结束而变化，缓存到下一个时段结束（08:00 或 20:00）为止；
+// 月末夜班在次月1日 08:00 结束，此后该月只会因数据变更而变化
+func monthlyReportCacheTTL(month string, now time.Time) time.Duration {
+	start, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return 0
+	}
+	lastSlotEnd := start.AddDate(0, 1, 0).Add(8 * time.Hour)
+	if !now.Before(lastSlotEnd) {
+		return monthlyReportCacheMaxTTL
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	for _, boundary := range []time.Time{today.Add(8 * time.Hour), today.Add(20 * time.Hour), today.AddDate(0, 0, 1).Add(8 * time.Hour)} {
+		if boundary.After(now) {
+			return boundary.Sub(now)
+		}
+	}
+	return monthlyReportCacheMaxTTL
+}
+
+// loadMonthlyReport 优先从 Redis 读取月度对账，未命中时汇总并写入缓存
+// 缓存读写失败不影响结果，只记录日志
+func loadMonthlyReport(c *gin.Context, month string) (*MonthlyReport, error) {
+	rdb := utils.GetRedis()
+	key := ""
+	if rdb != nil {
+		generation, err := monthlyReportGeneration(rdb, month)
+		if err != nil {
+			utils.WarnCtx(c, "读取月度对账缓存代数失败: month=%s, err=%v", month, err)
+		} else {
+			key = monthlyReportCacheKey(month, generation)
+			if val, err := rdb.Get(utils.RedisCtx(), key).Result(); err == nil {
+				var report MonthlyReport
+				if err := json.Unmarshal([]byte(val), &report); err == nil {
+					return &report, nil
+				}
+			}
+		}
+	}
+
+	report, err := buildMonthlyReport(month)
+	if err != nil {
+		return nil, err
+	}
+	if key != "" {
+		if ttl := monthlyReportCacheTTL(month, time.Now()); ttl > 0 {
+			data, _ := json.Marshal(report)
+			if err := rdb.Set(utils.RedisCtx(), key, data, ttl).Err(); err != nil {
+				utils.WarnCtx(c, "写入月度对账缓存失败: month=%s, err=%v", month, err)
+			}
+		}
+	}
+	return report, nil
+}
+
+// invalidateMonthlyReport 递增指定月份的缓存代数使缓存失效，应在数据变更提交后调用
+func invalidateMonthlyReport(months ...string) {
+	rdb := utils.GetRedis()
+	if rdb == nil || len(months) == 0 {
+		return
+	}
+	seen := make(map[string]bool, len(months))
+	pipe := rdb.TxPipeline()
+	for _, month := range months {
+		if seen[month] {
+			continue
+		}
+		seen[month] = true
+		pipe.Incr(utils.RedisCtx(), monthlyReportGenKey(month))
+	}
+	if _, err := pipe.Exec(utils.RedisCtx()); err != nil {
+		utils.WarnCtx(nil, "清除月度对账缓存失败: months=%v, err=%v", months, err)
+	}
+}
+
+// invalidateMonthlyReportDates 清除给定日期所在月份的月度对账缓存
+func invalidateMonthlyReportDates(dates ...time.Time) {
+	months := make([]string, 0, len(dates))
+	for _, date := range dates {
+		if !date.IsZero() {
+			months = append(months, date.Format("2006-01"))
+		}
+	}
+	invalidateMonthlyReport(months...)
+}
+
+// invalidateMonthlyReportForRecord 清除充电记录及其关联预约所在月份的缓存
+// 夜班记录的日期可能与预约日期不在同一个月
+func invalidateMonthlyReportForRecord(record models.Record) {
+	dates := []time.Time{record.Date}
+	if record.ReservationID != 0 {
+		var reservation models.Reservation
+		if err := models.DB.Unscoped().Select("date").First(&reservation, record.ReservationID).Error; err == nil {
+			dates = append(dates, reservation.Date)
+		}
+	}
+	invalidateMonthlyReportDates(dates...)
+}
+
+// invalidateAllMonthlyReports 递增全局缓存代数使全部月份的缓存失效，用于用户、车牌号等跨月份数据变更
+func invalidateAllMonthlyReports() {
+	rdb := utils.GetRedis()
+	if rdb == nil {
+		return
+	}
+	if err := rdb.Incr(utils.RedisCtx(), monthlyReportGenAll).Err(); err != nil {
+		utils.WarnCtx(nil, "清除月度对账缓存失败: %v", err)
+	}
+}
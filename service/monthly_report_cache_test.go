@@ -0,0 +1,62 @@
+package service
+
+import (
+	"shared-charge/utils"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+// setupMiniRedis 使用 miniredis 替换全局 Redis 客户端
+func setupMiniRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	server := miniredis.RunT(t)
+	previous := utils.RedisClient
+	utils.RedisClient = redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() {
+		utils.RedisClient.Close()
+		utils.RedisClient = previous
+	})
+	return utils.RedisClient
+}
+
+func TestMonthlyReportGenerationChangesOnInvalidation(t *testing.T) {
+	rdb := setupMiniRedis(t)
+	generation := func(month string) string {
+		t.Helper()
+		gen, err := monthlyReportGeneration(rdb, month)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return gen
+	}
+
+	start := generation("2024-05")
+	if start != "0.0" {
+		t.Fatalf("初始代数 = %q, want 0.0", start)
+	}
+	// 汇总开始前读取的代数对应的键，在汇总期间发生失效后不应再被读取
+	staleKey := monthlyReportCacheKey("2024-05", start)
+	other := generation("2024-06")
+
+	invalidateMonthlyReport("2024-05", "2024-05")
+	afterMonth := generation("2024-05")
+	if afterMonth != "0.1" {
+		t.Fatalf("失效后代数 = %q, want 0.1", afterMonth)
+	}
+	if monthlyReportCacheKey("2024-05", afterMonth) == staleKey {
+		t.Fatal("失效后缓存键应变化")
+	}
+	if generation("2024-06") != other {
+		t.Fatal("其他月份的代数不应变化")
+	}
+
+	invalidateAllMonthlyReports()
+	if got := generation("2024-05"); got != "1.1" {
+		t.Fatalf("全部失效后代数 = %q, want 1.1", got)
+	}
+	if got := generation("2024-06"); got != "1.0" {
+		t.Fatalf("全部失效后其他月份代数 = %q, want 1.0", got)
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 通知类型
+const (
+	NotificationRecordApproved = "record_approved"
+	NotificationRecordRejected = "record_rejected"
+)
+
+// CreateNotification 写入一条站内通知，tx 为空时使用全局连接
+func CreateNotification(tx *gorm.DB, userID uint, notificationType, title, content string, relatedID uint) error {
+	if tx == nil {
+		tx = models.DB
+	}
+	return tx.Create(&models.Notification{
+		UserID:    userID,
+		Type:      notificationType,
+		Title:     title,
+		Content:   content,
+		RelatedID: relatedID,
+	}).Error
+}
+
+// GetUserNotifications 获取用户通知列表
+func GetUserNotifications(c *gin.Context, userID uint, unreadOnly bool) ([]map[string]interface{}, int64, error) {
+	utils.InfoCtx(c, "查询用户通知: user_id=%d, unread_only=%t", userID, unreadOnly)
+	var notifications []models.Notification
+	query := models.DB.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if err := query.Order("created_at DESC").Limit(defaultLimit).Find(&notifications).Error; err != nil {
+		utils.ErrorCtx(c, "查询用户通知失败: %v", err)
+		return nil, 0, err
+	}
+	var unread int64
+	models.DB.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&unread)
+
+	resp := make([]map[string]interface{}, 0, len(notifications))
+	for _, n := range notifications {
+		resp = append(resp, n.FormatNotificationInfo())
+	}
+	return resp, unread, nil
+}
+
+// MarkNotificationRead 标记通知为已读
+func MarkNotificationRead(c *gin.Context, userID, notificationID uint) error {
+	result := models.DB.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", notificationID, userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		utils.ErrorCtx(c, "标记通知已读失败: %v", result.Error)
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		models.DB.Model(&models.Notification{}).Where("id = ? AND user_id = ?", notificationID, userID).Count(&count)
+		if count == 0 {
+			return errors.New("通知不存在")
+		}
+	}
+	return nil
+}
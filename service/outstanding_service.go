@@ -0,0 +1,168 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// NotificationUploadReminder 补传充电记录提醒的通知类型
+const NotificationUploadReminder = "upload_reminder"
+
+// OutstandingUpload 已结束但未上传充电记录的预约及其提醒历史
+type OutstandingUpload struct {
+	Reservation models.Reservation
+	Reminders   []models.UploadReminder
+}
+
+// activeRecordExistsCondition 预约下存在未作废的充电记录
+const activeRecordExistsCondition = "EXISTS (SELECT 1 FROM records WHERE records.reservation_id = reservations.id AND records.voided_at IS NULL AND records.deleted_at IS NULL)"
+
+// noActiveRecordCondition 预约下没有未作废的充电记录
+const noActiveRecordCondition = "NOT " + activeRecordExistsCondition
+
+// findOutstandingUploads 查询已结束、未取消且没有充电记录的预约
+// userID 为 0 时查询全部用户；startDate/endDate 为空时不限日期
+func findOutstandingUploads(userID uint, startDate, endDate string) ([]OutstandingUpload, error) {
+	now := time.Now()
+	query := models.DB.Where("status != ? AND date <= ?", "cancelled", now.Format("2006-01-02")).
+		Where(noActiveRecordCondition)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if startDate != "" && endDate != "" {
+		query = query.Where("date >= ? AND date <= ?", startDate, endDate)
+	}
+	var reservations []models.Reservation
+	err := query.Preload("User").Preload("LicensePlate").Order("date ASC, id ASC").Find(&reservations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// 当天的预约可能尚未结束，按时段结束时间过滤
+	outstanding := make([]OutstandingUpload, 0, len(reservations))
+	ids := make([]uint, 0, len(reservations))
+	for _, reservation := range reservations {
+		if !reservation.HasEnded(now) {
+			continue
+		}
+		outstanding = append(outstanding, OutstandingUpload{Reservation: reservation})
+		ids = append(ids, reservation.ID)
+	}
+	if len(ids) == 0 {
+		return outstanding, nil
+	}
+
+	var reminders []models.UploadReminder
+	if err := models.DB.Where("reservation_id IN ?", ids).Order("created_at ASC").Find(&reminders).Error; err != nil {
+		return nil, err
+	}
+	reminderMap := make(map[uint][]models.UploadReminder)
+	for _, reminder := range reminders {
+		reminderMap[reminder.ReservationID] = append(reminderMap[reminder.ReservationID], reminder)
+	}
+	for i := range outstanding {
+		outstanding[i].Reminders = reminderMap[outstanding[i].Reservation.ID]
+	}
+	return outstanding, nil
+}
+
+// formatOutstandingUpload 格式化待上传预约，包含超期时长与提醒历史
+func formatOutstandingUpload(item OutstandingUpload, now time.Time) map[string]interface{} {
+	endTime := item.Reservation.EndTime()
+	result := FormatReservationDate(&item.Reservation)
+	result["ended_at"] = endTime
+	result["outstanding_hours"] = int64(now.Sub(endTime).Hours())
+
+	reminders := make([]map[string]interface{}, 0, len(item.Reminders))
+	for _, reminder := range item.Reminders {
+		reminders = append(reminders, map[string]interface{}{
+			"id":         reminder.ID,
+			"sent_by":    reminder.SentBy,
+			"created_at": reminder.CreatedAt,
+		})
+	}
+	result["reminders"] = reminders
+	result["reminder_count"] = len(item.Reminders)
+	if len(item.Reminders) > 0 {
+		result["last_reminded_at"] = item.Reminders[len(item.Reminders)-1].CreatedAt
+	} else {
+		result["last_reminded_at"] = nil
+	}
+	return result
+}
+
+// GetOutstandingUploads 获取已结束但未上传充电记录的预约
+// userID 为 0 时返回全部用户（管理员）；month 为空时不限月份
+func GetOutstandingUploads(c *gin.Context, userID uint, month string) ([]map[string]interface{}, error) {
+	utils.InfoCtx(c, "查询待上传充电记录的预约: user_id=%d, month=%s", userID, month)
+	var startDate, endDate string
+	if month != "" {
+		var err error
+		startDate, endDate, err = getMonthDateRange(month)
+		if err != nil {
+			return nil, errors.New("月份格式错误")
+		}
+	}
+	items, err := findOutstandingUploads(userID, startDate, endDate)
+	if err != nil {
+		utils.ErrorCtx(c, "查询待上传充电记录的预约失败: %v", err)
+		return nil, err
+	}
+	now := time.Now()
+	resp := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		resp = append(resp, formatOutstandingUpload(item, now))
+	}
+	return resp, nil
+}
+
+// SendUploadReminder 管理员提醒用户补传预约的充电记录
+func SendUploadReminder(c *gin.Context, adminID, reservationID uint) (*models.UploadReminder, error) {
+	utils.InfoCtx(c, "发送补传提醒: admin_id=%d, reservation_id=%d", adminID, reservationID)
+	var reservation models.Reservation
+	if err := models.DB.First(&reservation, reservationID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("预约不存在")
+		}
+		return nil, err
+	}
+	if reservation.IsCancelled() || !reservation.HasEnded(time.Now()) {
+		return nil, errors.New("预约未结束或已取消，无需提醒")
+	}
+	var count int64
+	models.DB.Model(&models.Record{}).Scopes(models.ActiveRecords).Where("reservation_id = ?", reservationID).Count(&count)
+	if count > 0 {
+		return nil, errors.New("该预约已上传充电记录")
+	}
+
+	reminder := &models.UploadReminder{
+		ReservationID: reservation.ID,
+		UserID:        reservation.UserID,
+		SentBy:        adminID,
+	}
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(reminder).Error; err != nil {
+			return err
+		}
+		if err := writeAudit(c, tx, AuditReservationRemind, AuditTargetReservation, reservation.ID, nil,
+			map[string]interface{}{"reminder_id": reminder.ID, "user_id": reservation.UserID}); err != nil {
+			return err
+		}
+		return CreateNotification(tx, reservation.UserID, NotificationUploadReminder,
+			"请上传充电记录",
+			fmt.Sprintf("您 %s %s 的预约已结束，请尽快上传充电记录", reservation.Date.Format("2006-01-02"), reservation.TimeslotText()),
+			reservation.ID)
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "发送补传提醒失败: reservation_id=%d, err=%v", reservationID, err)
+		return nil, err
+	}
+	utils.InfoCtx(c, "补传提醒已发送: reservation_id=%d, user_id=%d", reservation.ID, reservation.UserID)
+	return reminder, nil
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"errors"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// applyDuePriceSchedules 将已到生效日、尚未写入的电价计划写入用户电价，并以系统身份写入审计日志
+// 同一用户有多条到期计划时取生效日最晚的一条；userID 为 0 时处理全部用户
+// 到期计划在事务内以 FOR UPDATE 锁定，并发调用时后到的事务会等待并跳过已写入或已取消的计划，避免重复写入和重复审计
+func applyDuePriceSchedules(userID uint) error {
+	today := time.Now().Format("2006-01-02")
+	dueQuery := func(db *gorm.DB) *gorm.DB {
+		query := db.Model(&models.UnitPriceSchedule{}).Where("applied_at IS NULL AND effective_date <= ?", today)
+		if userID != 0 {
+			query = query.Where("user_id = ?", userID)
+		}
+		return query
+	}
+	// 绝大多数调用没有到期计划，先不加锁检查，避免每次查询电价都开启事务
+	var count int64
+	if err := dueQuery(models.DB).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		query := dueQuery(tx).Clauses(clause.Locking{Strength: "UPDATE"})
+		var due []models.UnitPriceSchedule
+		if err := query.Order("user_id ASC, effective_date ASC, id ASC").Find(&due).Error; err != nil {
+			return err
+		}
+		if len(due) == 0 {
+			return nil
+		}
+		latest := make(map[uint]models.UnitPriceSchedule)
+		userIDs := make([]uint, 0)
+		scheduleIDs := make(map[uint][]uint)
+		for _, schedule := range due {
+			if _, ok := latest[schedule.UserID]; !ok {
+				userIDs = append(userIDs, schedule.UserID)
+			}
+			latest[schedule.UserID] = schedule
+			scheduleIDs[schedule.UserID] = append(scheduleIDs[schedule.UserID], schedule.ID)
+		}
+		now := time.Now()
+		for _, uid := range userIDs {
+			schedule := latest[uid]
+			// 只有本事务实际标记为已生效的计划才写入电价和审计日志
+			result := tx.Model(&models.UnitPriceSchedule{}).
+				Where("id IN ? AND applied_at IS NULL", scheduleIDs[uid]).
+				Update("applied_at", now)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+			var user models.User
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id", "unit_price").First(&user, uid).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue
+				}
+				return err
+			}
+			if err := tx.Model(&models.User{}).Where("id = ?", uid).Update("unit_price", schedule.UnitPrice).Error; err != nil {
+				return err
+			}
+			if err := writeAudit(nil, tx, AuditUserUnitPriceScheduled, AuditTargetUser, uid,
+				map[string]interface{}{"unit_price": user.UnitPrice},
+				map[string]interface{}{"unit_price": schedule.UnitPrice, "schedule_id": schedule.ID}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// pendingPriceSchedules 查询尚未生效的电价计划，按生效日排序；userID 为 0 时查询全部用户
+func pendingPriceSchedules(userID uint) ([]models.UnitPriceSchedule, error) {
+	query := models.DB.Where("applied_at IS NULL")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	var schedules []models.UnitPriceSchedule
+	err := query.Order("user_id ASC, effective_date ASC, id ASC").Find(&schedules).Error
+	return schedules, err
+}
+
+// unitPriceOn 返回指定日期适用的电价：取生效日不晚于该日期的最后一条待生效计划，否则为当前电价
+// schedules 需按生效日升序排列
+func unitPriceOn(current decimal.Decimal, schedules []models.UnitPriceSchedule, date time.Time) decimal.Decimal {
+	day := date.Format("2006-01-02")
+	price := current
+	for _, schedule := range schedules {
+		if schedule.EffectiveDate.Format("2006-01-02") > day {
+			break
+		}
+		price = schedule.UnitPrice
+	}
+	return price
+}
+
+// formatPriceSchedule 格式化电价计划
+func formatPriceSchedule(schedule models.UnitPriceSchedule) map[string]interface{} {
+	return map[string]interface{}{
+		"id":             schedule.ID,
+		"user_id":        schedule.UserID,
+		"unit_price":     schedule.UnitPrice,
+		"effective_date": schedule.EffectiveDate.Format("2006-01-02"),
+		"applied_at":     schedule.AppliedAt,
+		"created_by":     schedule.CreatedBy,
+		"created_at":     schedule.CreatedAt,
+	}
+}
+
+// GetUserPriceInfo 获取用户当前电价及待生效的电价计划
+func GetUserPriceInfo(c *gin.Context, userID uint) (map[string]interface{}, error) {
+	price, err := GetUserPrice(userID)
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := pendingPriceSchedules(userID)
+	if err != nil {
+		utils.ErrorCtx(c, "查询电价计划失败: %v", err)
+		return nil, err
+	}
+	scheduled := make([]map[string]interface{}, 0, len(schedules))
+	for _, schedule := range schedules {
+		scheduled = append(scheduled, map[string]interface{}{
+			"unit_price":     schedule.UnitPrice,
+			"effective_date": schedule.EffectiveDate.Format("2006-01-02"),
+		})
+	}
+	return map[string]interface{}{"unit_price": price, "scheduled": scheduled}, nil
+}
+
+// parsePriceScheduleInput 校验电价计划的电价和生效日期，生效日期必须晚于今天
+func parsePriceScheduleInput(unitPrice decimal.Decimal, effectiveDate string) (time.Time, error) {
+	if !unitPrice.IsPositive() {
+		return time.Time{}, errors.New("电价必须为正数")
+	}
+	date, err := time.ParseInLocation("2006-01-02", effectiveDate, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("日期格式错误，应为YYYY-MM-DD")
+	}
+	if effectiveDate <= time.Now().Format("2006-01-02") {
+		return time.Time{}, errors.New("生效日期必须晚于今天，立即生效请直接修改电价")
+	}
+	return date, nil
+}
+
+// CreatePriceSchedule 管理员为用户设置未来生效的电价
+func CreatePriceSchedule(c *gin.Context, adminID, userID uint, unitPrice decimal.Decimal, effectiveDate string) (*models.UnitPriceSchedule, error) {
+	utils.InfoCtx(c, "创建电价计划: admin_id=%d, user_id=%d, unit_price=%s, effective_date=%s", adminID, userID, unitPrice, effectiveDate)
+	date, err := parsePriceScheduleInput(unitPrice, effectiveDate)
+	if err != nil {
+		return nil, err
+	}
+	var user models.User
+	if err := models.DB.Select("id").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+	schedule := &models.UnitPriceSchedule{
+		UserID:        userID,
+		UnitPrice:     models.RoundDecimal(unitPrice, models.PricePlaces),
+		EffectiveDate: date,
+		CreatedBy:     adminID,
+	}
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(schedule).Error; err != nil {
+			return err
+		}
+		return writeAudit(c, tx, AuditPriceScheduleCreate, AuditTargetPriceSchedule, schedule.ID, nil, formatPriceSchedule(*schedule))
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "创建电价计划失败: %v", err)
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// GetPriceSchedules 管理员查看待生效的电价计划，userID 为 0 时返回全部用户
+func GetPriceSchedules(c *gin.Context, userID uint) ([]map[string]interface{}, error) {
+	schedules, err := pendingPriceSchedules(userID)
+	if err != nil {
+		utils.ErrorCtx(c, "查询电价计划失败: %v", err)
+		return nil, err
+	}
+	result := make([]map[string]interface{}, 0, len(schedules))
+	for _, schedule := range schedules {
+		result = append(result, formatPriceSchedule(schedule))
+	}
+	return result, nil
+}
+
+// CancelPriceSchedule 管理员取消尚未生效的电价计划
+func CancelPriceSchedule(c *gin.Context, scheduleID uint) error {
+	utils.InfoCtx(c, "取消电价计划: schedule_id=%d", scheduleID)
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		var schedule models.UnitPriceSchedule
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND applied_at IS NULL", scheduleID).
+			First(&schedule).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("电价计划不存在或已生效")
+			}
+			return err
+		}
+		if err := tx.Delete(&schedule).Error; err != nil {
+			return err
+		}
+		return writeAudit(c, tx, AuditPriceScheduleCancel, AuditTargetPriceSchedule, schedule.ID, formatPriceSchedule(schedule), nil)
+	})
+}
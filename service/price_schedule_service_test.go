@@ -0,0 +1,36 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestApplyDuePriceSchedulesSkipsAlreadyApplied(t *testing.T) {
+	mock := setupMockDB(t)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "unit_price_schedules"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "unit_price_schedules" WHERE .*applied_at IS NULL.* FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "unit_price", "effective_date"}).
+			AddRow(5, 9, "0.8000", time.Now()))
+	// 并发事务已写入该计划，本事务标记不到任何行，不应再更新电价或写审计日志
+	mock.ExpectExec(`UPDATE "unit_price_schedules" SET "applied_at"=.* WHERE .*applied_at IS NULL`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	if err := applyDuePriceSchedules(9); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestApplyDuePriceSchedulesNothingDue(t *testing.T) {
+	mock := setupMockDB(t)
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "unit_price_schedules"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	if err := applyDuePriceSchedules(9); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,275 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// 统计粒度
+const (
+	GranularityDay   = "day"
+	GranularityWeek  = "week"
+	GranularityMonth = "month"
+	GranularityYear  = "year"
+)
+
+// defaultBucketCounts 未指定 from 时，按粒度默认统计的区间数
+var defaultBucketCounts = map[string]int{
+	GranularityDay:   30,
+	GranularityWeek:  12,
+	GranularityMonth: 12,
+	GranularityYear:  5,
+}
+
+// maxStatisticsBuckets 单次查询允许的最大区间数
+const maxStatisticsBuckets = 366
+
+// StatisticsRange 统计的日期范围、粒度与时区
+type StatisticsRange struct {
+	From        time.Time
+	To          time.Time
+	Granularity string
+	Location    *time.Location
+}
+
+// StatisticsRangeRequest 区间统计请求参数
+type StatisticsRangeRequest struct {
+	From        string
+	To          string
+	Month       string
+	Granularity string
+	Timezone    string
+}
+
+// truncateToBucket 返回日期所在区间的起始日（周从周一开始）
+func truncateToBucket(date time.Time, granularity string) time.Time {
+	y, m, d := date.Date()
+	loc := date.Location()
+	switch granularity {
+	case GranularityWeek:
+		offset := (int(date.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, loc)
+	case GranularityMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	case GranularityYear:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(y, m, d, 0, 0, 0, 0, loc)
+	}
+}
+
+// shiftBucket 将区间起始日前后移动 n 个区间
+func shiftBucket(start time.Time, granularity string, n int) time.Time {
+	switch granularity {
+	case GranularityWeek:
+		return start.AddDate(0, 0, 7*n)
+	case GranularityMonth:
+		return start.AddDate(0, n, 0)
+	case GranularityYear:
+		return start.AddDate(n, 0, 0)
+	default:
+		return start.AddDate(0, 0, n)
+	}
+}
+
+// bucketLabel 区间的展示名称
+func bucketLabel(start time.Time, granularity string) string {
+	switch granularity {
+	case GranularityWeek:
+		year, week := start.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case GranularityMonth:
+		return start.Format("2006-01")
+	case GranularityYear:
+		return start.Format("2006")
+	default:
+		return start.Format("2006-01-02")
+	}
+}
+
+// ParseStatisticsRange 解析区间统计参数
+// 未传 from/to 时：传了 month 则统计该月，否则以时区内的今天为止按粒度回溯默认区间数
+func ParseStatisticsRange(req StatisticsRangeRequest) (StatisticsRange, error) {
+	granularity := req.Granularity
+	if granularity == "" {
+		granularity = GranularityDay
+	}
+	if _, ok := defaultBucketCounts[granularity]; !ok {
+		return StatisticsRange{}, errors.New("统计粒度错误，应为day、week、month或year")
+	}
+	loc := time.Local
+	if req.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(req.Timezone)
+		if err != nil {
+			return StatisticsRange{}, errors.New("时区无效")
+		}
+	}
+
+	parseDate := func(value string) (time.Time, error) {
+		return time.ParseInLocation("2006-01-02", value, loc)
+	}
+	var from, to time.Time
+	var err error
+	switch {
+	case req.From != "" || req.To != "":
+		if req.From == "" || req.To == "" {
+			return StatisticsRange{}, errors.New("from和to需同时提供")
+		}
+		if from, err = parseDate(req.From); err != nil {
+			return StatisticsRange{}, errors.New("日期格式错误，应为YYYY-MM-DD")
+		}
+		if to, err = parseDate(req.To); err != nil {
+			return StatisticsRange{}, errors.New("日期格式错误，应为YYYY-MM-DD")
+		}
+	case req.Month != "":
+		startDate, endDate, err := getMonthDateRange(req.Month)
+		if err != nil {
+			return StatisticsRange{}, errors.New("月份格式错误")
+		}
+		from, _ = parseDate(startDate)
+		to, _ = parseDate(endDate)
+	default:
+		now := time.Now().In(loc)
+		to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+		from = shiftBucket(truncateToBucket(to, granularity), granularity, 1-defaultBucketCounts[granularity])
+	}
+	if to.Before(from) {
+		return StatisticsRange{}, errors.New("结束日期不能早于开始日期")
+	}
+
+	buckets := 0
+	for start := truncateToBucket(from, granularity); !start.After(to); start = shiftBucket(start, granularity, 1) {
+		buckets++
+		if buckets > maxStatisticsBuckets {
+			return StatisticsRange{}, fmt.Errorf("统计区间过多，最多%d个", maxStatisticsBuckets)
+		}
+	}
+	return StatisticsRange{From: from, To: to, Granularity: granularity, Location: loc}, nil
+}
+
+// statisticsBucket 单个统计区间的汇总
+type statisticsBucket struct {
+	Start time.Time
+	End   time.Time
+	ShiftAggregate
+}
+
+// formatBucketTotals 格式化区间用电量与费用
+func formatBucketTotals(agg ShiftAggregate) map[string]interface{} {
+	return map[string]interface{}{
+		"day_kwh":      agg.DayKwh,
+		"night_kwh":    agg.NightKwh,
+		"total_kwh":    agg.TotalKwh(),
+		"total_amount": models.FenToYuan(agg.TotalAmount),
+		"record_count": agg.RecordCount,
+	}
+}
+
+// changeRate 环比变化率（百分比），上期为0时无法计算返回 nil
+func changeRate(current, previous decimal.Decimal) interface{} {
+	if previous.IsZero() {
+		return nil
+	}
+	return current.Sub(previous).Mul(decimal.NewFromInt(100)).DivRound(previous, 2)
+}
+
+// GetRangeStatistics 按日/周/月/年汇总日期范围内的白班、夜班、总用电量和费用，并计算环比
+// userID 为 0 时统计全部用户；首尾区间按 from/to 截断，环比总是与前一个完整区间比较。
+// 第一个区间被 from 截断时与完整的上一区间不可比，delta 返回 null
+func GetRangeStatistics(c *gin.Context, userID uint, r StatisticsRange) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "查询区间统计: user_id=%d, from=%s, to=%s, granularity=%s, tz=%s",
+		userID, r.From.Format("2006-01-02"), r.To.Format("2006-01-02"), r.Granularity, r.Location)
+
+	// 多查一个区间用于计算第一个区间的环比
+	previousStart := shiftBucket(truncateToBucket(r.From, r.Granularity), r.Granularity, -1)
+	rows, err := aggregateShiftStatistics(userID, previousStart.Format("2006-01-02"), r.To.Format("2006-01-02"), shiftGroupDate)
+	if err != nil {
+		utils.ErrorCtx(c, "查询区间统计失败: %v", err)
+		return nil, err
+	}
+
+	firstStart := truncateToBucket(r.From, r.Granularity)
+	buckets := make([]*statisticsBucket, 0)
+	bucketMap := make(map[string]*statisticsBucket)
+	for start := previousStart; !start.After(r.To); start = shiftBucket(start, r.Granularity, 1) {
+		bucket := &statisticsBucket{Start: start, End: shiftBucket(start, r.Granularity, 1).AddDate(0, 0, -1)}
+		if len(buckets) > 0 && bucket.Start.Before(r.From) {
+			bucket.Start = r.From
+		}
+		if bucket.End.After(r.To) {
+			bucket.End = r.To
+		}
+		buckets = append(buckets, bucket)
+		bucketMap[bucketLabel(start, r.Granularity)] = bucket
+	}
+	// 第一个区间的完整合计，用作第二个区间环比的上期
+	var firstFull ShiftAggregate
+	for _, row := range rows {
+		date, err := time.ParseInLocation("2006-01-02", row.Date, r.Location)
+		if err != nil {
+			continue
+		}
+		if !date.Before(firstStart) && date.Before(shiftBucket(firstStart, r.Granularity, 1)) {
+			firstFull.DayKwh = firstFull.DayKwh.Add(row.DayKwh)
+			firstFull.NightKwh = firstFull.NightKwh.Add(row.NightKwh)
+			firstFull.TotalAmount += row.TotalAmount
+			firstFull.RecordCount += row.RecordCount
+		}
+		// 第一个区间被 from 截断，截断前的记录不计入
+		if date.Before(r.From) && !date.Before(firstStart) {
+			continue
+		}
+		bucket := bucketMap[bucketLabel(truncateToBucket(date, r.Granularity), r.Granularity)]
+		if bucket == nil {
+			continue
+		}
+		bucket.DayKwh = bucket.DayKwh.Add(row.DayKwh)
+		bucket.NightKwh = bucket.NightKwh.Add(row.NightKwh)
+		bucket.TotalAmount += row.TotalAmount
+		bucket.RecordCount += row.RecordCount
+	}
+
+	var summary ShiftAggregate
+	items := make([]map[string]interface{}, 0, len(buckets)-1)
+	for i := 1; i < len(buckets); i++ {
+		bucket, previous := buckets[i], buckets[i-1].ShiftAggregate
+		if i == 2 {
+			previous = firstFull
+		}
+		summary.DayKwh = summary.DayKwh.Add(bucket.DayKwh)
+		summary.NightKwh = summary.NightKwh.Add(bucket.NightKwh)
+		summary.TotalAmount += bucket.TotalAmount
+		summary.RecordCount += bucket.RecordCount
+
+		item := formatBucketTotals(bucket.ShiftAggregate)
+		item["label"] = bucketLabel(truncateToBucket(bucket.Start, r.Granularity), r.Granularity)
+		item["start"] = bucket.Start.Format("2006-01-02")
+		item["end"] = bucket.End.Format("2006-01-02")
+		item["delta"] = nil
+		if i > 1 || !r.From.After(firstStart) {
+			item["delta"] = map[string]interface{}{
+				"total_kwh":         bucket.TotalKwh().Sub(previous.TotalKwh()),
+				"total_amount":      models.FenToYuan(bucket.TotalAmount - previous.TotalAmount),
+				"total_kwh_rate":    changeRate(bucket.TotalKwh(), previous.TotalKwh()),
+				"total_amount_rate": changeRate(decimal.NewFromInt(bucket.TotalAmount), decimal.NewFromInt(previous.TotalAmount)),
+			}
+		}
+		items = append(items, item)
+	}
+
+	return map[string]interface{}{
+		"from":        r.From.Format("2006-01-02"),
+		"to":          r.To.Format("2006-01-02"),
+		"granularity": r.Granularity,
+		"timezone":    r.Location.String(),
+		"buckets":     items,
+		"summary":     formatBucketTotals(summary),
+	}, nil
+}
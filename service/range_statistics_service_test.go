@@ -0,0 +1,110 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+func TestTruncateToBucket(t *testing.T) {
+	shanghai := time.FixedZone("CST", 8*3600)
+	at := func(value string) time.Time {
+		date, err := time.ParseInLocation("2006-01-02 15:04", value, shanghai)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return date
+	}
+	tests := []struct {
+		name        string
+		date        string
+		granularity string
+		start       string
+		label       string
+	}{
+		{"日：去掉时间", "2024-03-31 23:59", GranularityDay, "2024-03-31 00:00", "2024-03-31"},
+		{"周：周一是起始日", "2024-01-01 08:00", GranularityWeek, "2024-01-01 00:00", "2024-W01"},
+		{"周：周日归到前一个周一", "2024-01-07 23:59", GranularityWeek, "2024-01-01 00:00", "2024-W01"},
+		{"周：跨月", "2024-03-01 12:00", GranularityWeek, "2024-02-26 00:00", "2024-W09"},
+		{"周：闰日", "2024-02-29 12:00", GranularityWeek, "2024-02-26 00:00", "2024-W09"},
+		{"周：元旦属于上一年最后一周", "2023-01-01 10:00", GranularityWeek, "2022-12-26 00:00", "2022-W52"},
+		{"周：第53周跨年", "2021-01-03 10:00", GranularityWeek, "2020-12-28 00:00", "2020-W53"},
+		{"周：年末属于下一年第1周", "2024-12-31 10:00", GranularityWeek, "2024-12-30 00:00", "2025-W01"},
+		{"月：月初", "2024-03-01 00:00", GranularityMonth, "2024-03-01 00:00", "2024-03"},
+		{"月：月末最后一刻", "2024-03-31 23:59", GranularityMonth, "2024-03-01 00:00", "2024-03"},
+		{"月：闰年二月", "2024-02-29 12:00", GranularityMonth, "2024-02-01 00:00", "2024-02"},
+		{"月：十二月", "2024-12-31 23:59", GranularityMonth, "2024-12-01 00:00", "2024-12"},
+		{"年：年末", "2024-12-31 23:59", GranularityYear, "2024-01-01 00:00", "2024"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := truncateToBucket(at(tt.date), tt.granularity)
+			if want := at(tt.start); !start.Equal(want) || start.Location() != shanghai {
+				t.Errorf("truncateToBucket(%s, %s) = %s, want %s", tt.date, tt.granularity, start, want)
+			}
+			if label := bucketLabel(start, tt.granularity); label != tt.label {
+				t.Errorf("bucketLabel = %s, want %s", label, tt.label)
+			}
+		})
+	}
+}
+
+func TestShiftBucketFromMonthEnd(t *testing.T) {
+	// 区间起始日总是月初，按月移动不会因月末天数不同而跳月
+	start := truncateToBucket(time.Date(2024, 1, 31, 15, 0, 0, 0, time.UTC), GranularityMonth)
+	tests := []struct {
+		n    int
+		want time.Time
+	}{
+		{1, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{2, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{-1, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := shiftBucket(start, GranularityMonth, tt.n); !got.Equal(tt.want) {
+			t.Errorf("shiftBucket(%d) = %s, want %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestGetRangeStatisticsPartialFirstBucket(t *testing.T) {
+	mock := setupMockDB(t)
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	// 四月完整一个月作为上期，五月从 15 日开始截断，六月完整
+	mock.ExpectQuery(`FROM "user_day_stats"`).
+		WithArgs("2024-04-01", "2024-06-30").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "date", "day_kwh", "night_kwh", "total_amount", "record_count"}).
+			AddRow(0, "2024-04-10", "10", "0", 1000, 1).
+			AddRow(0, "2024-05-02", "20", "0", 2000, 1).
+			AddRow(0, "2024-05-20", "5", "0", 500, 1).
+			AddRow(0, "2024-06-05", "30", "0", 3000, 1))
+
+	r := StatisticsRange{
+		From:        time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC),
+		Granularity: GranularityMonth,
+		Location:    time.UTC,
+	}
+	result, err := GetRangeStatistics(c, 0, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buckets := result["buckets"].([]map[string]interface{})
+	if len(buckets) != 2 {
+		t.Fatalf("len(buckets) = %d, want 2", len(buckets))
+	}
+	if buckets[0]["start"] != "2024-05-15" || buckets[0]["delta"] != nil {
+		t.Errorf("截断的第一个区间不应有环比: %+v", buckets[0])
+	}
+	// 六月与完整的五月（25 度）比较，而不是截断后的 5 度
+	delta := buckets[1]["delta"].(map[string]interface{})
+	if got := delta["total_kwh"].(decimal.Decimal); !got.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("delta.total_kwh = %s, want 5", got)
+	}
+}
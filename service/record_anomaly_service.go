@@ -0,0 +1,255 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"shared-charge/config"
+	"shared-charge/models"
+	"shared-charge/utils"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// historySampleSize 历史偏离检查使用的最近记录数
+const historySampleSize = 50
+
+// AnomalyFinding 单项异常检查结果
+type AnomalyFinding struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Weight  int    `json:"weight"`
+}
+
+// anomalyCheck 异常检查函数，db 为创建或修改记录所在的事务，未命中时返回 nil
+type anomalyCheck func(db *gorm.DB, record *models.Record, reservation *models.Reservation) (*AnomalyFinding, error)
+
+// recordAnomalyChecks 创建或修改充电记录时依次执行的检查项
+var recordAnomalyChecks = []anomalyCheck{
+	checkMaxPower,
+	checkUserHistory,
+	checkDuplicateKwh,
+	checkReservationDate,
+	checkOCRMismatch,
+}
+
+// ScoreRecordAnomalies 对记录执行全部异常检查，返回总分与命中项
+func ScoreRecordAnomalies(c *gin.Context, db *gorm.DB, record *models.Record, reservation *models.Reservation) (int, []AnomalyFinding) {
+	score := 0
+	findings := make([]AnomalyFinding, 0)
+	for _, check := range recordAnomalyChecks {
+		// 每项检查在独立的保存点内执行，查询失败不会中止外层事务
+		var finding *AnomalyFinding
+		err := db.Transaction(func(sp *gorm.DB) error {
+			var err error
+			finding, err = check(sp, record, reservation)
+			return err
+		})
+		if err != nil {
+			utils.WarnCtx(c, "充电记录异常检查失败，跳过该项: user_id=%d, err=%v", record.UserID, err)
+			continue
+		}
+		if finding != nil {
+			score += finding.Weight
+			findings = append(findings, *finding)
+		}
+	}
+	return score, findings
+}
+
+// encodeAnomalyReasons 将命中项编码为 jsonb 字段值，无命中时返回 nil 以写入 NULL（空字符串不是合法的 JSON）
+func encodeAnomalyReasons(findings []AnomalyFinding) *string {
+	if len(findings) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(findings)
+	if err != nil {
+		return nil
+	}
+	reasons := string(data)
+	return &reasons
+}
+
+// applyAnomalyScore 在创建或修改记录的事务内写入异常分数与原因，达到阈值的记录强制进入人工审核
+func applyAnomalyScore(c *gin.Context, tx *gorm.DB, record *models.Record, reservation *models.Reservation) {
+	score, findings := ScoreRecordAnomalies(c, tx, record, reservation)
+	record.AnomalyScore = score
+	record.AnomalyReasons = encodeAnomalyReasons(findings)
+	if score >= config.GetConfig().Anomaly.FlagScoreThreshold && score > 0 {
+		utils.WarnCtx(c, "充电记录被标记为异常: user_id=%d, score=%d, reasons=%s", record.UserID, score, record.AnomalyReasonsJSON())
+		record.ReviewStatus = models.ReviewStatusSubmitted
+		record.ReviewedAt = nil
+		record.ReviewedBy = nil
+	}
+}
+
+// rescoreRecordAnomalies 成员修改记录后在同一事务内按修改后的值重新评分，达到阈值的记录回到待审核
+func rescoreRecordAnomalies(c *gin.Context, tx *gorm.DB, record *models.Record) error {
+	var reservation *models.Reservation
+	if record.ReservationID != 0 {
+		var linked models.Reservation
+		err := tx.First(&linked, record.ReservationID).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err == nil {
+			reservation = &linked
+		}
+	}
+	applyAnomalyScore(c, tx, record, reservation)
+	return nil
+}
+
+// checkMaxPower 度数超过充电桩最大功率 × 时段时长
+func checkMaxPower(_ *gorm.DB, record *models.Record, _ *models.Reservation) (*AnomalyFinding, error) {
+	cfg := config.GetConfig().Anomaly
+	if cfg.ChargerMaxPowerKW <= 0 || cfg.SlotHours <= 0 {
+		return nil, nil
+	}
+	limit := cfg.ChargerMaxPowerKW * cfg.SlotHours
+	if record.KWH.InexactFloat64() <= limit {
+		return nil, nil
+	}
+	return &AnomalyFinding{
+		Code:    "exceeds_max_power",
+		Message: fmt.Sprintf("充电度数 %s 超过充电桩单时段上限 %.2f", record.KWH.StringFixed(models.KWHPlaces), limit),
+		Weight:  3,
+	}, nil
+}
+
+// anomalyHistory 异常检查参考的历史记录：未作废且未被驳回
+func anomalyHistory(db *gorm.DB) *gorm.DB {
+	return db.Model(&models.Record{}).Scopes(models.ActiveRecords).
+		Where("review_status != ?", models.ReviewStatusRejected)
+}
+
+// checkUserHistory 度数明显偏离该用户的历史记录
+func checkUserHistory(db *gorm.DB, record *models.Record, _ *models.Reservation) (*AnomalyFinding, error) {
+	cfg := config.GetConfig().Anomaly
+	var history []float64
+	err := anomalyHistory(db).
+		Where("user_id = ? AND id != ?", record.UserID, record.ID).
+		Order("date DESC").
+		Limit(historySampleSize).
+		Pluck("kwh", &history).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(history) < cfg.HistoryMinSamples || len(history) == 0 {
+		return nil, nil
+	}
+	mean, std := meanAndStdDev(history)
+	// 历史记录非常稳定时标准差接近0，取均值的10%作为下限避免误报
+	std = math.Max(std, mean*0.1)
+	if std == 0 || math.Abs(record.KWH.InexactFloat64()-mean) <= cfg.HistoryFactor*std {
+		return nil, nil
+	}
+	return &AnomalyFinding{
+		Code:    "deviates_from_history",
+		Message: fmt.Sprintf("充电度数 %s 明显偏离历史均值 %.2f（标准差 %.2f）", record.KWH.StringFixed(models.KWHPlaces), mean, std),
+		Weight:  2,
+	}, nil
+}
+
+// checkDuplicateKwh 同一日期已存在相同度数的记录（可能重复上传同一截图）
+func checkDuplicateKwh(db *gorm.DB, record *models.Record, _ *models.Reservation) (*AnomalyFinding, error) {
+	var duplicates []models.Record
+	err := anomalyHistory(db).Select("id, user_id").
+		Where("date = ? AND kwh = ? AND id != ?", record.Date.Format("2006-01-02"), record.KWH, record.ID).
+		Limit(5).
+		Find(&duplicates).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(duplicates) == 0 {
+		return nil, nil
+	}
+	sameUser := false
+	for _, duplicate := range duplicates {
+		if duplicate.UserID == record.UserID {
+			sameUser = true
+			break
+		}
+	}
+	message := fmt.Sprintf("%s 已有 %d 条相同度数(%s)的记录", record.Date.Format("2006-01-02"), len(duplicates), record.KWH.StringFixed(models.KWHPlaces))
+	if sameUser {
+		message += "，包含本人的记录"
+	}
+	return &AnomalyFinding{Code: "duplicate_kwh_date", Message: message, Weight: 2}, nil
+}
+
+// checkReservationDate 记录日期不在预约日期内（夜班允许次日）
+func checkReservationDate(_ *gorm.DB, record *models.Record, reservation *models.Reservation) (*AnomalyFinding, error) {
+	if reservation == nil {
+		return nil, nil
+	}
+	recordDate := record.Date.Format("2006-01-02")
+	if recordDate == reservation.Date.Format("2006-01-02") {
+		return nil, nil
+	}
+	if reservation.Timeslot == "night" && recordDate == reservation.Date.AddDate(0, 0, 1).Format("2006-01-02") {
+		return nil, nil
+	}
+	return &AnomalyFinding{
+		Code:    "outside_reservation_date",
+		Message: fmt.Sprintf("记录日期 %s 与预约日期 %s 不符", recordDate, reservation.Date.Format("2006-01-02")),
+		Weight:  3,
+	}, nil
+}
+
+// checkOCRMismatch 填写度数与截图识别度数不一致
+func checkOCRMismatch(_ *gorm.DB, record *models.Record, _ *models.Reservation) (*AnomalyFinding, error) {
+	if !IsOCRMismatch(record) {
+		return nil, nil
+	}
+	return &AnomalyFinding{
+		Code:    "ocr_mismatch",
+		Message: fmt.Sprintf("填写度数 %s 与截图识别度数 %s 不一致", record.KWH.StringFixed(models.KWHPlaces), record.OCRKWH.StringFixed(models.KWHPlaces)),
+		Weight:  2,
+	}, nil
+}
+
+// meanAndStdDev 计算均值与总体标准差
+func meanAndStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+// GetFlaggedRecords 管理员获取被标记为异常的记录队列
+func GetFlaggedRecords(c *gin.Context, status string) ([]map[string]interface{}, error) {
+	if status == "" {
+		status = models.ReviewStatusSubmitted
+	}
+	utils.InfoCtx(c, "查询异常充电记录: status=%s", status)
+	var records []models.Record
+	err := models.DB.Scopes(models.ActiveRecords).Where("anomaly_score >= ? AND anomaly_score > 0 AND review_status = ?", config.GetConfig().Anomaly.FlagScoreThreshold, status).
+		Preload("User").
+		Preload("LicensePlate").
+		Order("anomaly_score DESC, created_at ASC").
+		Find(&records).Error
+	if err != nil {
+		utils.ErrorCtx(c, "查询异常充电记录失败: %v", err)
+		return nil, err
+	}
+	resp := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		info := record.FormatRecordInfo()
+		info["user_name"] = record.User.Name
+		info["user_avatar"] = record.User.Avatar
+		resp = append(resp, info)
+	}
+	return resp, nil
+}
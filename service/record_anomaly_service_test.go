@@ -0,0 +1,156 @@
+package service
+
+import (
+	"encoding/json"
+	"shared-charge/models"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func TestEncodeAnomalyReasons(t *testing.T) {
+	if got := encodeAnomalyReasons(nil); got != nil {
+		t.Fatalf("无命中项应返回 nil，实际为 %q", *got)
+	}
+	got := encodeAnomalyReasons([]AnomalyFinding{{Code: "ocr_mismatch", Message: "不一致", Weight: 2}})
+	if got == nil || !json.Valid([]byte(*got)) {
+		t.Fatalf("命中项应编码为合法 JSON，实际为 %v", got)
+	}
+}
+
+// insertValue 返回 INSERT 语句中指定列绑定的参数
+func insertValue(t *testing.T, stmt *gorm.Statement, column string) interface{} {
+	t.Helper()
+	sql := stmt.SQL.String()
+	start, end := strings.Index(sql, "("), strings.Index(sql, ")")
+	if start < 0 || end < start {
+		t.Fatalf("无法解析 INSERT 语句: %s", sql)
+	}
+	for i, name := range strings.Split(sql[start+1:end], ",") {
+		if strings.Trim(name, ` "`) == column {
+			return stmt.Vars[i]
+		}
+	}
+	t.Fatalf("INSERT 语句缺少列 %s: %s", column, sql)
+	return nil
+}
+
+func TestApplyAnomalyScoreCleanRecordInsertsNullReasons(t *testing.T) {
+	mock := setupMockDB(t)
+	record := &models.Record{
+		UserID:       1,
+		Date:         time.Date(2024, 5, 10, 0, 0, 0, 0, time.Local),
+		KWH:          decimal.RequireFromString("20.5"),
+		UnitPrice:    decimal.RequireFromString("0.6"),
+		ReviewStatus: models.ReviewStatusApproved,
+	}
+
+	mock.ExpectBegin()
+	// checkMaxPower
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	// checkUserHistory：在事务内查询，且不参考已驳回的记录
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT "kwh" FROM "records" WHERE .*review_status != \$1.*user_id = \$2`).
+		WithArgs(models.ReviewStatusRejected, record.UserID, record.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"kwh"}))
+	// checkDuplicateKwh
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT id, user_id FROM "records" WHERE .*review_status != \$1.*date = \$2`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+	// checkReservationDate、checkOCRMismatch
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	var stmt *gorm.Statement
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		applyAnomalyScore(nil, tx, record, nil)
+		stmt = tx.Session(&gorm.Session{DryRun: true}).Create(record).Statement
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("事务执行失败: %v", err)
+	}
+	if record.AnomalyScore != 0 || record.AnomalyReasons != nil {
+		t.Fatalf("正常记录不应有异常: score=%d, reasons=%v", record.AnomalyScore, record.AnomalyReasons)
+	}
+	if record.ReviewStatus != models.ReviewStatusApproved {
+		t.Fatalf("正常记录审核状态不应改变: %s", record.ReviewStatus)
+	}
+	// jsonb 列必须写入 NULL，空字符串会被 Postgres 拒绝
+	value := insertValue(t, stmt, "anomaly_reasons")
+	if reasons, ok := value.(*string); value != nil && (!ok || reasons != nil) {
+		t.Fatalf("anomaly_reasons 应写入 NULL，实际为 %#v", value)
+	}
+	if string(record.AnomalyReasonsJSON()) != "[]" {
+		t.Fatalf("无异常时应返回空数组，实际为 %s", record.AnomalyReasonsJSON())
+	}
+}
+
+func TestApplyRecordUpdateMemberEditRescoresAnomalies(t *testing.T) {
+	mock := setupMockDB(t)
+	date := time.Date(2024, 5, 10, 0, 0, 0, 0, time.Local)
+	stored := models.Record{
+		ID:            5,
+		UserID:        1,
+		Date:          date,
+		Timeslot:      "day",
+		KWH:           decimal.RequireFromString("20"),
+		UnitPrice:     decimal.RequireFromString("0.5"),
+		Amount:        1000,
+		GrossAmount:   1000,
+		ReservationID: 9,
+		ReviewStatus:  models.ReviewStatusApproved,
+	}
+	record := stored
+	// 默认 auto 策略下修改后的记录先按 approved 处理
+	record.ReviewStatus = models.ReviewStatusApproved
+	req := UpdateRecordRequest{KWH: decimal.RequireFromString("100"), Reason: "改度数"}
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "reservations" WHERE "reservations"."id" = \$1`).
+		WithArgs(uint(9)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "date", "timeslot"}).AddRow(9, 1, date, "day"))
+	// checkMaxPower：100 度超过默认 7kW × 12h，按修改后的度数判断
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT "kwh" FROM "records"`).
+		WillReturnRows(sqlmock.NewRows([]string{"kwh"}))
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT id, user_id FROM "records" WHERE .*date = \$2 AND kwh = \$3`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("SAVEPOINT").WillReturnResult(sqlmock.NewResult(0, 0))
+	// 重新评分在计算金额之前
+	mock.ExpectQuery(`SELECT \* FROM "discount_rules" WHERE .* FOR SHARE`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectExec(`UPDATE "records" SET .*"review_status"=\$\d+.*"anomaly_score"=\$\d+,"anomaly_reasons"=\$\d+`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// 原记录已计入统计，修改后转为待审核，只扣除不再计入
+	mock.ExpectQuery(`INSERT INTO "user_day_stats"`).
+		WithArgs(uint(1), sqlmock.AnyArg(), uint(0), decimal.RequireFromString("-20"), decimal.Zero, decimal.RequireFromString("-20"), int64(-1000), int64(-1), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO "user_month_stats"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(`DELETE FROM "record_discounts"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO "record_revisions"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery(`INSERT INTO "audit_logs"`).WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		return applyRecordUpdate(nil, tx, stored, &record, req, stored.UserID, models.RevisionSourceMember, req.Reason)
+	})
+	if err != nil {
+		t.Fatalf("修改记录失败: %v", err)
+	}
+	if record.AnomalyScore < 3 || record.AnomalyReasons == nil || !strings.Contains(*record.AnomalyReasons, "exceeds_max_power") {
+		t.Fatalf("应按修改后的度数标记异常: score=%d, reasons=%v", record.AnomalyScore, record.AnomalyReasons)
+	}
+	if record.ReviewStatus != models.ReviewStatusSubmitted || record.ReviewedAt != nil {
+		t.Fatalf("异常记录应保持待审核: status=%s, reviewed_at=%v", record.ReviewStatus, record.ReviewedAt)
+	}
+}
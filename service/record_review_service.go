@@ -0,0 +1,152 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"shared-charge/config"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 审核策略
+const (
+	ReviewPolicyAuto   = "auto"
+	ReviewPolicyManual = "manual"
+)
+
+// initialReviewStatus 根据审核策略确定新提交记录的审核状态
+func initialReviewStatus() string {
+	if config.GetConfig().Review.Policy == ReviewPolicyManual {
+		return models.ReviewStatusSubmitted
+	}
+	return models.ReviewStatusApproved
+}
+
+// editedReviewStatus 成员修改记录后的审核状态
+// 已驳回或被标记为异常的记录只能回到待审核，由管理员重新处理；其余记录按审核策略
+func editedReviewStatus(stored models.Record) string {
+	threshold := config.GetConfig().Anomaly.FlagScoreThreshold
+	if stored.IsRejected() || (stored.AnomalyScore > 0 && stored.AnomalyScore >= threshold) {
+		return models.ReviewStatusSubmitted
+	}
+	return initialReviewStatus()
+}
+
+// GetRecordsForReview 管理员按审核状态获取充电记录
+func GetRecordsForReview(c *gin.Context, status string) ([]map[string]interface{}, error) {
+	if status == "" {
+		status = models.ReviewStatusSubmitted
+	}
+	utils.InfoCtx(c, "查询待审核充电记录: status=%s", status)
+	var records []models.Record
+	err := models.DB.Scopes(models.ActiveRecords).Where("review_status = ?", status).
+		Preload("User").
+		Preload("LicensePlate").
+		Order("date ASC, created_at ASC").
+		Find(&records).Error
+	if err != nil {
+		utils.ErrorCtx(c, "查询待审核充电记录失败: %v", err)
+		return nil, err
+	}
+	resp := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		info := record.FormatRecordInfo()
+		info["user_name"] = record.User.Name
+		info["user_avatar"] = record.User.Avatar
+		resp = append(resp, info)
+	}
+	return resp, nil
+}
+
+// ApproveRecords 批量审核通过充电记录
+func ApproveRecords(c *gin.Context, reviewerID uint, recordIDs []uint) (int, error) {
+	return reviewRecords(c, reviewerID, recordIDs, models.ReviewStatusApproved, "")
+}
+
+// RejectRecords 批量驳回充电记录，必须填写原因
+func RejectRecords(c *gin.Context, reviewerID uint, recordIDs []uint, reason string) (int, error) {
+	if reason == "" {
+		return 0, errors.New("驳回原因不能为空")
+	}
+	return reviewRecords(c, reviewerID, recordIDs, models.ReviewStatusRejected, reason)
+}
+
+// reviewRecords 在同一事务内更新审核状态并通知记录所属用户
+func reviewRecords(c *gin.Context, reviewerID uint, recordIDs []uint, status, reason string) (int, error) {
+	if len(recordIDs) == 0 {
+		return 0, errors.New("请选择要审核的记录")
+	}
+	utils.InfoCtx(c, "审核充电记录: reviewer_id=%d, status=%s, count=%d", reviewerID, status, len(recordIDs))
+	var records []models.Record
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Scopes(models.ActiveRecords).
+			Where("id IN ? AND review_status != ?", recordIDs, status).
+			Order("id ASC").
+			Find(&records).Error
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		for _, record := range records {
+			// Updates 会同时修改 record 上的字段，先保留审核前的副本
+			before := record
+			if err := tx.Model(&record).Updates(map[string]interface{}{
+				"review_status": status,
+				"review_reason": reason,
+				"reviewed_by":   reviewerID,
+				"reviewed_at":   now,
+			}).Error; err != nil {
+				return err
+			}
+			// 统计表只计入审核通过的记录
+			if err := replaceRecordStats(tx, before, record); err != nil {
+				return err
+			}
+			action := AuditRecordApprove
+			if status == models.ReviewStatusRejected {
+				action = AuditRecordReject
+			}
+			if err := writeAudit(c, tx, action, AuditTargetRecord, record.ID,
+				map[string]interface{}{"review_status": before.ReviewStatus, "amount": before.Amount},
+				map[string]interface{}{"review_status": status, "review_reason": reason}); err != nil {
+				return err
+			}
+			if err := notifyReviewResult(tx, record, status, reason); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "审核充电记录失败: %v", err)
+		return 0, err
+	}
+	reviewed := len(records)
+	dates := make([]time.Time, 0, reviewed)
+	for _, record := range records {
+		dates = append(dates, record.Date)
+	}
+	invalidateMonthlyReportDates(dates...)
+	utils.InfoCtx(c, "审核充电记录完成: reviewer_id=%d, status=%s, reviewed=%d", reviewerID, status, reviewed)
+	return reviewed, nil
+}
+
+// notifyReviewResult 通知用户审核结果
+func notifyReviewResult(tx *gorm.DB, record models.Record, status, reason string) error {
+	date := record.Date.Format("2006-01-02")
+	if status == models.ReviewStatusApproved {
+		return CreateNotification(tx, record.UserID, NotificationRecordApproved,
+			"充电记录审核通过",
+			fmt.Sprintf("您 %s 的充电记录（%s 度）已审核通过", date, record.KWH.StringFixed(models.KWHPlaces)),
+			record.ID)
+	}
+	return CreateNotification(tx, record.UserID, NotificationRecordRejected,
+		"充电记录被驳回",
+		fmt.Sprintf("您 %s 的充电记录（%s 度）被驳回，原因：%s。请修改后重新提交", date, record.KWH.StringFixed(models.KWHPlaces), reason),
+		record.ID)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"shared-charge/config"
+	"shared-charge/models"
+	"testing"
+)
+
+func TestEditedReviewStatus(t *testing.T) {
+	config.LoadConfig()
+	cfg := config.GetConfig()
+	previousPolicy, previousThreshold := cfg.Review.Policy, cfg.Anomaly.FlagScoreThreshold
+	cfg.Anomaly.FlagScoreThreshold = 2
+	t.Cleanup(func() {
+		cfg.Review.Policy = previousPolicy
+		cfg.Anomaly.FlagScoreThreshold = previousThreshold
+	})
+
+	tests := []struct {
+		name   string
+		policy string
+		stored models.Record
+		want   string
+	}{
+		{"自动策略：已通过", ReviewPolicyAuto, models.Record{ReviewStatus: models.ReviewStatusApproved}, models.ReviewStatusApproved},
+		{"自动策略：待审核", ReviewPolicyAuto, models.Record{ReviewStatus: models.ReviewStatusSubmitted}, models.ReviewStatusApproved},
+		{"自动策略：已驳回", ReviewPolicyAuto, models.Record{ReviewStatus: models.ReviewStatusRejected}, models.ReviewStatusSubmitted},
+		{"自动策略：被标记异常", ReviewPolicyAuto, models.Record{ReviewStatus: models.ReviewStatusSubmitted, AnomalyScore: 3}, models.ReviewStatusSubmitted},
+		{"自动策略：异常已被通过", ReviewPolicyAuto, models.Record{ReviewStatus: models.ReviewStatusApproved, AnomalyScore: 2}, models.ReviewStatusSubmitted},
+		{"自动策略：分数低于阈值", ReviewPolicyAuto, models.Record{ReviewStatus: models.ReviewStatusApproved, AnomalyScore: 1}, models.ReviewStatusApproved},
+		{"人工策略：已通过", ReviewPolicyManual, models.Record{ReviewStatus: models.ReviewStatusApproved}, models.ReviewStatusSubmitted},
+		{"人工策略：已驳回", ReviewPolicyManual, models.Record{ReviewStatus: models.ReviewStatusRejected}, models.ReviewStatusSubmitted},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Review.Policy = tt.policy
+			if got := editedReviewStatus(tt.stored); got != tt.want {
+				t.Errorf("editedReviewStatus = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
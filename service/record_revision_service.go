@@ -0,0 +1,337 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"shared-charge/config"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 更正申请相关通知类型
+const (
+	NotificationCorrectionApproved = "correction_approved"
+	NotificationCorrectionRejected = "correction_rejected"
+)
+
+// withinEditWindow 判断记录是否仍在用户可直接修改的期限内
+func withinEditWindow(record models.Record) bool {
+	hours := config.GetConfig().Record.EditWindowHours
+	if hours <= 0 {
+		return true
+	}
+	return time.Since(record.CreatedAt) <= time.Duration(hours)*time.Hour
+}
+
+// recordSnapshot 提取记录中可被修改的字段
+func recordSnapshot(record *models.Record) map[string]interface{} {
+	var licensePlateID interface{}
+	if record.LicensePlateID != nil {
+		licensePlateID = *record.LicensePlateID
+	}
+	return map[string]interface{}{
+		"kwh":              record.KWH,
+		"amount":           record.Amount,
+		"discount_amount":  record.DiscountAmount,
+		"image_url":        record.ImageURL,
+		"remark":           record.Remark,
+		"license_plate_id": licensePlateID,
+	}
+}
+
+// diffSnapshots 仅保留发生变化的字段
+func diffSnapshots(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	oldValues := make(map[string]interface{})
+	newValues := make(map[string]interface{})
+	for key, value := range after {
+		if fmt.Sprint(before[key]) != fmt.Sprint(value) {
+			oldValues[key] = before[key]
+			newValues[key] = value
+		}
+	}
+	return oldValues, newValues
+}
+
+// lockRecord 在事务内以 FOR UPDATE 锁定并读取充电记录
+func lockRecord(tx *gorm.DB, query interface{}, args ...interface{}) (models.Record, error) {
+	var record models.Record
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where(query, args...).First(&record).Error
+	return record, err
+}
+
+// applyRecordUpdate 在事务内应用修改、重算费用并写入修订历史和审计日志
+// stored 为调用方在同一事务内通过 lockRecord 读取的记录；record 为其副本，审核相关字段由调用方预先设置，随修改一并保存
+func applyRecordUpdate(c *gin.Context, tx *gorm.DB, stored models.Record, record *models.Record, req UpdateRecordRequest, actorID uint, source, reason string) error {
+	before := recordSnapshot(&stored)
+	record.KWH = models.RoundDecimal(req.KWH, models.KWHPlaces)
+	record.Remark = req.Remark
+	if req.ImageURL != "" {
+		record.ImageURL = req.ImageURL
+	}
+	if req.LicensePlateID != nil {
+		record.LicensePlateID = req.LicensePlateID
+	}
+	// 成员自行修改的内容未经管理员确认，需按修改后的值重新做异常检查
+	if source == models.RevisionSourceMember {
+		if err := rescoreRecordAnomalies(c, tx, record); err != nil {
+			return err
+		}
+	}
+	discounts, err := calculateRecordAmount(tx, record)
+	if err != nil {
+		return err
+	}
+	oldValues, newValues := diffSnapshots(before, recordSnapshot(record))
+
+	err = tx.Model(record).
+		Select("kwh", "remark", "image_url", "license_plate_id", "amount", "gross_amount", "discount_amount", "review_status", "review_reason", "reviewed_by", "reviewed_at", "anomaly_score", "anomaly_reasons", "updated_at").
+		Updates(record).Error
+	if err != nil {
+		return err
+	}
+	if err := replaceRecordStats(tx, stored, *record); err != nil {
+		return err
+	}
+	if err := saveRecordDiscounts(tx, record.ID, discounts); err != nil {
+		return err
+	}
+	if len(newValues) == 0 {
+		return nil
+	}
+	if err := createRecordRevision(tx, record.ID, actorID, source, reason, oldValues, newValues); err != nil {
+		return err
+	}
+	return writeAudit(c, tx, AuditRecordUpdate, AuditTargetRecord, record.ID, oldValues, newValues)
+}
+
+// createRecordRevision 写入一条修订历史
+func createRecordRevision(tx *gorm.DB, recordID, actorID uint, source, reason string, oldValues, newValues map[string]interface{}) error {
+	oldJSON, err := json.Marshal(oldValues)
+	if err != nil {
+		return err
+	}
+	newJSON, err := json.Marshal(newValues)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&models.RecordRevision{
+		RecordID:  recordID,
+		ChangedBy: actorID,
+		Source:    source,
+		OldValues: string(oldJSON),
+		NewValues: string(newJSON),
+		Reason:    reason,
+	}).Error
+}
+
+// GetRecordRevisions 获取记录修订历史，userID 为 0 时不校验记录归属（管理员）
+func GetRecordRevisions(c *gin.Context, userID uint, recordID uint) ([]map[string]interface{}, error) {
+	query := models.DB.Model(&models.Record{}).Where("id = ?", recordID)
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("充电记录不存在")
+	}
+
+	var revisions []models.RecordRevision
+	err := models.DB.Where("record_id = ?", recordID).Preload("User").Order("created_at ASC, id ASC").Find(&revisions).Error
+	if err != nil {
+		utils.ErrorCtx(c, "查询修订历史失败: record_id=%d, err=%v", recordID, err)
+		return nil, err
+	}
+	resp := make([]map[string]interface{}, 0, len(revisions))
+	for _, revision := range revisions {
+		resp = append(resp, map[string]interface{}{
+			"id":              revision.ID,
+			"changed_by":      revision.ChangedBy,
+			"changed_by_name": revision.User.Name,
+			"source":          revision.Source,
+			"old_values":      json.RawMessage(revision.OldValues),
+			"new_values":      json.RawMessage(revision.NewValues),
+			"reason":          revision.Reason,
+			"created_at":      revision.CreatedAt,
+		})
+	}
+	return resp, nil
+}
+
+// CreateCorrectionRequest 超过编辑期限后提交更正申请
+// 同一记录只能有一条待审核的申请，由部分唯一索引 uniq_record_corrections_pending 保证
+func CreateCorrectionRequest(c *gin.Context, tx *gorm.DB, record models.Record, req UpdateRecordRequest) (*models.RecordCorrectionRequest, error) {
+	changes, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	correction := &models.RecordCorrectionRequest{
+		RecordID: record.ID,
+		UserID:   record.UserID,
+		Changes:  string(changes),
+		Reason:   req.Reason,
+		Status:   models.CorrectionStatusPending,
+	}
+	if err := tx.Create(correction).Error; err != nil {
+		if models.IsUniqueViolation(err, "uniq_record_corrections_pending") {
+			utils.WarnCtx(c, "记录已有待审核的更正申请: record_id=%d", record.ID)
+			return nil, errors.New("该记录已有待审核的更正申请")
+		}
+		utils.ErrorCtx(c, "创建更正申请失败: %v", err)
+		return nil, err
+	}
+	utils.InfoCtx(c, "更正申请已提交: record_id=%d, correction_id=%d", record.ID, correction.ID)
+	return correction, nil
+}
+
+// formatCorrectionRequest 格式化更正申请
+func formatCorrectionRequest(correction models.RecordCorrectionRequest) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":            correction.ID,
+		"record_id":     correction.RecordID,
+		"user_id":       correction.UserID,
+		"changes":       json.RawMessage(correction.Changes),
+		"reason":        correction.Reason,
+		"status":        correction.Status,
+		"review_reason": correction.ReviewReason,
+		"reviewed_at":   correction.ReviewedAt,
+		"created_at":    correction.CreatedAt,
+	}
+	if correction.User.ID != 0 {
+		result["user_name"] = correction.User.Name
+	}
+	if correction.Record.ID != 0 {
+		result["record"] = correction.Record.FormatRecordInfo()
+	}
+	return result
+}
+
+// GetCorrectionRequests 管理员按状态获取更正申请
+func GetCorrectionRequests(c *gin.Context, status string) ([]map[string]interface{}, error) {
+	if status == "" {
+		status = models.CorrectionStatusPending
+	}
+	var corrections []models.RecordCorrectionRequest
+	err := models.DB.Where("status = ?", status).
+		Preload("User").
+		Preload("Record").
+		Order("created_at ASC").
+		Find(&corrections).Error
+	if err != nil {
+		utils.ErrorCtx(c, "查询更正申请失败: %v", err)
+		return nil, err
+	}
+	resp := make([]map[string]interface{}, 0, len(corrections))
+	for _, correction := range corrections {
+		resp = append(resp, formatCorrectionRequest(correction))
+	}
+	return resp, nil
+}
+
+// ApproveCorrectionRequest 管理员通过更正申请，按申请内容修改记录
+func ApproveCorrectionRequest(c *gin.Context, reviewerID, correctionID uint) error {
+	utils.InfoCtx(c, "审核通过更正申请: reviewer_id=%d, correction_id=%d", reviewerID, correctionID)
+	var record models.Record
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		correction, err := findPendingCorrection(tx, correctionID)
+		if err != nil {
+			return err
+		}
+		var req UpdateRecordRequest
+		if err := json.Unmarshal([]byte(correction.Changes), &req); err != nil {
+			return err
+		}
+		stored, err := lockRecord(tx, "id = ?", correction.RecordID)
+		if err != nil {
+			return err
+		}
+		if stored.IsVoided() {
+			return errors.New("充电记录已作废，不能更正")
+		}
+		record = stored
+		// 管理员已核对更正内容，记录视为审核通过
+		now := time.Now()
+		record.ReviewStatus = models.ReviewStatusApproved
+		record.ReviewReason = ""
+		record.ReviewedBy = &reviewerID
+		record.ReviewedAt = &now
+		if err := applyRecordUpdate(c, tx, stored, &record, req, reviewerID, models.RevisionSourceCorrection, correction.Reason); err != nil {
+			return err
+		}
+		if err := finishCorrection(tx, &correction, reviewerID, models.CorrectionStatusApproved, ""); err != nil {
+			return err
+		}
+		if err := writeAudit(c, tx, AuditCorrectionApprove, AuditTargetCorrection, correction.ID,
+			map[string]interface{}{"status": models.CorrectionStatusPending},
+			map[string]interface{}{"status": models.CorrectionStatusApproved, "record_id": correction.RecordID}); err != nil {
+			return err
+		}
+		return CreateNotification(tx, correction.UserID, NotificationCorrectionApproved,
+			"更正申请已通过",
+			fmt.Sprintf("您 %s 的充电记录更正申请已通过", record.Date.Format("2006-01-02")),
+			record.ID)
+	})
+	if err == nil {
+		invalidateMonthlyReportDates(record.Date)
+	}
+	return err
+}
+
+// RejectCorrectionRequest 管理员驳回更正申请
+func RejectCorrectionRequest(c *gin.Context, reviewerID, correctionID uint, reason string) error {
+	if reason == "" {
+		return errors.New("驳回原因不能为空")
+	}
+	utils.InfoCtx(c, "驳回更正申请: reviewer_id=%d, correction_id=%d", reviewerID, correctionID)
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		correction, err := findPendingCorrection(tx, correctionID)
+		if err != nil {
+			return err
+		}
+		if err := finishCorrection(tx, &correction, reviewerID, models.CorrectionStatusRejected, reason); err != nil {
+			return err
+		}
+		if err := writeAudit(c, tx, AuditCorrectionReject, AuditTargetCorrection, correction.ID,
+			map[string]interface{}{"status": models.CorrectionStatusPending},
+			map[string]interface{}{"status": models.CorrectionStatusRejected, "record_id": correction.RecordID, "reason": reason}); err != nil {
+			return err
+		}
+		return CreateNotification(tx, correction.UserID, NotificationCorrectionRejected,
+			"更正申请被驳回",
+			fmt.Sprintf("您的充电记录更正申请被驳回，原因：%s", reason),
+			correction.RecordID)
+	})
+}
+
+// findPendingCorrection 锁定并查找待审核的更正申请，避免并发审核重复处理同一申请
+func findPendingCorrection(tx *gorm.DB, correctionID uint) (models.RecordCorrectionRequest, error) {
+	var correction models.RecordCorrectionRequest
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&correction, correctionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return correction, errors.New("更正申请不存在")
+		}
+		return correction, err
+	}
+	if !correction.IsPending() {
+		return correction, errors.New("更正申请已处理")
+	}
+	return correction, nil
+}
+
+// finishCorrection 更新更正申请的审核结果
+func finishCorrection(tx *gorm.DB, correction *models.RecordCorrectionRequest, reviewerID uint, status, reason string) error {
+	return tx.Model(correction).Updates(map[string]interface{}{
+		"status":        status,
+		"review_reason": reason,
+		"reviewed_by":   reviewerID,
+		"reviewed_at":   time.Now(),
+	}).Error
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"shared-charge/models"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"gorm.io/gorm"
+)
+
+func TestFindPendingCorrectionLocksRow(t *testing.T) {
+	mock := setupMockDB(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "record_correction_requests" WHERE .* FOR UPDATE`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "record_id", "status"}).AddRow(7, 3, models.CorrectionStatusApproved))
+	mock.ExpectRollback()
+
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		_, err := findPendingCorrection(tx, 7)
+		return err
+	})
+	if err == nil || err.Error() != "更正申请已处理" {
+		t.Fatalf("已处理的申请应返回错误，实际为 %v", err)
+	}
+}
@@ -4,10 +4,11 @@ import (
 	"errors"
 	"shared-charge/models"
 	"shared-charge/utils"
-	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 const defaultLimit = 50
@@ -35,29 +36,29 @@ func GetRecentRecordsByUser(userID uint, limit int) ([]models.Record, error) {
 		limit = defaultLimit
 	}
 	var records []models.Record
-	err := models.DB.Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&records).Error
+	err := models.DB.Scopes(models.ActiveRecords).Where("user_id = ?", userID).Order("created_at DESC").Limit(limit).Find(&records).Error
 	return records, err
 }
 
 // 创建充电记录（自动查预约表获取 timeslot）
 type CreateRecordRequest struct {
 	Date           string
-	KWH            float64
+	KWH            decimal.Decimal
 	ImageURL       string
 	Remark         string
 	ReservationID  uint
-	UnitPrice      float64
+	UnitPrice      decimal.Decimal
 	UserID         uint
 	Timeslot       string
 	LicensePlateID *uint
 }
 
-func CreateRecordWithTimeslot(c *gin.Context, req CreateRecordRequest) error {
+func CreateRecordWithTimeslot(c *gin.Context, req CreateRecordRequest) (*models.Record, error) {
 	utils.InfoCtx(c, "创建充电记录: user_id=%d, date=%s, kwh=%v, reservation_id=%d, image_url=%s", req.UserID, req.Date, req.KWH, req.ReservationID, req.ImageURL)
 	date, err := time.ParseInLocation("2006-01-02", req.Date, time.Local)
 	if err != nil {
 		utils.WarnCtx(c, "创建充电记录日期格式错误: %v", err)
-		return err
+		return nil, err
 	}
 	timeslot := req.Timeslot
 	if req.ReservationID != 0 && timeslot == "" {
@@ -70,23 +71,25 @@ func CreateRecordWithTimeslot(c *gin.Context, req CreateRecordRequest) error {
 		}
 	}
 	// 新增：校验预约必须为pending状态，且一个预约只能有一条record
+	var linkedReservation *models.Reservation
 	if req.ReservationID != 0 {
 		var reservation models.Reservation
 		errRes := models.DB.First(&reservation, req.ReservationID).Error
 		if errRes != nil {
 			utils.WarnCtx(c, "预约不存在: reservation_id=%d", req.ReservationID)
-			return errRes
+			return nil, errRes
 		}
 		if reservation.Status != "pending" {
 			utils.WarnCtx(c, "预约状态不是pending: reservation_id=%d, status=%s", req.ReservationID, reservation.Status)
-			return errors.New("预约状态必须为pending")
+			return nil, errors.New("预约状态必须为pending")
 		}
 		var count int64
-		models.DB.Model(&models.Record{}).Where("reservation_id = ?", req.ReservationID).Count(&count)
+		models.DB.Model(&models.Record{}).Scopes(models.ActiveRecords).Where("reservation_id = ?", req.ReservationID).Count(&count)
 		if count > 0 {
 			utils.WarnCtx(c, "该预约已上传过充电记录: reservation_id=%d", req.ReservationID)
-			return errors.New("一个预约只能上传一条充电记录")
+			return nil, errors.New("一个预约只能上传一条充电记录")
 		}
+		linkedReservation = &reservation
 	}
 	// 验证车牌号是否属于当前用户
 	if req.LicensePlateID != nil {
@@ -94,27 +97,57 @@ func CreateRecordWithTimeslot(c *gin.Context, req CreateRecordRequest) error {
 		err := models.DB.Where("id = ? AND user_id = ?", *req.LicensePlateID, req.UserID).First(&licensePlate).Error
 		if err != nil {
 			utils.WarnCtx(c, "车牌号不存在或不属于当前用户: user_id=%d, license_plate_id=%d", req.UserID, *req.LicensePlateID)
-			return errors.New("车牌号不存在或不属于当前用户")
+			return nil, errors.New("车牌号不存在或不属于当前用户")
 		}
 	}
 
 	record := &models.Record{
 		UserID:         req.UserID,
 		Date:           date,
-		KWH:            req.KWH,
+		KWH:            models.RoundDecimal(req.KWH, models.KWHPlaces),
 		UnitPrice:      req.UnitPrice,
 		ImageURL:       req.ImageURL,
 		Remark:         req.Remark,
 		ReservationID:  req.ReservationID,
 		Timeslot:       timeslot,
 		LicensePlateID: req.LicensePlateID,
+		ReviewStatus:   initialReviewStatus(),
+	}
+	if record.IsApproved() {
+		now := time.Now()
+		record.ReviewedAt = &now
 	}
 	utils.InfoCtx(c, "即将写入数据库的 record.ImageURL=%s", record.ImageURL)
-	record.CalculateAmount()
-	errCreate := models.DB.Create(record).Error
+	// 截图识别度数，结果用于与填写度数比对
+	extractMeterReading(c, record)
+	// 异常检测、计算费用并应用优惠规则，与记录在同一事务内保存优惠明细
+	errCreate := models.DB.Transaction(func(tx *gorm.DB) error {
+		// 异常检测：命中的记录进入管理员审核队列
+		applyAnomalyScore(c, tx, record, linkedReservation)
+		discounts, err := calculateRecordAmount(tx, record)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return err
+		}
+		if err := applyRecordStats(tx, *record, 1); err != nil {
+			return err
+		}
+		record.Discounts = discounts
+		if err := saveRecordDiscounts(tx, record.ID, discounts); err != nil {
+			return err
+		}
+		after := recordSnapshot(record)
+		after["user_id"] = record.UserID
+		after["date"] = record.Date.Format("2006-01-02")
+		after["unit_price"] = record.UnitPrice
+		after["review_status"] = record.ReviewStatus
+		return writeAudit(c, tx, AuditRecordCreate, AuditTargetRecord, record.ID, nil, after)
+	})
 	if errCreate != nil {
 		utils.ErrorCtx(c, "充电记录入库失败: %v", errCreate)
-		return errCreate
+		return nil, errCreate
 	}
 	utils.InfoCtx(c, "充电记录创建成功: user_id=%d, record_id=%d, image_url=%s", req.UserID, record.ID, record.ImageURL)
 	// 新增：自动将预约状态设为 completed
@@ -125,7 +158,13 @@ func CreateRecordWithTimeslot(c *gin.Context, req CreateRecordRequest) error {
 			utils.InfoCtx(c, "预约状态已设为 completed: reservation_id=%d, user_id=%d", req.ReservationID, req.UserID)
 		}
 	}
-	return nil
+	// 夜班记录日期可能与预约不在同一个月，两个月份的对账都需要刷新
+	if linkedReservation != nil {
+		invalidateMonthlyReportDates(record.Date, linkedReservation.Date)
+	} else {
+		invalidateMonthlyReportDates(record.Date)
+	}
+	return record, nil
 }
 
 // 获取未提交的充电记录
@@ -137,28 +176,27 @@ func GetUnsubmittedRecords(userID uint) ([]models.Record, error) {
 
 // 统计相关方法略，可根据需要补充
 
-// 获取月度累计用电量和费用
-func GetMonthlyStatistics(userID uint, month string) (float64, float64, error) {
-	var totalKwh, totalCost float64
+// 获取月度累计用电量和费用(分)，读取按月统计表
+func GetMonthlyStatistics(userID uint, month string) (decimal.Decimal, int64, error) {
+	var totalKwh decimal.Decimal
+	var totalCost int64
 
-	// 获取月份日期范围
-	startDate, endDate, err := getMonthDateRange(month)
-	if err != nil {
-		return 0, 0, err
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return decimal.Zero, 0, err
 	}
 
-	err = models.DB.Model(&models.Record{}).
-		Where("user_id = ? AND date >= ? AND date <= ?", userID, startDate, endDate).
-		Select("COALESCE(SUM(kwh),0), COALESCE(SUM(amount),0)").
+	err := models.DB.Model(&models.UserMonthStat{}).
+		Where("user_id = ? AND month = ?", userID, month).
+		Select("COALESCE(SUM(total_kwh),0), COALESCE(SUM(total_amount),0)").
 		Row().Scan(&totalKwh, &totalCost)
 	return totalKwh, totalCost, err
 }
 
-// 获取指定月份每日用电量
+// 获取指定月份每日用电量，读取按日统计表
 func GetDailyStatistics(userID uint, month string) ([]map[string]interface{}, error) {
 	var results []struct {
-		Date     string  `json:"date"`
-		TotalKwh float64 `json:"totalKwh"`
+		Date     string          `json:"date"`
+		TotalKwh decimal.Decimal `json:"totalKwh"`
 	}
 
 	// 获取月份日期范围
@@ -167,10 +205,12 @@ func GetDailyStatistics(userID uint, month string) ([]map[string]interface{}, er
 		return nil, err
 	}
 
-	err = models.DB.Model(&models.Record{}).
-		Select("to_char(date, 'YYYY-MM-DD') as date, COALESCE(SUM(kwh),0) as total_kwh").
+	// 按日统计表按车牌号分行，按日期合计
+	err = models.DB.Model(&models.UserDayStat{}).
+		Select("to_char(date, 'YYYY-MM-DD') as date, SUM(total_kwh) as total_kwh").
 		Where("user_id = ? AND date >= ? AND date <= ?", userID, startDate, endDate).
 		Group("date").
+		Having("SUM(record_count) > 0").
 		Order("date").
 		Scan(&results).Error
 	if err != nil {
@@ -187,34 +227,81 @@ func GetDailyStatistics(userID uint, month string) ([]map[string]interface{}, er
 }
 
 // 获取指定月份白班、夜班和总用电量
-func GetMonthlyShiftStatistics(userID uint, month string) (float64, float64, float64, error) {
-	var result struct {
-		DayKwh   float64 `json:"day_kwh"`
-		NightKwh float64 `json:"night_kwh"`
-	}
-
+func GetMonthlyShiftStatistics(userID uint, month string) (decimal.Decimal, decimal.Decimal, decimal.Decimal, error) {
 	// 获取月份日期范围
 	startDate, endDate, err := getMonthDateRange(month)
 	if err != nil {
-		return 0, 0, 0, err
+		return decimal.Zero, decimal.Zero, decimal.Zero, err
 	}
 
-	// 使用单次查询替代两次独立查询
-	err = models.DB.Model(&models.Record{}).
-		Select(`
-			COALESCE(SUM(CASE WHEN r.timeslot = 'day' THEN records.kwh ELSE 0 END), 0) as day_kwh,
-			COALESCE(SUM(CASE WHEN r.timeslot = 'night' THEN records.kwh ELSE 0 END), 0) as night_kwh
-		`).
-		Joins("LEFT JOIN reservations r ON records.reservation_id = r.id").
-		Where("records.user_id = ? AND records.date >= ? AND records.date <= ?", userID, startDate, endDate).
-		Scan(&result).Error
-
-	if err != nil {
-		return 0, 0, 0, err
+	rows, err := aggregateShiftStatistics(userID, startDate, endDate, shiftGroupNone)
+	if err != nil || len(rows) == 0 {
+		return decimal.Zero, decimal.Zero, decimal.Zero, err
 	}
+	result := rows[0]
+	return result.DayKwh, result.NightKwh, result.TotalKwh(), nil
+}
 
-	totalKwh := result.DayKwh + result.NightKwh
-	return result.DayKwh, result.NightKwh, totalKwh, nil
+// 班次汇总的分组方式
+const (
+	shiftGroupNone  = "" // 不分组
+	shiftGroupUser  = "user"
+	shiftGroupDate  = "date"
+	shiftGroupPlate = "plate"
+)
+
+// ShiftAggregate 按班次汇总的用电量与费用
+type ShiftAggregate struct {
+	UserID         uint
+	Date           string
+	LicensePlateID *uint
+	DayKwh         decimal.Decimal
+	NightKwh       decimal.Decimal
+	TotalAmount    int64
+	RecordCount    int64
+}
+
+// TotalKwh 白班与夜班用电量之和
+func (a ShiftAggregate) TotalKwh() decimal.Decimal {
+	return a.DayKwh.Add(a.NightKwh)
+}
+
+// aggregateShiftStatistics 单次查询汇总日期范围内白班/夜班用电量与费用，读取按日统计表
+// userID 为 0 时统计全部用户；groupBy 指定按用户、日期或车牌号分组返回，未关联车牌号的记录 license_plate_id 为 nil
+func aggregateShiftStatistics(userID uint, startDate, endDate string, groupBy string) ([]ShiftAggregate, error) {
+	selectGroup := "0 as user_id, '' as date,"
+	switch groupBy {
+	case shiftGroupUser:
+		selectGroup = "user_day_stats.user_id, '' as date,"
+	case shiftGroupDate:
+		selectGroup = "0 as user_id, to_char(user_day_stats.date, 'YYYY-MM-DD') as date,"
+	case shiftGroupPlate:
+		selectGroup = "0 as user_id, '' as date, NULLIF(user_day_stats.license_plate_id, 0) as license_plate_id,"
+	}
+	query := models.DB.Model(&models.UserDayStat{}).
+		Select(selectGroup+`
+			COALESCE(SUM(user_day_stats.day_kwh), 0) as day_kwh,
+			COALESCE(SUM(user_day_stats.night_kwh), 0) as night_kwh,
+			COALESCE(SUM(user_day_stats.total_amount), 0) as total_amount,
+			COALESCE(SUM(user_day_stats.record_count), 0) as record_count
+		`).
+		Where("user_day_stats.date >= ? AND user_day_stats.date <= ?", startDate, endDate)
+	if userID != 0 {
+		query = query.Where("user_day_stats.user_id = ?", userID)
+	}
+	switch groupBy {
+	case shiftGroupUser:
+		query = query.Group("user_day_stats.user_id").Order("user_day_stats.user_id")
+	case shiftGroupDate:
+		query = query.Group("user_day_stats.date").Order("user_day_stats.date")
+	case shiftGroupPlate:
+		query = query.Group("user_day_stats.license_plate_id").Order("user_day_stats.license_plate_id")
+	}
+	// 记录全部作废后统计行仍保留为0，不作为分组结果返回
+	query = query.Having("COALESCE(SUM(user_day_stats.record_count), 0) > 0")
+	var rows []ShiftAggregate
+	err := query.Scan(&rows).Error
+	return rows, err
 }
 
 // 获取用户最近N条充电记录（带timeslot）
@@ -224,7 +311,7 @@ func GetRecentRecordsWithTimeslotByUser(c *gin.Context, userID uint, limit int)
 		limit = defaultLimit
 	}
 	var records []models.Record
-	err := models.DB.Where("user_id = ?", userID).Preload("LicensePlate").Order("created_at DESC").Limit(limit).Find(&records).Error
+	err := models.DB.Scopes(models.ActiveRecords).Where("user_id = ?", userID).Preload("LicensePlate").Order("created_at DESC").Limit(limit).Find(&records).Error
 	if err != nil {
 		utils.ErrorCtx(c, "查询用户充电记录失败: %v", err)
 		return nil, err
@@ -282,54 +369,27 @@ func SetReservationCompleted(reservationID, userID uint) error {
 	return models.DB.Model(&models.Reservation{}).Where("id = ? AND user_id = ?", reservationID, userID).Update("status", "completed").Error
 }
 
-// 获取指定月份每日白班、夜班、总用电量，按日期倒序（直接用冗余字段timeslot）
+// 获取指定月份每日白班、夜班、总用电量，按日期倒序（读取按日统计表）
 func GetDailyStatisticsWithShift(userID uint, month string) ([]map[string]interface{}, error) {
-	var results []struct {
-		Date     string
-		Timeslot string
-		TotalKwh float64
-	}
-
 	// 获取月份日期范围
 	startDate, endDate, err := getMonthDateRange(month)
 	if err != nil {
 		return nil, err
 	}
 
-	err = models.DB.Model(&models.Record{}).
-		Select("to_char(date, 'YYYY-MM-DD') as date, timeslot, COALESCE(SUM(kwh),0) as total_kwh").
-		Where("user_id = ? AND date >= ? AND date <= ?", userID, startDate, endDate).
-		Group("date, timeslot").
-		Order("date DESC").
-		Scan(&results).Error
+	rows, err := aggregateShiftStatistics(userID, startDate, endDate, shiftGroupDate)
 	if err != nil {
 		return nil, err
 	}
-	// 组装每天的 dayKwh/nightKwh/totalKwh
-	statMap := make(map[string]map[string]float64)
-	for _, r := range results {
-		if statMap[r.Date] == nil {
-			statMap[r.Date] = map[string]float64{"dayKwh": 0, "nightKwh": 0}
-		}
-		if r.Timeslot == "day" {
-			statMap[r.Date]["dayKwh"] = r.TotalKwh
-		} else if r.Timeslot == "night" {
-			statMap[r.Date]["nightKwh"] = r.TotalKwh
-		}
-	}
-	var resp []map[string]interface{}
-	for day, stat := range statMap {
-		total := stat["dayKwh"] + stat["nightKwh"]
+	resp := make([]map[string]interface{}, 0, len(rows))
+	for i := len(rows) - 1; i >= 0; i-- {
 		resp = append(resp, map[string]interface{}{
-			"date":     day,
-			"dayKwh":   stat["dayKwh"],
-			"nightKwh": stat["nightKwh"],
-			"totalKwh": total,
+			"date":     rows[i].Date,
+			"dayKwh":   rows[i].DayKwh,
+			"nightKwh": rows[i].NightKwh,
+			"totalKwh": rows[i].TotalKwh(),
 		})
 	}
-	sort.Slice(resp, func(i, j int) bool {
-		return resp[i]["date"].(string) > resp[j]["date"].(string)
-	})
 	return resp, nil
 }
 
@@ -344,6 +404,7 @@ func GetRecordsByMonth(userID uint, month string) ([]map[string]interface{}, err
 	var records []models.Record
 	err = models.DB.Where("user_id = ? AND date >= ? AND date <= ?", userID, startDate, endDate).
 		Preload("LicensePlate").
+		Preload("Discounts").
 		Order("date DESC, created_at DESC").
 		Find(&records).Error
 	if err != nil {
@@ -363,6 +424,17 @@ func GetRecordsByMonth(userID uint, month string) ([]map[string]interface{}, err
 			"image_url":  record.ImageURL,
 			"created_at": record.CreatedAt,
 			"updated_at": record.UpdatedAt,
+			// 审核信息
+			"review_status": record.ReviewStatus,
+			"review_reason": record.ReviewReason,
+			// 作废信息
+			"voided":      record.IsVoided(),
+			"voided_at":   record.VoidedAt,
+			"void_reason": record.VoidReason,
+			// 优惠信息
+			"gross_amount":    record.GrossAmount,
+			"discount_amount": record.DiscountAmount,
+			"discounts":       record.Discounts,
 		}
 
 		// 添加车牌号信息
@@ -382,7 +454,7 @@ func GetRecordsByMonth(userID uint, month string) ([]map[string]interface{}, err
 // GetRecordByID 根据ID获取充电记录详情
 func GetRecordByID(userID uint, recordID string) (map[string]interface{}, error) {
 	var record models.Record
-	err := models.DB.Where("id = ? AND user_id = ?", recordID, userID).Preload("LicensePlate").First(&record).Error
+	err := models.DB.Where("id = ? AND user_id = ?", recordID, userID).Preload("LicensePlate").Preload("Discounts").First(&record).Error
 	if err != nil {
 		return nil, err
 	}
@@ -397,6 +469,17 @@ func GetRecordByID(userID uint, recordID string) (map[string]interface{}, error)
 		"image_url":  record.ImageURL,
 		"created_at": record.CreatedAt,
 		"updated_at": record.UpdatedAt,
+		// 审核信息
+		"review_status": record.ReviewStatus,
+		"review_reason": record.ReviewReason,
+		// 作废信息
+		"voided":      record.IsVoided(),
+		"voided_at":   record.VoidedAt,
+		"void_reason": record.VoidReason,
+		// 优惠信息
+		"gross_amount":    record.GrossAmount,
+		"discount_amount": record.DiscountAmount,
+		"discounts":       record.Discounts,
 	}
 
 	// 添加车牌号信息
@@ -411,13 +494,8 @@ func GetRecordByID(userID uint, recordID string) (map[string]interface{}, error)
 }
 
 // UpdateRecordByID 根据ID更新充电记录
-func UpdateRecordByID(userID uint, recordID string, req UpdateRecordRequest) (map[string]interface{}, error) {
-	var record models.Record
-	err := models.DB.Where("id = ? AND user_id = ?", recordID, userID).First(&record).Error
-	if err != nil {
-		return nil, err
-	}
-
+// 编辑期限内直接修改并记录修订历史；超过期限则转为更正申请，返回值中包含 correction_request
+func UpdateRecordByID(c *gin.Context, userID uint, recordID string, req UpdateRecordRequest) (map[string]interface{}, error) {
 	// 验证车牌号是否属于当前用户
 	if req.LicensePlateID != nil {
 		var licensePlate models.LicensePlate
@@ -427,32 +505,50 @@ func UpdateRecordByID(userID uint, recordID string, req UpdateRecordRequest) (ma
 		}
 	}
 
-	// 更新记录
-	updates := map[string]interface{}{
-		"kwh":        req.KWH,
-		"remark":     req.Remark,
-		"updated_at": time.Now(),
-	}
-
-	// 如果提供了新的图片URL，则更新
-	if req.ImageURL != "" {
-		updates["image_url"] = req.ImageURL
-	}
-
-	// 更新车牌号
-	if req.LicensePlateID != nil {
-		updates["license_plate_id"] = req.LicensePlateID
-	}
+	// 在事务内锁定记录，修改前的值和修改内容均基于锁定后读取的最新数据
+	var record models.Record
+	var correction *models.RecordCorrectionRequest
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		stored, err := lockRecord(tx, "id = ? AND user_id = ?", recordID, userID)
+		if err != nil {
+			return err
+		}
+		if stored.IsVoided() {
+			return errors.New("充电记录已作废，不能修改")
+		}
+		record = stored
 
-	// 重新计算费用
-	record.KWH = req.KWH
-	record.CalculateAmount()
-	updates["amount"] = record.Amount
+		// 超过编辑期限，转为更正申请由管理员审核
+		if !withinEditWindow(stored) {
+			utils.InfoCtx(c, "记录已超过编辑期限，转为更正申请: record_id=%d, user_id=%d", stored.ID, userID)
+			correction, err = CreateCorrectionRequest(c, tx, stored, req)
+			return err
+		}
 
-	err = models.DB.Model(&record).Updates(updates).Error
+		// 修改后需重新审核，已驳回或异常的记录不会因修改自动通过
+		record.ReviewStatus = editedReviewStatus(stored)
+		record.ReviewReason = ""
+		record.ReviewedBy = nil
+		record.ReviewedAt = nil
+		if record.IsApproved() {
+			now := time.Now()
+			record.ReviewedAt = &now
+		}
+		return applyRecordUpdate(c, tx, stored, &record, req, userID, models.RevisionSourceMember, req.Reason)
+	})
 	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+		utils.ErrorCtx(c, "更新充电记录失败: record_id=%s, err=%v", recordID, err)
 		return nil, err
 	}
+	if correction != nil {
+		return map[string]interface{}{
+			"correction_request": formatCorrectionRequest(*correction),
+		}, nil
+	}
+	invalidateMonthlyReportDates(record.Date)
 
 	// 返回更新后的记录
 	return map[string]interface{}{
@@ -465,13 +561,16 @@ func UpdateRecordByID(userID uint, recordID string, req UpdateRecordRequest) (ma
 		"image_url":  record.ImageURL,
 		"created_at": record.CreatedAt,
 		"updated_at": record.UpdatedAt,
+		// 审核信息
+		"review_status": record.ReviewStatus,
 	}, nil
 }
 
 // UpdateRecordRequest 更新充电记录请求结构
 type UpdateRecordRequest struct {
-	KWH            float64 `json:"kwh"`
-	ImageURL       string  `json:"image_url"`
-	Remark         string  `json:"remark"`
-	LicensePlateID *uint   `json:"license_plate_id"`
+	KWH            decimal.Decimal `json:"kwh"`
+	ImageURL       string          `json:"image_url"`
+	Remark         string          `json:"remark"`
+	LicensePlateID *uint           `json:"license_plate_id"`
+	Reason         string          `json:"reason"`
 }
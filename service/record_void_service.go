@@ -0,0 +1,94 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationRecordVoided 管理员作废记录的通知类型
+const NotificationRecordVoided = "record_voided"
+
+// VoidRecord 作废充电记录，记录保留用于审计但不再计入统计与报表
+// 成员只能在编辑期限内作废自己的记录；管理员(asAdmin)可随时作废任意记录
+// 关联的预约恢复为 pending，可重新上传一条记录
+func VoidRecord(c *gin.Context, actorID, recordID uint, reason string, asAdmin bool) (*models.Record, error) {
+	if reason == "" {
+		return nil, errors.New("作废原因不能为空")
+	}
+	utils.InfoCtx(c, "作废充电记录: actor_id=%d, record_id=%d, as_admin=%v", actorID, recordID, asAdmin)
+	var record models.Record
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		query := tx.Where("id = ?", recordID)
+		if !asAdmin {
+			query = query.Where("user_id = ?", actorID)
+		}
+		if err := query.Clauses(clause.Locking{Strength: "UPDATE"}).First(&record).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("充电记录不存在")
+			}
+			return err
+		}
+		if record.IsVoided() {
+			return errors.New("充电记录已作废")
+		}
+		if !asAdmin && !withinEditWindow(record) {
+			return errors.New("已超过编辑期限，请联系管理员作废")
+		}
+
+		before := recordSnapshot(&record)
+		before["voided"] = false
+		active := record
+		now := time.Now()
+		record.VoidedAt = &now
+		record.VoidedBy = &actorID
+		record.VoidReason = reason
+		if err := tx.Model(&record).Select("voided_at", "voided_by", "void_reason", "updated_at").Updates(&record).Error; err != nil {
+			return err
+		}
+		if err := applyRecordStats(tx, active, -1); err != nil {
+			return err
+		}
+		source := models.RevisionSourceMember
+		if asAdmin {
+			source = models.RevisionSourceAdmin
+		}
+		if err := createRecordRevision(tx, record.ID, actorID, source, reason,
+			map[string]interface{}{"voided": false},
+			map[string]interface{}{"voided": true}); err != nil {
+			return err
+		}
+		if err := writeAudit(c, tx, AuditRecordVoid, AuditTargetRecord, record.ID, before,
+			map[string]interface{}{"voided": true, "void_reason": reason}); err != nil {
+			return err
+		}
+		// 预约恢复为待上传状态，允许补传替代记录
+		if record.ReservationID != 0 {
+			if err := tx.Model(&models.Reservation{}).
+				Where("id = ? AND status = ?", record.ReservationID, "completed").
+				Update("status", "pending").Error; err != nil {
+				return err
+			}
+		}
+		if asAdmin && record.UserID != actorID {
+			return CreateNotification(tx, record.UserID, NotificationRecordVoided,
+				"充电记录已被作废",
+				fmt.Sprintf("您 %s 的充电记录（%s 度）已被管理员作废，原因：%s", record.Date.Format("2006-01-02"), record.KWH.StringFixed(models.KWHPlaces), reason),
+				record.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		utils.WarnCtx(c, "作废充电记录失败: record_id=%d, err=%v", recordID, err)
+		return nil, err
+	}
+	utils.InfoCtx(c, "充电记录已作废: record_id=%d, reservation_id=%d", record.ID, record.ReservationID)
+	invalidateMonthlyReportForRecord(record)
+	return &record, nil
+}
@@ -31,6 +31,10 @@ func GetReservationsByUser(c *gin.Context, userID uint, date string) ([]models.R
 // 创建预约并做业务校验
 func CreateReservationWithCheck(c *gin.Context, userID uint, date time.Time, timeslot, remark string, licensePlateID *uint) (models.Reservation, error) {
 	utils.InfoCtx(c, "创建预约业务校验: user_id=%d, date=%s, timeslot=%s", userID, date.Format("2006-01-02"), timeslot)
+	// 检查是否已同意最新的充电规则
+	if err := ensureRulesAccepted(c, userID); err != nil {
+		return models.Reservation{}, err
+	}
 	// 检查是否有未完成预约
 	var ongoing models.Reservation
 	err := models.DB.Where("user_id = ? AND status = ? AND date >= ?", userID, "pending", time.Now().Format("2006-01-02")).Preload("User").Preload("LicensePlate").First(&ongoing).Error
@@ -43,17 +47,9 @@ func CreateReservationWithCheck(c *gin.Context, userID uint, date time.Time, tim
 	var lastReservation models.Reservation
 	errLast := models.DB.Where("user_id = ? AND status != ?", userID, "cancelled").Preload("User").Preload("LicensePlate").Order("date DESC").First(&lastReservation).Error
 	if errLast == nil {
-		var endTime time.Time
-		switch lastReservation.Timeslot {
-		case "day":
-			endTime = time.Date(lastReservation.Date.Year(), lastReservation.Date.Month(), lastReservation.Date.Day(), 20, 0, 0, 0, time.Local)
-		case "night":
-			nextDay := lastReservation.Date.AddDate(0, 0, 1)
-			endTime = time.Date(nextDay.Year(), nextDay.Month(), nextDay.Day(), 8, 0, 0, 0, time.Local)
-		}
-		if time.Now().After(endTime) {
+		if lastReservation.HasEnded(time.Now()) {
 			var count int64
-			models.DB.Model(&models.Record{}).Where("user_id = ? AND reservation_id = ?", userID, lastReservation.ID).Count(&count)
+			models.DB.Model(&models.Record{}).Scopes(models.ActiveRecords).Where("user_id = ? AND reservation_id = ?", userID, lastReservation.ID).Count(&count)
 			if count == 0 {
 				utils.WarnCtx(c, "上次预约未上传充电记录: user_id=%d, last_reservation_id=%d", userID, lastReservation.ID)
 				return models.Reservation{}, errors.New("上一次预约已结束但未上传充电记录，请先上传记录")
@@ -93,6 +89,7 @@ func CreateReservationWithCheck(c *gin.Context, userID uint, date time.Time, tim
 		utils.ErrorCtx(c, "创建预约入库失败: %v", err)
 		return models.Reservation{}, err
 	}
+	invalidateMonthlyReportDates(reservation.Date)
 	models.DB.Preload("User").Preload("LicensePlate").First(&reservation, reservation.ID)
 	utils.InfoCtx(c, "预约创建成功: user_id=%d, reservation_id=%d", userID, reservation.ID)
 	return reservation, nil
@@ -110,8 +107,10 @@ func CancelReservation(c *gin.Context, id, userID uint) error {
 	err := models.DB.Save(&reservation).Error
 	if err != nil {
 		utils.ErrorCtx(c, "取消预约保存失败: %v", err)
+		return err
 	}
-	return err
+	invalidateMonthlyReportDates(reservation.Date)
+	return nil
 }
 
 // 获取当前预约及充电记录状态
@@ -129,19 +128,11 @@ func GetCurrentReservationStatus(c *gin.Context, userID uint) (map[string]interf
 		Order("date DESC, id DESC").
 		First(&lastReservation).Error
 	if err == nil {
-		var endTime time.Time
-		switch lastReservation.Timeslot {
-		case "day":
-			endTime = time.Date(lastReservation.Date.Year(), lastReservation.Date.Month(), lastReservation.Date.Day(), 20, 0, 0, 0, time.Local)
-		case "night":
-			nextDay := lastReservation.Date.AddDate(0, 0, 1)
-			endTime = time.Date(nextDay.Year(), nextDay.Month(), nextDay.Day(), 8, 0, 0, 0, time.Local)
-		}
-		if time.Now().Before(endTime) {
+		if time.Now().Before(lastReservation.EndTime()) {
 			currentRes = &lastReservation
 		} else {
 			var count int64
-			models.DB.Model(&models.Record{}).Where("user_id = ? AND reservation_id = ?", userID, lastReservation.ID).Count(&count)
+			models.DB.Model(&models.Record{}).Scopes(models.ActiveRecords).Where("user_id = ? AND reservation_id = ?", userID, lastReservation.ID).Count(&count)
 			if count == 0 {
 				needUploadRecord = true
 				lastRes = &lastReservation
@@ -209,7 +200,15 @@ func CreateReservation(userID uint, date string, timeslot string) (*models.Reser
 
 // 删除预约
 func DeleteReservation(id, userID uint) error {
-	return models.DB.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Reservation{}).Error
+	var reservation models.Reservation
+	if err := models.DB.Where("id = ? AND user_id = ?", id, userID).First(&reservation).Error; err != nil {
+		return err
+	}
+	if err := models.DB.Delete(&reservation).Error; err != nil {
+		return err
+	}
+	invalidateMonthlyReportDates(reservation.Date)
+	return nil
 }
 
 // 获取当前预约
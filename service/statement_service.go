@@ -0,0 +1,392 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"os"
+	"shared-charge/config"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jung-kurt/gofpdf"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// statementLabels 账单文字
+var statementLabels = map[string]string{
+	"title":     "充电费用月度账单",
+	"member":    "成员",
+	"month":     "账单月份",
+	"generated": "生成时间",
+	"payment":   "支付状态",
+	"paid_at":   "支付时间",
+	"date":      "日期",
+	"timeslot":  "班次",
+	"plate":     "车牌号",
+	"kwh":       "度数(kWh)",
+	"price":     "单价(元/度)",
+	"amount":    "金额(元)",
+	"image":     "截图",
+	"total_kwh": "合计度数",
+	"gross":     "原价合计(元)",
+	"discount":  "优惠(元)",
+	"due":       "应付金额(元)",
+	"empty":     "本月没有审核通过的充电记录",
+	"pending":   "另有 %d 条记录尚未审核通过，未计入本账单",
+}
+
+// 账单表格列宽(mm)与行高
+var statementColumnWidths = []float64{26, 22, 30, 24, 28, 28, 32}
+
+const (
+	statementRowHeight    = 20.0
+	statementHeaderHeight = 8.0
+	statementFontFamily   = "statement"
+)
+
+// statementRecord 账单中的一条充电记录
+type statementRecord struct {
+	exportRecordRow
+	ImageURL string
+}
+
+// memberStatement 用户月度账单数据
+type memberStatement struct {
+	User            models.User
+	Month           string
+	Records         []statementRecord
+	Bill            models.MonthlyBill
+	UnapprovedCount int64
+}
+
+// loadMemberStatement 查询用户当月审核通过且未作废的记录，口径与月度对账一致
+func loadMemberStatement(userID uint, month string) (*memberStatement, error) {
+	startDate, endDate, err := getMonthDateRange(month)
+	if err != nil {
+		return nil, errors.New("月份格式错误")
+	}
+	statement := &memberStatement{Month: month}
+	if err := models.DB.First(&statement.User, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("用户不存在")
+		}
+		return nil, err
+	}
+	err = exportRecordQuery().Scopes(models.ActiveRecords).
+		Select(`records.id, records.date, COALESCE(license_plates.plate_number, '') as plate_number,
+			COALESCE(NULLIF(records.timeslot, ''), reservations.timeslot, '') as timeslot,
+			records.kwh, records.unit_price, records.gross_amount, records.discount_amount, records.amount,
+			COALESCE(records.image_url, '') as image_url`).
+		Where("records.user_id = ? AND records.date >= ? AND records.date <= ? AND records.review_status = ?", userID, startDate, endDate, models.ReviewStatusApproved).
+		Order("records.date ASC, records.id ASC").
+		Scan(&statement.Records).Error
+	if err != nil {
+		return nil, err
+	}
+	err = models.DB.Model(&models.Record{}).Scopes(models.ActiveRecords).
+		Where("user_id = ? AND date >= ? AND date <= ? AND review_status != ?", userID, startDate, endDate, models.ReviewStatusApproved).
+		Count(&statement.UnapprovedCount).Error
+	if err != nil {
+		return nil, err
+	}
+	if statement.Bill, err = GetMonthlyBill(userID, month); err != nil {
+		return nil, err
+	}
+	return statement, nil
+}
+
+// statementThumbnail 读取电量截图并缩放为 JPEG 缩略图，失败时返回 nil
+func statementThumbnail(c *gin.Context, imageURL string) []byte {
+	if imageURL == "" || utils.GetMinioClient() == nil {
+		return nil
+	}
+	cfg := config.GetConfig().PDF
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ImageTimeoutSec)*time.Second)
+	defer cancel()
+	data, err := loadUploadedImage(ctx, imageURL)
+	if err != nil {
+		utils.WarnCtx(c, "读取账单截图失败: image_url=%s, err=%v", imageURL, err)
+		return nil
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		utils.WarnCtx(c, "解析账单截图失败: image_url=%s, err=%v", imageURL, err)
+		return nil
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, resizeImage(src, cfg.ThumbnailWidth), &jpeg.Options{Quality: 70}); err != nil {
+		return nil
+	}
+	return buf.Bytes()
+}
+
+// resizeImage 按宽度等比缩小图片（最近邻采样），原图较小时不放大
+func resizeImage(src image.Image, width int) image.Image {
+	bounds := src.Bounds()
+	if width <= 0 || bounds.Dx() <= width {
+		return src
+	}
+	height := bounds.Dy() * width / bounds.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		srcY := bounds.Min.Y + y*bounds.Dy()/height
+		for x := 0; x < width; x++ {
+			dst.Set(x, y, src.At(bounds.Min.X+x*bounds.Dx()/width, srcY))
+		}
+	}
+	return dst
+}
+
+// errStatementFontMissing 未加载中文字体时不生成账单，避免成员姓名、车牌等中文内容显示为乱码
+var errStatementFontMissing = errors.New("未配置PDF中文字体，无法生成账单")
+
+// statementFont 启动时加载的中文字体，未加载时为 nil
+var statementFont []byte
+
+// InitStatementFont 根据配置加载账单使用的中文 TTF 字体，失败时账单功能不可用
+func InitStatementFont() error {
+	statementFont = nil
+	fontPath := config.GetConfig().PDF.FontPath
+	if fontPath == "" {
+		return errors.New("未配置 PDF_FONT_PATH")
+	}
+	font, err := os.ReadFile(fontPath)
+	if err != nil {
+		return err
+	}
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(statementFontFamily, "", font)
+	if !pdf.Ok() {
+		return fmt.Errorf("解析字体失败: path=%s, err=%v", fontPath, pdf.Error())
+	}
+	statementFont = font
+	return nil
+}
+
+// newStatementPDF 创建使用中文字体的 PDF 文档，未加载字体时返回错误
+func newStatementPDF() (*gofpdf.Fpdf, error) {
+	if statementFont == nil {
+		return nil, errStatementFontMissing
+	}
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(10, 12, 10)
+	pdf.SetAutoPageBreak(false, 12)
+	pdf.AddUTF8FontFromBytes(statementFontFamily, "", statementFont)
+	if !pdf.Ok() {
+		return nil, pdf.Error()
+	}
+	return pdf, nil
+}
+
+// writeStatementTableHeader 写出记录表格表头
+func writeStatementTableHeader(pdf *gofpdf.Fpdf) {
+	pdf.SetFillColor(235, 235, 235)
+	for i, key := range []string{"date", "timeslot", "plate", "kwh", "price", "amount", "image"} {
+		pdf.CellFormat(statementColumnWidths[i], statementHeaderHeight, statementLabels[key], "1", 0, "C", true, 0, "")
+	}
+	pdf.Ln(-1)
+}
+
+// RenderMemberStatement 生成用户月度账单 PDF，包含记录明细、合计、支付状态和截图缩略图
+func RenderMemberStatement(c *gin.Context, w io.Writer, userID uint, month string) error {
+	utils.InfoCtx(c, "生成月度账单: user_id=%d, month=%s", userID, month)
+	if statementFont == nil {
+		return errStatementFontMissing
+	}
+	statement, err := loadMemberStatement(userID, month)
+	if err != nil {
+		return err
+	}
+	if err := renderStatementPDF(c, w, statement); err != nil {
+		utils.ErrorCtx(c, "生成账单PDF失败: user_id=%d, month=%s, err=%v", userID, month, err)
+		return err
+	}
+	return nil
+}
+
+// renderStatementPDF 将账单数据写出为 PDF
+func renderStatementPDF(c *gin.Context, w io.Writer, statement *memberStatement) error {
+	pdf, err := newStatementPDF()
+	if err != nil {
+		return err
+	}
+	pdf.AddPage()
+
+	// 账单头
+	pdf.SetFont(statementFontFamily, "", 16)
+	pdf.CellFormat(0, 10, statementLabels["title"], "", 1, "C", false, 0, "")
+	pdf.SetFont(statementFontFamily, "", 10)
+	header := [][2]string{
+		{statementLabels["member"], fmt.Sprintf("%s (ID %d)", statement.User.Name, statement.User.ID)},
+		{statementLabels["month"], statement.Month},
+		{statementLabels["payment"], exportLabel(ExportLangZH, statement.Bill.Status)},
+	}
+	if statement.Bill.PaidAt != nil {
+		header = append(header, [2]string{statementLabels["paid_at"], statement.Bill.PaidAt.Format("2006-01-02 15:04")})
+	}
+	header = append(header, [2]string{statementLabels["generated"], time.Now().Format("2006-01-02 15:04")})
+	for _, line := range header {
+		pdf.CellFormat(35, 6, line[0]+":", "", 0, "L", false, 0, "")
+		pdf.CellFormat(0, 6, line[1], "", 1, "L", false, 0, "")
+	}
+	pdf.Ln(3)
+
+	// 记录明细
+	_, pageHeight := pdf.GetPageSize()
+	_, _, _, bottom := pdf.GetMargins()
+	var totalKWH decimal.Decimal
+	var gross, discount, amount int64
+	if len(statement.Records) == 0 {
+		pdf.CellFormat(0, 8, statementLabels["empty"], "", 1, "L", false, 0, "")
+	} else {
+		writeStatementTableHeader(pdf)
+	}
+	for i, record := range statement.Records {
+		if pdf.GetY()+statementRowHeight > pageHeight-bottom {
+			pdf.AddPage()
+			writeStatementTableHeader(pdf)
+		}
+		totalKWH = totalKWH.Add(record.KWH)
+		gross += record.GrossAmount
+		discount += record.DiscountAmount
+		amount += record.Amount
+
+		x, y := pdf.GetXY()
+		cells := []string{
+			record.Date.Format("2006-01-02"),
+			exportLabel(ExportLangZH, record.Timeslot),
+			record.PlateNumber,
+			record.KWH.StringFixed(models.KWHPlaces),
+			record.UnitPrice.StringFixed(models.PricePlaces),
+			models.FenToYuan(record.Amount).StringFixed(models.YuanPlaces),
+			"",
+		}
+		for j, cell := range cells {
+			align := "C"
+			if j >= 3 && j <= 5 {
+				align = "R"
+			}
+			pdf.CellFormat(statementColumnWidths[j], statementRowHeight, cell, "1", 0, align, false, 0, "")
+		}
+		if thumbnail := statementThumbnail(c, record.ImageURL); thumbnail != nil {
+			name := fmt.Sprintf("record_%d_%d", record.ID, i)
+			options := gofpdf.ImageOptions{ImageType: "JPG"}
+			info := pdf.RegisterImageOptionsReader(name, options, bytes.NewReader(thumbnail))
+			if pdf.Ok() && info != nil {
+				// 缩略图按比例放入截图单元格
+				cellX := x
+				for j := 0; j < len(statementColumnWidths)-1; j++ {
+					cellX += statementColumnWidths[j]
+				}
+				maxW, maxH := statementColumnWidths[len(statementColumnWidths)-1]-2, statementRowHeight-2
+				imgW, imgH := maxW, maxW*info.Height()/info.Width()
+				if imgH > maxH {
+					imgW, imgH = maxH*info.Width()/info.Height(), maxH
+				}
+				pdf.ImageOptions(name, cellX+(maxW+2-imgW)/2, y+(maxH+2-imgH)/2, imgW, imgH, false, options, 0, "")
+			}
+			pdf.ClearError()
+		}
+		pdf.SetXY(x, y+statementRowHeight)
+	}
+
+	// 合计
+	pdf.Ln(4)
+	if pdf.GetY()+30 > pageHeight-bottom {
+		pdf.AddPage()
+	}
+	totals := [][2]string{
+		{statementLabels["total_kwh"], totalKWH.StringFixed(models.KWHPlaces)},
+		{statementLabels["gross"], models.FenToYuan(gross).StringFixed(models.YuanPlaces)},
+		{statementLabels["discount"], models.FenToYuan(discount).StringFixed(models.YuanPlaces)},
+		{statementLabels["due"], models.FenToYuan(amount).StringFixed(models.YuanPlaces)},
+	}
+	for _, line := range totals {
+		pdf.CellFormat(150, 7, line[0], "", 0, "R", false, 0, "")
+		pdf.CellFormat(40, 7, line[1], "", 1, "R", false, 0, "")
+	}
+	if statement.UnapprovedCount > 0 {
+		pdf.Ln(2)
+		pdf.SetFont(statementFontFamily, "", 9)
+		pdf.CellFormat(0, 6, fmt.Sprintf(statementLabels["pending"], statement.UnapprovedCount), "", 1, "L", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
+
+// StatementFileName 用户月度账单的下载文件名
+func StatementFileName(userID uint, month string) string {
+	return fmt.Sprintf("statement_%s_%d.pdf", month, userID)
+}
+
+// ExportStatementsZip 管理员批量导出月度账单，每个用户一个 PDF 打包为 zip
+// userID 为 0 时导出当月有审核通过记录的全部用户
+// 先校验用户并加载全部账单数据，出错时尚未写出响应头，由调用方返回错误；开始写出 zip 后出错时返回错误，由调用方中断连接
+func ExportStatementsZip(c *gin.Context, month string, userID uint) error {
+	utils.InfoCtx(c, "批量导出月度账单: month=%s, user_id=%d", month, userID)
+	if statementFont == nil {
+		return errStatementFontMissing
+	}
+	startDate, endDate, err := getMonthDateRange(month)
+	if err != nil {
+		return errors.New("月份格式错误")
+	}
+	var userIDs []uint
+	if userID != 0 {
+		userIDs = []uint{userID}
+	} else {
+		err = models.DB.Model(&models.Record{}).Scopes(models.ActiveRecords).
+			Where("date >= ? AND date <= ? AND review_status = ?", startDate, endDate, models.ReviewStatusApproved).
+			Distinct().Order("user_id").Pluck("user_id", &userIDs).Error
+		if err != nil {
+			utils.ErrorCtx(c, "查询账单用户失败: %v", err)
+			return err
+		}
+	}
+	statements := make([]*memberStatement, 0, len(userIDs))
+	for _, id := range userIDs {
+		statement, err := loadMemberStatement(id, month)
+		if err != nil {
+			utils.ErrorCtx(c, "加载月度账单失败: user_id=%d, month=%s, err=%v", id, month, err)
+			return err
+		}
+		statements = append(statements, statement)
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="statements_%s.zip"`, month))
+	c.Status(200)
+	archive := zip.NewWriter(c.Writer)
+	for _, statement := range statements {
+		err := func() error {
+			file, err := archive.Create(StatementFileName(statement.User.ID, month))
+			if err != nil {
+				return err
+			}
+			return renderStatementPDF(c, file, statement)
+		}()
+		if err != nil {
+			utils.ErrorCtx(c, "生成账单PDF失败，中断下载: user_id=%d, month=%s, err=%v", statement.User.ID, month, err)
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	utils.InfoCtx(c, "月度账单导出完成: month=%s, count=%d", month, len(statements))
+	return nil
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+func TestExportStatementsZipRequiresFont(t *testing.T) {
+	setupMockDB(t)
+	statementFont = nil
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	if err := ExportStatementsZip(c, "2024-05", 1); err != errStatementFontMissing {
+		t.Fatalf("未加载字体时应返回 %v，实际为 %v", errStatementFontMissing, err)
+	}
+	if c.Writer.Written() {
+		t.Fatal("未加载字体时不应写出响应")
+	}
+}
+
+func TestExportStatementsZipValidatesUsersBeforeHeaders(t *testing.T) {
+	mock := setupMockDB(t)
+	statementFont = []byte("font")
+	t.Cleanup(func() { statementFont = nil })
+	mock.ExpectQuery(`SELECT \* FROM "users"`).WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	err := ExportStatementsZip(c, "2024-05", 42)
+	if err == nil || err.Error() != "用户不存在" {
+		t.Fatalf("应返回用户不存在，实际为 %v", err)
+	}
+	if c.Writer.Written() {
+		t.Fatal("校验失败时不应写出响应")
+	}
+	if contentType := c.Writer.Header().Get("Content-Type"); contentType != "" {
+		t.Fatalf("校验失败时不应设置 zip 响应头，实际为 %q", contentType)
+	}
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"shared-charge/config"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// refreshTokenBytes 刷新令牌的随机字节数
+const refreshTokenBytes = 32
+
+// userAgentMaxLength 保存的 User-Agent 最大长度
+const userAgentMaxLength = 255
+
+// TokenPair 登录或刷新后签发的访问令牌和刷新令牌
+type TokenPair struct {
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+// hashRefreshToken 计算刷新令牌的 SHA-256 哈希，数据库只保存哈希
+func hashRefreshToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueTokenPair 为用户签发同一会话的访问令牌和刷新令牌
+func issueTokenPair(c *gin.Context, tx *gorm.DB, user *models.User, sessionID string) (TokenPair, error) {
+	access, err := utils.GenerateToken(user.FormatUserInfo(), sessionID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	rawRefresh, err := utils.GenerateRandomID(refreshTokenBytes)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh := models.RefreshToken{
+		UserID:          user.ID,
+		SessionID:       sessionID,
+		TokenHash:       hashRefreshToken(rawRefresh),
+		ExpiresAt:       time.Now().AddDate(0, 0, config.GetConfig().JWT.RefreshExpireDays),
+		AccessJTI:       access.JTI,
+		AccessExpiresAt: &access.ExpiresAt,
+	}
+	if c != nil && c.Request != nil {
+		refresh.IP = c.ClientIP()
+		refresh.UserAgent = c.Request.UserAgent()
+		if len(refresh.UserAgent) > userAgentMaxLength {
+			refresh.UserAgent = refresh.UserAgent[:userAgentMaxLength]
+		}
+	}
+	if err := tx.Create(&refresh).Error; err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		Token:            access.Token,
+		ExpiresAt:        access.ExpiresAt,
+		RefreshToken:     rawRefresh,
+		RefreshExpiresAt: refresh.ExpiresAt,
+	}, nil
+}
+
+// revokeRefreshTokens 在事务内注销符合条件且尚未注销的刷新令牌，返回被注销的令牌用于将访问令牌加入黑名单
+func revokeRefreshTokens(tx *gorm.DB, reason string, query interface{}, args ...interface{}) ([]models.RefreshToken, error) {
+	var tokens []models.RefreshToken
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Find(&tokens).Error
+	if err != nil || len(tokens) == 0 {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(tokens))
+	for _, token := range tokens {
+		ids = append(ids, token.ID)
+	}
+	err = tx.Model(&models.RefreshToken{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+		"revoked_at":    time.Now(),
+		"revoke_reason": reason,
+	}).Error
+	return tokens, err
+}
+
+// denyAccessTokens 将被注销的刷新令牌对应的、尚未过期的访问令牌加入黑名单，应在事务提交后调用
+func denyAccessTokens(c *gin.Context, tokens []models.RefreshToken) {
+	now := time.Now()
+	for _, token := range tokens {
+		if token.AccessJTI == "" || token.AccessExpiresAt == nil || !token.AccessExpiresAt.After(now) {
+			continue
+		}
+		if err := utils.DenyToken(token.AccessJTI, *token.AccessExpiresAt); err != nil {
+			utils.ErrorCtx(c, "访问令牌加入黑名单失败: user_id=%d, jti=%s, err=%v", token.UserID, token.AccessJTI, err)
+		}
+	}
+}
+
+// countSessions 统计令牌涉及的会话数
+func countSessions(tokens []models.RefreshToken) int {
+	sessions := make(map[string]bool)
+	for _, token := range tokens {
+		sessions[token.SessionID] = true
+	}
+	return len(sessions)
+}
+
+// IssueTokens 登录成功后开启新会话，签发访问令牌和刷新令牌
+func IssueTokens(c *gin.Context, user *models.User) (TokenPair, error) {
+	sessionID, err := utils.GenerateRandomID(16)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	// 顺便清理该用户已过期的刷新令牌
+	if err := models.DB.Where("user_id = ? AND expires_at < ?", user.ID, time.Now()).Delete(&models.RefreshToken{}).Error; err != nil {
+		utils.WarnCtx(c, "清理过期刷新令牌失败: user_id=%d, err=%v", user.ID, err)
+	}
+	pair, err := issueTokenPair(c, models.DB, user, sessionID)
+	if err != nil {
+		utils.ErrorCtx(c, "签发令牌失败: user_id=%d, err=%v", user.ID, err)
+		return TokenPair{}, err
+	}
+	return pair, nil
+}
+
+// RotateRefreshToken 用刷新令牌换取新的访问令牌和刷新令牌，旧刷新令牌随即失效
+// 已轮换过的刷新令牌再次使用时视为泄露，注销整个会话（包括已签发的访问令牌）
+func RotateRefreshToken(c *gin.Context, rawToken string) (TokenPair, *models.User, error) {
+	var pair TokenPair
+	var user models.User
+	var revoked []models.RefreshToken
+	reused := false
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var token models.RefreshToken
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashRefreshToken(rawToken)).
+			First(&token).Error
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("刷新令牌无效或已过期")
+			}
+			return err
+		}
+		now := time.Now()
+		if token.RevokedAt != nil || !now.Before(token.ExpiresAt) {
+			return errors.New("刷新令牌无效或已过期")
+		}
+		if token.UsedAt != nil {
+			reused = true
+			revoked, err = revokeRefreshTokens(tx, models.TokenRevokeReuse, "session_id = ?", token.SessionID)
+			return err
+		}
+		if err := tx.First(&user, token.UserID).Error; err != nil {
+			return errors.New("刷新令牌无效或已过期")
+		}
+		if _, err := user.LiftExpiredSuspension(tx); err != nil {
+			return err
+		}
+		if !user.IsActive() {
+			return errors.New("用户状态异常，无法刷新令牌")
+		}
+		if err := tx.Model(&token).Update("used_at", now).Error; err != nil {
+			return err
+		}
+		pair, err = issueTokenPair(c, tx, &user, token.SessionID)
+		return err
+	})
+	if err != nil {
+		utils.WarnCtx(c, "刷新令牌失败: %v", err)
+		return TokenPair{}, nil, err
+	}
+	if reused {
+		denyAccessTokens(c, revoked)
+		utils.WarnCtx(c, "刷新令牌被重复使用，已注销会话: revoked_tokens=%d", len(revoked))
+		return TokenPair{}, nil, errors.New("刷新令牌已被使用，会话已注销，请重新登录")
+	}
+	return pair, &user, nil
+}
+
+// Logout 注销当前会话的刷新令牌，并将会话内尚未过期的访问令牌加入黑名单
+func Logout(c *gin.Context, userID uint, sessionID string) error {
+	utils.InfoCtx(c, "退出登录: user_id=%d, session_id=%s", userID, sessionID)
+	var revoked []models.RefreshToken
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		revoked, err = revokeRefreshTokens(tx, models.TokenRevokeLogout, "user_id = ? AND session_id = ?", userID, sessionID)
+		return err
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "退出登录失败: user_id=%d, err=%v", userID, err)
+		return err
+	}
+	denyAccessTokens(c, revoked)
+	return nil
+}
+
+// RevokeUserSessions 管理员注销用户的全部会话，用户需重新登录，返回注销的会话数
+func RevokeUserSessions(c *gin.Context, adminID, userID uint) (int, error) {
+	utils.InfoCtx(c, "注销用户全部会话: admin_id=%d, user_id=%d", adminID, userID)
+	var revoked []models.RefreshToken
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Select("id").First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("用户不存在")
+			}
+			return err
+		}
+		var err error
+		revoked, err = revokeRefreshTokens(tx, models.TokenRevokeAdmin, "user_id = ?", userID)
+		if err != nil {
+			return err
+		}
+		return writeAudit(c, tx, AuditUserRevokeSessions, AuditTargetUser, userID, nil,
+			map[string]interface{}{"revoked_sessions": countSessions(revoked)})
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "注销用户会话失败: user_id=%d, err=%v", userID, err)
+		return 0, err
+	}
+	denyAccessTokens(c, revoked)
+	return countSessions(revoked), nil
+}
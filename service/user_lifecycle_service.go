@@ -0,0 +1,360 @@
+package service
+
+import (
+	"errors"
+	"shared-charge/models"
+	"shared-charge/utils"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// lockManagedUser 在事务内锁定并读取被管理的用户
+func lockManagedUser(tx *gorm.DB, userID uint) (models.User, error) {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return user, errors.New("用户不存在")
+		}
+		return user, err
+	}
+	if user.Status == models.UserStatusRemoved {
+		return user, errors.New("用户已被移除")
+	}
+	return user, nil
+}
+
+// ensureOtherActiveAdmin 确认除指定用户外还有正常状态的管理员
+func ensureOtherActiveAdmin(tx *gorm.DB, userID uint) error {
+	var count int64
+	err := tx.Model(&models.User{}).
+		Where("id <> ? AND role = ? AND status = ?", userID, models.UserRoleAdmin, models.UserStatusActive).
+		Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("至少需要保留一名正常状态的管理员")
+	}
+	return nil
+}
+
+// cancelFutureReservations 取消用户尚未开始的预约，until 不为空时只取消该日期（含）之前的预约
+// 返回被取消预约的日期，用于刷新月度对账缓存
+func cancelFutureReservations(tx *gorm.DB, userID uint, until *time.Time) ([]time.Time, error) {
+	now := time.Now()
+	query := tx.Where("user_id = ? AND status <> ? AND date >= ?", userID, "cancelled", now.Format("2006-01-02"))
+	if until != nil {
+		query = query.Where("date <= ?", until.Format("2006-01-02"))
+	}
+	var reservations []models.Reservation
+	if err := query.Find(&reservations).Error; err != nil {
+		return nil, err
+	}
+	var ids []uint
+	var dates []time.Time
+	for _, reservation := range reservations {
+		if !reservation.StartTime().After(now) {
+			continue
+		}
+		ids = append(ids, reservation.ID)
+		dates = append(dates, reservation.Date)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	err := tx.Model(&models.Reservation{}).Where("id IN ?", ids).Update("status", "cancelled").Error
+	return dates, err
+}
+
+// afterUserLifecycleChange 用户状态或角色变化后清除认证缓存，使新状态立即生效
+func afterUserLifecycleChange(c *gin.Context, userID uint, reservationDates []time.Time) {
+	if err := utils.DeleteUserCache(userID); err != nil {
+		utils.WarnCtx(c, "清除用户缓存失败: user_id=%d, err=%v", userID, err)
+	}
+	invalidateMonthlyReportDates(reservationDates...)
+}
+
+// parseSuspendUntil 解析暂停截止日期，为空表示需手动恢复
+func parseSuspendUntil(until string) (*time.Time, error) {
+	if until == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", until, time.Local)
+	if err != nil {
+		return nil, errors.New("日期格式错误，应为YYYY-MM-DD")
+	}
+	if until < time.Now().Format("2006-01-02") {
+		return nil, errors.New("暂停截止日期不能早于今天")
+	}
+	return &date, nil
+}
+
+// suspendLockedUser 暂停已在事务内锁定的用户，取消暂停期间尚未开始的预约并写入审计日志
+// 返回被取消预约的日期
+func suspendLockedUser(c *gin.Context, tx *gorm.DB, adminID uint, user *models.User, reason string, until *time.Time, audit map[string]interface{}) ([]time.Time, error) {
+	before := formatUserLifecycle(*user)
+	if user.IsAdmin() && user.IsActive() {
+		if err := ensureOtherActiveAdmin(tx, user.ID); err != nil {
+			return nil, err
+		}
+	}
+	now := time.Now()
+	user.Status = models.UserStatusSuspended
+	user.SuspendReason = reason
+	user.SuspendedUntil = until
+	user.SuspendedAt = &now
+	user.SuspendedBy = &adminID
+	err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"status":          user.Status,
+		"suspend_reason":  user.SuspendReason,
+		"suspended_until": user.SuspendedUntil,
+		"suspended_at":    user.SuspendedAt,
+		"suspended_by":    user.SuspendedBy,
+	}).Error
+	if err != nil {
+		return nil, err
+	}
+	cancelled, err := cancelFutureReservations(tx, user.ID, until)
+	if err != nil {
+		return nil, err
+	}
+	after := formatUserLifecycle(*user)
+	after["cancelled_reservations"] = len(cancelled)
+	for key, value := range audit {
+		after[key] = value
+	}
+	return cancelled, writeAudit(c, tx, AuditUserSuspend, AuditTargetUser, user.ID, before, after)
+}
+
+// reactivateLockedUser 恢复已在事务内锁定的暂停用户并写入审计日志
+func reactivateLockedUser(c *gin.Context, tx *gorm.DB, user *models.User, audit map[string]interface{}) error {
+	before := formatUserLifecycle(*user)
+	if user.Status != models.UserStatusSuspended {
+		return errors.New("用户未处于暂停状态")
+	}
+	user.Status = models.UserStatusActive
+	user.SuspendReason = ""
+	user.SuspendedUntil = nil
+	user.SuspendedAt = nil
+	user.SuspendedBy = nil
+	err := tx.Model(&models.User{}).Where("id = ?", user.ID).Updates(map[string]interface{}{
+		"status":          user.Status,
+		"suspend_reason":  "",
+		"suspended_until": nil,
+		"suspended_at":    nil,
+		"suspended_by":    nil,
+	}).Error
+	if err != nil {
+		return err
+	}
+	after := formatUserLifecycle(*user)
+	for key, value := range audit {
+		after[key] = value
+	}
+	return writeAudit(c, tx, AuditUserReactivate, AuditTargetUser, user.ID, before, after)
+}
+
+// SuspendUser 管理员暂停用户，until 为空表示需手动恢复，否则截止日期（含当天）后自动恢复
+// 暂停期间尚未开始的预约会被取消，返回取消的预约数
+func SuspendUser(c *gin.Context, adminID, userID uint, reason, until string) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "暂停用户: admin_id=%d, user_id=%d, until=%s", adminID, userID, until)
+	if userID == adminID {
+		return nil, errors.New("不能暂停自己的账号")
+	}
+	untilDate, err := parseSuspendUntil(until)
+	if err != nil {
+		return nil, err
+	}
+
+	var user models.User
+	var cancelled []time.Time
+	err = models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockManagedUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		cancelled, err = suspendLockedUser(c, tx, adminID, &user, reason, untilDate, nil)
+		return err
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "暂停用户失败: user_id=%d, err=%v", userID, err)
+		return nil, err
+	}
+	afterUserLifecycleChange(c, userID, cancelled)
+	result := formatUserLifecycle(user)
+	result["cancelled_reservations"] = len(cancelled)
+	return result, nil
+}
+
+// ReactivateUser 管理员恢复被暂停的用户
+func ReactivateUser(c *gin.Context, adminID, userID uint) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "恢复用户: admin_id=%d, user_id=%d", adminID, userID)
+	var user models.User
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockManagedUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		return reactivateLockedUser(c, tx, &user, nil)
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "恢复用户失败: user_id=%d, err=%v", userID, err)
+		return nil, err
+	}
+	afterUserLifecycleChange(c, userID, nil)
+	return formatUserLifecycle(user), nil
+}
+
+// UpdateUserRole 管理员修改用户角色，不能修改自己的角色，且至少保留一名正常状态的管理员
+func UpdateUserRole(c *gin.Context, adminID, userID uint, role string) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "修改用户角色: admin_id=%d, user_id=%d, role=%s", adminID, userID, role)
+	if !models.IsValidRole(role) {
+		return nil, errors.New("角色无效")
+	}
+	if userID == adminID {
+		return nil, errors.New("不能修改自己的角色")
+	}
+	var user models.User
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockManagedUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		before := formatUserLifecycle(user)
+		if user.Role == role {
+			return nil
+		}
+		if user.IsAdmin() && user.IsActive() {
+			if err := ensureOtherActiveAdmin(tx, userID); err != nil {
+				return err
+			}
+		}
+		user.Role = role
+		if err := tx.Model(&models.User{}).Where("id = ?", userID).Update("role", role).Error; err != nil {
+			return err
+		}
+		return writeAudit(c, tx, AuditUserRole, AuditTargetUser, userID, before, formatUserLifecycle(user))
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "修改用户角色失败: user_id=%d, err=%v", userID, err)
+		return nil, err
+	}
+	afterUserLifecycleChange(c, userID, nil)
+	return formatUserLifecycle(user), nil
+}
+
+// RemoveUser 管理员移除用户：取消尚未开始的预约和待生效的电价计划，保留充电记录、账单等财务数据
+// 已移除的用户仍出现在移除当月及之前的月度对账中
+func RemoveUser(c *gin.Context, adminID, userID uint) (map[string]interface{}, error) {
+	utils.InfoCtx(c, "移除用户: admin_id=%d, user_id=%d", adminID, userID)
+	if userID == adminID {
+		return nil, errors.New("不能移除自己的账号")
+	}
+	var user models.User
+	var cancelled []time.Time
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = lockManagedUser(tx, userID)
+		if err != nil {
+			return err
+		}
+		before := formatUserLifecycle(user)
+		if user.IsAdmin() && user.IsActive() {
+			if err := ensureOtherActiveAdmin(tx, userID); err != nil {
+				return err
+			}
+		}
+		now := time.Now()
+		user.Status = models.UserStatusRemoved
+		user.RemovedAt = &now
+		user.RemovedBy = &adminID
+		user.SuspendReason = ""
+		user.SuspendedUntil = nil
+		user.SuspendedAt = nil
+		user.SuspendedBy = nil
+		err = tx.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+			"status":          user.Status,
+			"removed_at":      user.RemovedAt,
+			"removed_by":      user.RemovedBy,
+			"suspend_reason":  "",
+			"suspended_until": nil,
+			"suspended_at":    nil,
+			"suspended_by":    nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		if cancelled, err = cancelFutureReservations(tx, userID, nil); err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ? AND applied_at IS NULL", userID).Delete(&models.UnitPriceSchedule{}).Error; err != nil {
+			return err
+		}
+		after := formatUserLifecycle(user)
+		after["cancelled_reservations"] = len(cancelled)
+		return writeAudit(c, tx, AuditUserRemove, AuditTargetUser, userID, before, after)
+	})
+	if err != nil {
+		utils.ErrorCtx(c, "移除用户失败: user_id=%d, err=%v", userID, err)
+		return nil, err
+	}
+	afterUserLifecycleChange(c, userID, cancelled)
+	// 月度对账的用户列表随移除时间变化
+	invalidateAllMonthlyReports()
+	result := formatUserLifecycle(user)
+	result["cancelled_reservations"] = len(cancelled)
+	return result, nil
+}
+
+// GetRoles 获取可分配的角色及其权限
+func GetRoles() []map[string]interface{} {
+	roles := make([]map[string]interface{}, 0, len(models.Roles))
+	for _, role := range models.Roles {
+		roles = append(roles, map[string]interface{}{
+			"role":        role,
+			"permissions": models.RolePermissions[role],
+		})
+	}
+	return roles
+}
+
+// formatOptionalDate 格式化可为空的日期
+func formatOptionalDate(date *time.Time) interface{} {
+	if date == nil {
+		return nil
+	}
+	return date.Format("2006-01-02")
+}
+
+// formatUserLifecycle 格式化用户状态和角色，供生命周期管理接口返回
+func formatUserLifecycle(user models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":              user.ID,
+		"user_name":       user.Name,
+		"role":            user.Role,
+		"status":          user.Status,
+		"suspend_reason":  user.SuspendReason,
+		"suspended_until": formatOptionalDate(user.SuspendedUntil),
+		"suspended_at":    user.SuspendedAt,
+		"removed_at":      user.RemovedAt,
+	}
+}
+
+// LiftExpiredSuspension 暂停已到期的用户恢复为正常状态，登录时调用
+func LiftExpiredSuspension(c *gin.Context, user *models.User) {
+	lifted, err := user.LiftExpiredSuspension(models.DB)
+	if err != nil {
+		utils.ErrorCtx(c, "恢复暂停到期用户失败: user_id=%d, err=%v", user.ID, err)
+		return
+	}
+	if lifted {
+		utils.InfoCtx(c, "用户暂停已到期，恢复正常: user_id=%d", user.ID)
+		afterUserLifecycleChange(c, user.ID, nil)
+	}
+}
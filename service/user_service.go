@@ -6,6 +6,7 @@ import (
 	"shared-charge/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 )
 
 // 获取用户信息
@@ -20,19 +21,22 @@ func GetUserByID(c *gin.Context, id uint) (models.User, error) {
 }
 
 // 获取用户专属电价（无则返回全局默认）
-func GetUserUnitPrice(user models.User) float64 {
-	if user.UnitPrice > 0 {
+func GetUserUnitPrice(user models.User) decimal.Decimal {
+	if user.UnitPrice.IsPositive() {
 		return user.UnitPrice
 	}
 	return config.GetConfig().App.DefaultUnitPrice
 }
 
-// GetUserPrice 获取用户电价
-func GetUserPrice(userID uint) (float64, error) {
+// GetUserPrice 获取用户电价，先写入已到生效日的电价计划
+func GetUserPrice(userID uint) (decimal.Decimal, error) {
+	if err := applyDuePriceSchedules(userID); err != nil {
+		return decimal.Zero, err
+	}
 	var user models.User
 	err := models.DB.First(&user, userID).Error
 	if err != nil {
-		return 0, err
+		return decimal.Zero, err
 	}
 	return GetUserUnitPrice(user), nil
 }
@@ -46,8 +50,11 @@ func UpdateUserProfile(c *gin.Context, userID uint, avatar string, nickName stri
 	}).Error
 	if err != nil {
 		utils.ErrorCtx(c, "更新用户信息失败: %v", err)
+		return err
 	}
-	return err
+	// 月度对账中显示用户名和头像
+	invalidateAllMonthlyReports()
+	return nil
 }
 
 // UpdateUserPhoneByID 更新用户手机号
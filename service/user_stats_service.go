@@ -0,0 +1,162 @@
+package service
+
+import (
+	"shared-charge/models"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// userStatsDelta 一条充电记录对统计表的增量
+type userStatsDelta struct {
+	DayKwh      decimal.Decimal
+	NightKwh    decimal.Decimal
+	TotalKwh    decimal.Decimal
+	TotalAmount int64
+	RecordCount int64
+}
+
+// recordStatsDelta 计算记录计入统计表的增量，sign 为 1 表示计入，-1 表示扣除
+// 班次使用记录上的冗余字段 timeslot
+func recordStatsDelta(record models.Record, sign int64) userStatsDelta {
+	kwh := record.KWH.Mul(decimal.NewFromInt(sign))
+	delta := userStatsDelta{
+		TotalKwh:    kwh,
+		TotalAmount: record.Amount * sign,
+		RecordCount: sign,
+	}
+	switch record.Timeslot {
+	case "day":
+		delta.DayKwh = kwh
+	case "night":
+		delta.NightKwh = kwh
+	}
+	return delta
+}
+
+// statsUpsertAssignments 冲突时在原值上累加增量
+func statsUpsertAssignments(table string) clause.Set {
+	assignments := clause.Set{}
+	for _, column := range []string{"day_kwh", "night_kwh", "total_kwh", "total_amount", "record_count"} {
+		assignments = append(assignments, clause.Assignment{
+			Column: clause.Column{Name: column},
+			Value:  gorm.Expr(table + "." + column + " + excluded." + column),
+		})
+	}
+	return append(assignments, clause.Assignment{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("excluded.updated_at")})
+}
+
+// recordInStats 统计表只计入审核通过且未作废的记录，口径与月度对账一致
+func recordInStats(record models.Record) bool {
+	return record.IsApproved() && !record.IsVoided()
+}
+
+// statsPlateID 记录在按日统计表中的车牌号维度，未关联车牌号时为 0
+func statsPlateID(record models.Record) uint {
+	if record.LicensePlateID == nil {
+		return 0
+	}
+	return *record.LicensePlateID
+}
+
+// applyRecordStats 在事务内将记录计入(sign=1)或扣除(sign=-1)按日、按月统计
+// record 为计入或扣除时记录的状态，未审核通过或已作废的记录不在统计内，直接跳过
+func applyRecordStats(tx *gorm.DB, record models.Record, sign int64) error {
+	if !recordInStats(record) {
+		return nil
+	}
+	delta := recordStatsDelta(record, sign)
+	now := time.Now()
+	day := models.UserDayStat{
+		UserID:         record.UserID,
+		Date:           record.Date,
+		LicensePlateID: statsPlateID(record),
+		DayKwh:         delta.DayKwh,
+		NightKwh:       delta.NightKwh,
+		TotalKwh:       delta.TotalKwh,
+		TotalAmount:    delta.TotalAmount,
+		RecordCount:    delta.RecordCount,
+		UpdatedAt:      now,
+	}
+	err := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "date"}, {Name: "license_plate_id"}},
+		DoUpdates: statsUpsertAssignments(day.TableName()),
+	}).Create(&day).Error
+	if err != nil {
+		return err
+	}
+	month := models.UserMonthStat{
+		UserID:      record.UserID,
+		Month:       record.Date.Format("2006-01"),
+		DayKwh:      delta.DayKwh,
+		NightKwh:    delta.NightKwh,
+		TotalKwh:    delta.TotalKwh,
+		TotalAmount: delta.TotalAmount,
+		RecordCount: delta.RecordCount,
+		UpdatedAt:   now,
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "month"}},
+		DoUpdates: statsUpsertAssignments(month.TableName()),
+	}).Create(&month).Error
+}
+
+// replaceRecordStats 记录修改（含更换车牌号）或审核状态变化后，扣除修改前计入的数值并计入修改后的数值
+func replaceRecordStats(tx *gorm.DB, before, after models.Record) error {
+	if recordInStats(before) == recordInStats(after) &&
+		before.KWH.Equal(after.KWH) && before.Amount == after.Amount &&
+		before.Timeslot == after.Timeslot && before.Date.Equal(after.Date) && before.UserID == after.UserID &&
+		statsPlateID(before) == statsPlateID(after) {
+		return nil
+	}
+	if err := applyRecordStats(tx, before, -1); err != nil {
+		return err
+	}
+	return applyRecordStats(tx, after, 1)
+}
+
+// rebuildDayStatsSQL 根据审核通过且未作废的充电记录按日、按车牌号汇总，与 recordInStats 口径一致
+const rebuildDayStatsSQL = `
+	INSERT INTO user_day_stats (user_id, date, license_plate_id, day_kwh, night_kwh, total_kwh, total_amount, record_count, updated_at)
+	SELECT user_id, date, COALESCE(license_plate_id, 0),
+		COALESCE(SUM(CASE WHEN timeslot = 'day' THEN kwh ELSE 0 END), 0),
+		COALESCE(SUM(CASE WHEN timeslot = 'night' THEN kwh ELSE 0 END), 0),
+		COALESCE(SUM(kwh), 0),
+		COALESCE(SUM(amount), 0),
+		COUNT(*),
+		NOW()
+	FROM records
+	WHERE voided_at IS NULL AND deleted_at IS NULL AND review_status = 'approved'
+	GROUP BY user_id, date, COALESCE(license_plate_id, 0)`
+
+// RebuildUserStats 根据充电记录重建按日、按月统计表
+// 重建期间锁定 records 表的写入，避免与增量更新交错
+func RebuildUserStats() (int64, error) {
+	var days int64
+	err := models.DB.Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			"LOCK TABLE records IN SHARE MODE",
+			"LOCK TABLE user_day_stats, user_month_stats IN EXCLUSIVE MODE",
+			"DELETE FROM user_day_stats",
+			"DELETE FROM user_month_stats",
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		result := tx.Exec(rebuildDayStatsSQL)
+		if result.Error != nil {
+			return result.Error
+		}
+		days = result.RowsAffected
+		return tx.Exec(`
+			INSERT INTO user_month_stats (user_id, month, day_kwh, night_kwh, total_kwh, total_amount, record_count, updated_at)
+			SELECT user_id, to_char(date, 'YYYY-MM'), SUM(day_kwh), SUM(night_kwh), SUM(total_kwh), SUM(total_amount), SUM(record_count), NOW()
+			FROM user_day_stats
+			GROUP BY user_id, to_char(date, 'YYYY-MM')`).Error
+	})
+	return days, err
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"fmt"
+	"shared-charge/models"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// statsTotals 统计表中一行的数值
+type statsTotals struct {
+	DayKwh      decimal.Decimal
+	NightKwh    decimal.Decimal
+	TotalKwh    decimal.Decimal
+	TotalAmount int64
+	RecordCount int64
+}
+
+func (s statsTotals) add(other statsTotals) statsTotals {
+	return statsTotals{
+		DayKwh:      s.DayKwh.Add(other.DayKwh),
+		NightKwh:    s.NightKwh.Add(other.NightKwh),
+		TotalKwh:    s.TotalKwh.Add(other.TotalKwh),
+		TotalAmount: s.TotalAmount + other.TotalAmount,
+		RecordCount: s.RecordCount + other.RecordCount,
+	}
+}
+
+func (s statsTotals) isZero() bool {
+	return s.DayKwh.IsZero() && s.NightKwh.IsZero() && s.TotalKwh.IsZero() && s.TotalAmount == 0 && s.RecordCount == 0
+}
+
+func (s statsTotals) String() string {
+	return fmt.Sprintf("day=%s night=%s total=%s amount=%d count=%d", s.DayKwh, s.NightKwh, s.TotalKwh, s.TotalAmount, s.RecordCount)
+}
+
+// captureStatsUpserts 以 DryRun 方式执行统计增量写入，按行累加每次 upsert 的增量
+func captureStatsUpserts(t *testing.T) (*gorm.DB, map[string]statsTotals) {
+	t.Helper()
+	setupMockDB(t)
+	rows := make(map[string]statsTotals)
+	err := models.DB.Callback().Create().After("gorm:create").Register("test:capture_stats", func(db *gorm.DB) {
+		switch stat := db.Statement.Dest.(type) {
+		case *models.UserDayStat:
+			key := fmt.Sprintf("day:%d:%s:%d", stat.UserID, stat.Date.Format("2006-01-02"), stat.LicensePlateID)
+			rows[key] = rows[key].add(statsTotals{stat.DayKwh, stat.NightKwh, stat.TotalKwh, stat.TotalAmount, stat.RecordCount})
+		case *models.UserMonthStat:
+			key := fmt.Sprintf("month:%d:%s", stat.UserID, stat.Month)
+			rows[key] = rows[key].add(statsTotals{stat.DayKwh, stat.NightKwh, stat.TotalKwh, stat.TotalAmount, stat.RecordCount})
+		}
+	})
+	if err != nil {
+		t.Fatalf("注册回调失败: %v", err)
+	}
+	return models.DB.Session(&gorm.Session{DryRun: true, SkipDefaultTransaction: true}), rows
+}
+
+// rebuildStats 按 rebuildDayStatsSQL 的口径根据记录最终状态汇总按日（含车牌号）、按月统计
+func rebuildStats(records []models.Record) map[string]statsTotals {
+	rows := make(map[string]statsTotals)
+	for _, record := range records {
+		if record.ReviewStatus != models.ReviewStatusApproved || record.VoidedAt != nil {
+			continue
+		}
+		row := statsTotals{TotalKwh: record.KWH, TotalAmount: record.Amount, RecordCount: 1}
+		switch record.Timeslot {
+		case "day":
+			row.DayKwh = record.KWH
+		case "night":
+			row.NightKwh = record.KWH
+		}
+		dayKey := fmt.Sprintf("day:%d:%s:%d", record.UserID, record.Date.Format("2006-01-02"), statsPlateID(record))
+		monthKey := fmt.Sprintf("month:%d:%s", record.UserID, record.Date.Format("2006-01"))
+		rows[dayKey] = rows[dayKey].add(row)
+		rows[monthKey] = rows[monthKey].add(row)
+	}
+	return rows
+}
+
+func TestRecordStatsDeltasMatchRebuild(t *testing.T) {
+	tx, deltas := captureStatsUpserts(t)
+
+	date := func(value string) time.Time {
+		d, _ := time.Parse("2006-01-02", value)
+		return d
+	}
+	newRecord := func(id, userID uint, day, timeslot, kwh string, amount int64, status string) models.Record {
+		return models.Record{
+			ID:           id,
+			UserID:       userID,
+			Date:         date(day),
+			Timeslot:     timeslot,
+			KWH:          decimal.RequireFromString(kwh),
+			Amount:       amount,
+			ReviewStatus: status,
+		}
+	}
+	// 模拟创建、审核、修改、作废各路径对统计表的调用
+	create := func(record models.Record) models.Record {
+		if err := applyRecordStats(tx, record, 1); err != nil {
+			t.Fatalf("计入统计失败: %v", err)
+		}
+		return record
+	}
+	update := func(before models.Record, change func(*models.Record)) models.Record {
+		after := before
+		change(&after)
+		if err := replaceRecordStats(tx, before, after); err != nil {
+			t.Fatalf("更新统计失败: %v", err)
+		}
+		return after
+	}
+	void := func(record models.Record) models.Record {
+		if err := applyRecordStats(tx, record, -1); err != nil {
+			t.Fatalf("扣除统计失败: %v", err)
+		}
+		now := time.Now()
+		record.VoidedAt = &now
+		return record
+	}
+	status := func(value string) func(*models.Record) {
+		return func(r *models.Record) { r.ReviewStatus = value }
+	}
+
+	// 待审核 -> 通过 -> 修改度数
+	r1 := create(newRecord(1, 1, "2024-05-31", "day", "10.5", 1050, models.ReviewStatusSubmitted))
+	r1 = update(r1, status(models.ReviewStatusApproved))
+	r1 = update(r1, func(r *models.Record) { r.KWH = decimal.RequireFromString("12.25"); r.Amount = 1225 })
+
+	// 免审核直接通过 -> 驳回
+	r2 := create(newRecord(2, 1, "2024-05-31", "night", "8", 800, models.ReviewStatusApproved))
+	r2 = update(r2, status(models.ReviewStatusRejected))
+
+	// 待审核 -> 驳回 -> 更正后重新提交 -> 通过 -> 改到下个月
+	r3 := create(newRecord(3, 2, "2024-05-20", "night", "5", 500, models.ReviewStatusSubmitted))
+	r3 = update(r3, status(models.ReviewStatusRejected))
+	r3 = update(r3, func(r *models.Record) {
+		r.KWH = decimal.RequireFromString("6")
+		r.Amount = 600
+		r.ReviewStatus = models.ReviewStatusSubmitted
+	})
+	r3 = update(r3, status(models.ReviewStatusApproved))
+	r3 = update(r3, func(r *models.Record) { r.Date = date("2024-06-01"); r.Timeslot = "day" })
+
+	// 通过后作废
+	r4 := create(newRecord(4, 2, "2024-06-01", "day", "7", 700, models.ReviewStatusApproved))
+	r4 = void(r4)
+
+	// 待审核时作废
+	r5 := create(newRecord(5, 1, "2024-06-02", "", "3", 300, models.ReviewStatusSubmitted))
+	r5 = void(r5)
+
+	// 一直待审核
+	r6 := create(newRecord(6, 2, "2024-06-02", "night", "4", 400, models.ReviewStatusSubmitted))
+
+	// 通过且未修改，未标记班次
+	r7 := create(newRecord(7, 1, "2024-06-02", "", "2.5", 250, models.ReviewStatusApproved))
+
+	// 通过后更换车牌号，再改回未关联车牌号
+	plateA, plateB := uint(11), uint(12)
+	r8 := newRecord(8, 1, "2024-06-02", "day", "9", 900, models.ReviewStatusApproved)
+	r8.LicensePlateID = &plateA
+	r8 = create(r8)
+	r8 = update(r8, func(r *models.Record) { r.LicensePlateID = &plateB })
+	r9 := newRecord(9, 1, "2024-06-02", "night", "1.5", 150, models.ReviewStatusApproved)
+	r9.LicensePlateID = &plateB
+	r9 = create(r9)
+	r9 = update(r9, func(r *models.Record) { r.LicensePlateID = nil })
+
+	expected := rebuildStats([]models.Record{r1, r2, r3, r4, r5, r6, r7, r8, r9})
+	for key, got := range deltas {
+		want := expected[key]
+		if got.isZero() && want.isZero() {
+			continue
+		}
+		if !got.DayKwh.Equal(want.DayKwh) || !got.NightKwh.Equal(want.NightKwh) || !got.TotalKwh.Equal(want.TotalKwh) ||
+			got.TotalAmount != want.TotalAmount || got.RecordCount != want.RecordCount {
+			t.Errorf("%s 增量累计为 %s，重建结果为 %s", key, got, want)
+		}
+	}
+	for key, want := range expected {
+		if _, ok := deltas[key]; !ok {
+			t.Errorf("%s 缺少增量，重建结果为 %s", key, want)
+		}
+	}
+	if len(expected) == 0 {
+		t.Fatal("重建结果不应为空")
+	}
+}
+
+func TestAggregatePlateStatisticsReadsDayStats(t *testing.T) {
+	mock := setupMockDB(t)
+	plateID := 11
+	// 车辆统计与其他统计一样读取按日统计表，不再扫描充电记录
+	mock.ExpectQuery(`SELECT 0 as user_id, '' as date, NULLIF\(user_day_stats.license_plate_id, 0\) as license_plate_id,.* FROM "user_day_stats" WHERE .*user_day_stats.user_id = \$3 GROUP BY "user_day_stats"."license_plate_id" HAVING`).
+		WithArgs("2024-06-01", "2024-06-30", uint(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "date", "license_plate_id", "day_kwh", "night_kwh", "total_amount", "record_count"}).
+			AddRow(0, "", plateID, "9", "0", 900, 1).
+			AddRow(0, "", nil, "2.5", "1.5", 400, 2))
+
+	rows, err := aggregateShiftStatistics(1, "2024-06-01", "2024-06-30", shiftGroupPlate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].LicensePlateID == nil || *rows[0].LicensePlateID != 11 || rows[1].LicensePlateID != nil {
+		t.Fatalf("按车牌号分组结果不正确: %+v", rows)
+	}
+	if !rows[1].TotalKwh().Equal(decimal.RequireFromString("4")) || rows[1].RecordCount != 2 {
+		t.Fatalf("未关联车牌号的合计不正确: %+v", rows[1])
+	}
+}
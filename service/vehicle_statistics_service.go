@@ -0,0 +1,103 @@
+package service
+
+import (
+	"shared-charge/models"
+	"shared-charge/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// averageKwh 平均每次充电度数
+func averageKwh(agg ShiftAggregate) decimal.Decimal {
+	if agg.RecordCount == 0 {
+		return decimal.Zero
+	}
+	return agg.TotalKwh().DivRound(decimal.NewFromInt(agg.RecordCount), models.KWHPlaces)
+}
+
+// formatVehicleStatistics 格式化单个车牌号的统计
+func formatVehicleStatistics(agg ShiftAggregate, totalKwh decimal.Decimal) map[string]interface{} {
+	result := formatBucketTotals(agg)
+	result["avg_kwh"] = averageKwh(agg)
+	result["share"] = percentOfDecimal(agg.TotalKwh(), totalKwh)
+	return result
+}
+
+// GetVehicleStatistics 按车牌号统计用户在日期范围内的用电量、费用、充电次数和平均每次度数
+// 用户名下没有记录的车牌号也会返回；未绑定车牌号的记录单独作为一组
+func GetVehicleStatistics(c *gin.Context, userID uint, r StatisticsRange) (map[string]interface{}, error) {
+	startDate, endDate := r.From.Format("2006-01-02"), r.To.Format("2006-01-02")
+	utils.InfoCtx(c, "查询车辆统计: user_id=%d, from=%s, to=%s", userID, startDate, endDate)
+	rows, err := aggregateShiftStatistics(userID, startDate, endDate, shiftGroupPlate)
+	if err != nil {
+		utils.ErrorCtx(c, "查询车辆统计失败: %v", err)
+		return nil, err
+	}
+
+	var summary ShiftAggregate
+	var unbound *ShiftAggregate
+	plateRows := make(map[uint]ShiftAggregate)
+	for i, row := range rows {
+		summary.DayKwh = summary.DayKwh.Add(row.DayKwh)
+		summary.NightKwh = summary.NightKwh.Add(row.NightKwh)
+		summary.TotalAmount += row.TotalAmount
+		summary.RecordCount += row.RecordCount
+		if row.LicensePlateID == nil {
+			unbound = &rows[i]
+			continue
+		}
+		plateRows[*row.LicensePlateID] = row
+	}
+
+	// 当前车牌号在前（默认车牌号优先），其后是已删除但范围内有记录的车牌号
+	var plates []models.LicensePlate
+	if err := models.DB.Where("user_id = ?", userID).Order("is_default DESC, id ASC").Find(&plates).Error; err != nil {
+		return nil, err
+	}
+	listed := make(map[uint]bool, len(plates))
+	for _, plate := range plates {
+		listed[plate.ID] = true
+	}
+	var deletedIDs []uint
+	for id := range plateRows {
+		if !listed[id] {
+			deletedIDs = append(deletedIDs, id)
+		}
+	}
+	if len(deletedIDs) > 0 {
+		var deleted []models.LicensePlate
+		if err := models.DB.Unscoped().Where("id IN ?", deletedIDs).Order("id ASC").Find(&deleted).Error; err != nil {
+			return nil, err
+		}
+		plates = append(plates, deleted...)
+	}
+
+	totalKwh := summary.TotalKwh()
+	vehicles := make([]map[string]interface{}, 0, len(plates)+1)
+	for _, plate := range plates {
+		item := formatVehicleStatistics(plateRows[plate.ID], totalKwh)
+		item["license_plate_id"] = plate.ID
+		item["plate_number"] = plate.PlateNumber
+		item["is_default"] = plate.IsDefault
+		item["deleted"] = plate.DeletedAt.Valid
+		vehicles = append(vehicles, item)
+	}
+	if unbound != nil {
+		item := formatVehicleStatistics(*unbound, totalKwh)
+		item["license_plate_id"] = nil
+		item["plate_number"] = unboundPlateNumber
+		item["is_default"] = false
+		item["deleted"] = false
+		vehicles = append(vehicles, item)
+	}
+
+	summaryData := formatBucketTotals(summary)
+	summaryData["avg_kwh"] = averageKwh(summary)
+	return map[string]interface{}{
+		"from":     startDate,
+		"to":       endDate,
+		"vehicles": vehicles,
+		"summary":  summaryData,
+	}, nil
+}
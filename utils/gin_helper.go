@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"net"
 	"net/http"
 	"shared-charge/models"
 	"time"
@@ -27,3 +28,42 @@ func GetUserFromContext(c *gin.Context) (models.User, bool) {
 func ParseDate(dateStr string) (date time.Time, err error) {
 	return time.Parse("2006-01-02", dateStr)
 }
+
+// AbortStream 已开始写出响应体后发生错误时直接关闭连接，使客户端得到中断的下载而不是看似完整的 200 响应
+func AbortStream(c *gin.Context) {
+	c.Abort()
+	if conn := hijackConn(c); conn != nil {
+		conn.Close()
+		return
+	}
+	// 不支持劫持连接（如 HTTP/2）时由 net/http 中止该响应
+	panic(http.ErrAbortHandler)
+}
+
+// hijackConn 劫持底层连接，底层 ResponseWriter 不支持劫持时 gin 会 panic，这里统一返回 nil
+func hijackConn(c *gin.Context) (conn net.Conn) {
+	defer func() {
+		if recover() != nil {
+			conn = nil
+		}
+	}()
+	hijacked, _, err := c.Writer.Hijack()
+	if err != nil {
+		return nil
+	}
+	return hijacked
+}
+
+// TraceIDKey gin.Context 中保存 trace_id 的 key
+const TraceIDKey = "trace_id"
+
+// SessionIDKey gin.Context 中保存当前访问令牌所属登录会话ID的 key
+const SessionIDKey = "session_id"
+
+// GetTraceID 获取当前请求的 trace_id，c 为空或未经过 TraceMiddleware 时返回空字符串
+func GetTraceID(c *gin.Context) string {
+	if c == nil {
+		return ""
+	}
+	return c.GetString(TraceIDKey)
+}
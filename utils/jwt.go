@@ -1,33 +1,63 @@
 package utils
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"shared-charge/config"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-// GenerateToken 生成JWT令牌
-func GenerateToken(user interface{}) (string, error) {
+// AccessToken 签发的访问令牌
+type AccessToken struct {
+	Token     string
+	JTI       string
+	ExpiresAt time.Time
+}
+
+// GenerateRandomID 生成指定字节数的随机十六进制字符串，用于令牌ID和会话ID
+func GenerateRandomID(size int) (string, error) {
+	bytes := make([]byte, size)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+// GenerateToken 生成JWT访问令牌，jti 用于注销后加入黑名单，sid 为所属登录会话
+func GenerateToken(user interface{}, sessionID string) (AccessToken, error) {
 	cfg := config.GetConfig()
 
 	// 类型断言获取用户信息
 	userMap, ok := user.(map[string]interface{})
 	if !ok {
-		return "", jwt.ErrSignatureInvalid
+		return AccessToken{}, jwt.ErrSignatureInvalid
 	}
 
+	jti, err := GenerateRandomID(16)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	now := time.Now()
+	expiresAt := now.Add(time.Duration(cfg.JWT.AccessExpireMinutes) * time.Minute)
 	claims := jwt.MapClaims{
 		"user_id": userMap["id"],
 		"openid":  userMap["openid"],
 		"name":    userMap["name"],
 		"role":    userMap["role"],
-		"exp":     time.Now().Add(time.Duration(cfg.JWT.ExpireHours) * time.Hour).Unix(),
-		"iat":     time.Now().Unix(),
+		"jti":     jti,
+		"sid":     sessionID,
+		"exp":     expiresAt.Unix(),
+		"iat":     now.Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.JWT.Secret))
+	signed, err := token.SignedString([]byte(cfg.JWT.Secret))
+	if err != nil {
+		return AccessToken{}, err
+	}
+	return AccessToken{Token: signed, JTI: jti, ExpiresAt: expiresAt}, nil
 }
 
 // ParseToken 解析JWT令牌
@@ -2,6 +2,8 @@ package utils
 
 import (
 	"context"
+	"fmt"
+	"time"
 
 	"github.com/go-redis/redis/v8"
 )
@@ -23,3 +25,42 @@ func GetRedis() *redis.Client {
 func RedisCtx() context.Context {
 	return context.Background()
 }
+
+// UserCacheKey 认证中间件缓存用户信息的 key
+func UserCacheKey(userID uint) string {
+	return fmt.Sprintf("user_cache:%d", userID)
+}
+
+// DeleteUserCache 删除用户缓存，用户状态或角色变化后立即生效
+func DeleteUserCache(userID uint) error {
+	if RedisClient == nil {
+		return nil
+	}
+	return RedisClient.Del(RedisCtx(), UserCacheKey(userID)).Err()
+}
+
+// tokenDenylistKey 已注销访问令牌黑名单的 key
+func tokenDenylistKey(jti string) string {
+	return "token_denylist:" + jti
+}
+
+// DenyToken 将访问令牌加入黑名单，保留到令牌过期为止
+func DenyToken(jti string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if RedisClient == nil || jti == "" || ttl <= 0 {
+		return nil
+	}
+	return RedisClient.Set(RedisCtx(), tokenDenylistKey(jti), 1, ttl).Err()
+}
+
+// IsTokenDenied 访问令牌是否已被注销
+func IsTokenDenied(jti string) (bool, error) {
+	if RedisClient == nil {
+		return false, nil
+	}
+	count, err := RedisClient.Exists(RedisCtx(), tokenDenylistKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}